@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+// schemaTimeout limits how long completion waits for the namespace schema.
+const schemaTimeout = 2 * time.Second
+
+var commandNames = []string{`\?`, `\c`, `\d`, `\pager`, `\q`, `\timing`}
+
+var keywords = []string{
+	"AND", "AS", "ASC", "BETWEEN", "BY", "CASE", "COUNT", "DATE_BIN", "DESC",
+	"DISTINCT", "ELSE", "END", "EXPLAIN", "FALSE", "FROM", "FULL", "GROUP",
+	"HAVING", "IN", "INNER", "IS", "JOIN", "LEFT", "LIKE", "LIMIT", "MAX",
+	"MIN", "NOT", "NULL", "NOW", "OFFSET", "ON", "OR", "ORDER", "OUTER",
+	"RIGHT", "SELECT", "SHOW", "SUM", "TABLES", "THEN", "TRUE", "UNION",
+	"WHEN", "WHERE", "WITH",
+}
+
+// complete completes the word before pos in line, as a liner.WordCompleter.
+// After FROM, JOIN and \d, it offers table names; elsewhere in a statement,
+// column names, table names and keywords. A word of the form table.prefix
+// completes the columns of table.
+func (s *shell) complete(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+	start := strings.LastIndexAny(head, " \t\n(,=<>") + 1
+	head, word := head[:start], head[start:]
+
+	previous := strings.Fields(head)
+	if len(previous) == 0 && strings.HasPrefix(word, `\`) {
+		return head, matching(commandNames, word), tail
+	}
+
+	var candidates []string
+	namespaceSchema := s.completionSchema()
+	afterTable := len(previous) > 0 && strings.Contains(" FROM JOIN INTO \\D ", " "+strings.ToUpper(previous[len(previous)-1])+" ")
+	switch {
+	case namespaceSchema == nil:
+		if !afterTable {
+			candidates = keywords
+		}
+	case strings.Contains(word, ".") && !afterTable:
+		dot := strings.LastIndex(word, ".")
+		table := strings.Trim(word[:dot], `"`)
+		head, word = head+word[:dot+1], word[dot+1:]
+		if tableSchema, ok := namespaceSchema.Tables[table]; ok {
+			for _, column := range tableSchema.ColumnNames() {
+				candidates = append(candidates, quoteIdentifier(column))
+			}
+		}
+	case afterTable:
+		for _, table := range namespaceSchema.TableNames() {
+			candidates = append(candidates, quoteIdentifier(table))
+		}
+	default:
+		columns := map[string]bool{}
+		for _, table := range namespaceSchema.Tables {
+			for column := range table.Columns {
+				columns[quoteIdentifier(column)] = true
+			}
+		}
+		for _, table := range namespaceSchema.TableNames() {
+			columns[quoteIdentifier(table)] = true
+		}
+		for column := range columns {
+			candidates = append(candidates, column)
+		}
+		sort.Strings(candidates)
+		candidates = append(candidates, keywords...)
+	}
+	return head, matching(candidates, word), tail
+}
+
+// completionSchema returns the namespace schema, fetching it if it has not
+// been loaded since the namespace was chosen, or nil if it is unavailable.
+func (s *shell) completionSchema() *influxdbiox.NamespaceSchema {
+	if s.schema != nil || s.namespace == "" {
+		return s.schema
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), schemaTimeout)
+	defer cancel()
+	namespaceSchema, _ := s.loadSchema(ctx)
+	return namespaceSchema
+}
+
+// matching returns the candidates that start with prefix, ignoring case and
+// a leading double quote.
+func matching(candidates []string, prefix string) []string {
+	prefix = strings.ToLower(strings.TrimPrefix(prefix, `"`))
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(strings.TrimPrefix(candidate, `"`)), prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// quoteIdentifier double quotes name if SQL requires it to keep its case or
+// characters.
+func quoteIdentifier(name string) string {
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+	}
+	if name == "" {
+		return `""`
+	}
+	return name
+}
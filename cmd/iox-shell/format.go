@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/internal/cli"
+)
+
+// newRecordTable returns a tableWriter for query results, with a header
+// showing the name and Arrow type of each column.
+func newRecordTable(w io.Writer, schema *arrow.Schema) *tableWriter {
+	fields := schema.Fields()
+	names := make([]string, len(fields))
+	types := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+		types[i] = typeName(field.Type)
+	}
+	return newTableWriter(w, names, types)
+}
+
+// formatRecord formats the rows of a record batch.
+func formatRecord(record arrow.Record, timeFormat string) ([][]string, error) {
+	rows := make([][]string, record.NumRows())
+	for row := range rows {
+		values := make([]string, record.NumCols())
+		for i, column := range record.Columns() {
+			value, err := influxdbiox.ValueFromArrowColumn(column, row)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", record.ColumnName(i), err)
+			}
+			values[i] = cli.FormatValue(value, timeFormat)
+		}
+		rows[row] = values
+	}
+	return rows, nil
+}
+
+// typeName names an Arrow type; dictionaries are named by their value type,
+// since the encoding is not of interest to the reader.
+func typeName(dataType arrow.DataType) string {
+	if dictionary, ok := dataType.(*arrow.DictionaryType); ok {
+		dataType = dictionary.ValueType
+	}
+	return dataType.String()
+}
+
+// formatTable formats rows in aligned columns, under a header of names and
+// optional types, followed by the number of rows.
+func formatTable(names, types []string, rows [][]string) string {
+	var b strings.Builder
+	tw := newTableWriter(&b, names, types)
+	_ = tw.writeRows(rows)
+	_ = tw.close()
+	return b.String()
+}
+
+// tableWriter writes rows in aligned columns as they arrive, under a header
+// of names and optional types, followed by the number of rows.
+//
+// The column widths are those of the header and the first rows written, so
+// that a large result need not be held in memory; wider cells in later rows
+// are written in full, and push the rest of their row to the right.
+type tableWriter struct {
+	w      io.Writer
+	names  []string
+	types  []string
+	widths []int // nil until the header is written
+	rows   int
+}
+
+func newTableWriter(w io.Writer, names, types []string) *tableWriter {
+	return &tableWriter{w: w, names: names, types: types}
+}
+
+// writeRows writes rows, preceded by the header if it has not been written.
+func (tw *tableWriter) writeRows(rows [][]string) error {
+	var b strings.Builder
+	if tw.widths == nil {
+		tw.writeHeader(&b, rows)
+	}
+	for _, row := range rows {
+		tw.writeRow(&b, row)
+	}
+	tw.rows += len(rows)
+	_, err := io.WriteString(tw.w, b.String())
+	return err
+}
+
+// close writes the header, if no rows were written, and the number of rows.
+func (tw *tableWriter) close() error {
+	var b strings.Builder
+	if tw.widths == nil {
+		tw.writeHeader(&b, nil)
+	}
+	if tw.rows == 1 {
+		b.WriteString("(1 row)\n")
+	} else {
+		fmt.Fprintf(&b, "(%d rows)\n", tw.rows)
+	}
+	_, err := io.WriteString(tw.w, b.String())
+	return err
+}
+
+// writeHeader sets the column widths to fit the header and rows, and writes
+// the header.
+func (tw *tableWriter) writeHeader(b *strings.Builder, rows [][]string) {
+	tw.widths = make([]int, len(tw.names))
+	measure := func(cells []string) {
+		for i, cell := range cells {
+			if n := utf8.RuneCountInString(cell); n > tw.widths[i] {
+				tw.widths[i] = n
+			}
+		}
+	}
+	measure(tw.names)
+	measure(tw.types)
+	for _, row := range rows {
+		measure(row)
+	}
+
+	tw.writeRow(b, tw.names)
+	if tw.types != nil {
+		tw.writeRow(b, tw.types)
+	}
+	for i, width := range tw.widths {
+		if i > 0 {
+			b.WriteString("-+-")
+		}
+		b.WriteString(strings.Repeat("-", width))
+	}
+	b.WriteString("\n")
+}
+
+func (tw *tableWriter) writeRow(b *strings.Builder, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		b.WriteString(cell)
+		if n := utf8.RuneCountInString(cell); i < len(cells)-1 && n < tw.widths[i] {
+			b.WriteString(strings.Repeat(" ", tw.widths[i]-n))
+		}
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,160 @@
+// Command iox-shell is an interactive SQL shell for InfluxDB IOx.
+//
+// Usage:
+//
+//	iox-shell [flags]
+//
+// Statements end with a semicolon, and may span several lines. Lines
+// starting with a backslash are shell commands:
+//
+//	\d           list the tables of the namespace
+//	\d <table>   describe the columns of a table
+//	\c <name>    use another namespace
+//	\timing      toggle printing the duration of each query
+//	\pager       toggle paging of results longer than the terminal
+//	\?           show help
+//	\q           quit
+//
+// Press tab to complete table names, column names and SQL keywords. History
+// is saved in the file named by -history, by default ~/.iox_shell_history.
+// Press Ctrl-C to cancel the statement being typed or the running query.
+//
+// When stdin is not a terminal, statements are read from it without
+// prompts, and the exit status is 1 if any statement failed.
+//
+// The client config is built from these sources, in increasing order of
+// precedence:
+//
+//   - environment variables INFLUXDB_IOX_ADDRESS, INFLUXDB_IOX_NAMESPACE and
+//     the rest, as read by influxdbiox.ClientConfigFromEnv
+//   - the config file named by -config, and its profile named by -profile,
+//     as read by influxdbiox.ClientConfigFromFile
+//   - the data source name given by -dsn, in any format supported by
+//     influxdbiox.ClientConfigFromString
+//   - flags such as -address, -namespace and -token
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/peterh/liner"
+	"golang.org/x/term"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/internal/cli"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("iox-shell", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: iox-shell [flags]\n\nAn interactive SQL shell for InfluxDB IOx.\n\n%s\n\nFlags:\n", cli.ConfigSourcesDoc)
+		fs.PrintDefaults()
+	}
+	config := cli.AddConfigFlags(fs)
+	historyFile := fs.String("history", defaultHistoryFile(), "`file` in which to save statement history; empty disables history")
+	pager := fs.String("pager", defaultPager(), "pager `command` for results longer than the terminal; empty disables paging")
+	timeFormat := fs.String("time-format", time.RFC3339Nano, "`layout` of timestamps, as for Go's time.Format")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "iox-shell: unexpected arguments %q\n", fs.Args())
+		fs.Usage()
+		return 2
+	}
+
+	clientConfig, err := config.ClientConfig(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "iox-shell: %s\n", err)
+		return 1
+	}
+	client, err := influxdbiox.NewClient(context.Background(), clientConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "iox-shell: %s\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	s := &shell{
+		client:     client,
+		namespace:  clientConfig.Namespace,
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+		timeFormat: *timeFormat,
+		interrupts: interrupts,
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+	if !interactive {
+		s.input = newScannerInput(os.Stdin)
+		if failures := s.run(context.Background()); failures > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetMultiLineMode(true)
+	line.SetTabCompletionStyle(liner.TabPrints)
+	line.SetWordCompleter(s.complete)
+	s.input = line
+	s.pager = *pager
+	s.pageHeight = func() int {
+		_, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			return 0
+		}
+		return height
+	}
+
+	if *historyFile != "" {
+		if f, err := os.Open(*historyFile); err == nil {
+			_, _ = line.ReadHistory(f)
+			_ = f.Close()
+		}
+	}
+	fmt.Fprintf(os.Stdout, "Connected to %s. Type \\? for help.\n", clientConfig.Address)
+	s.run(context.Background())
+	if *historyFile != "" {
+		if f, err := os.OpenFile(*historyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600); err == nil {
+			_, _ = line.WriteHistory(f)
+			_ = f.Close()
+		}
+	}
+	return 0
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".iox_shell_history")
+}
+
+func defaultPager() string {
+	if pager, ok := os.LookupEnv("PAGER"); ok {
+		return pager
+	}
+	return "less -FRSX"
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/peterh/liner"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+// lineInput reads the lines typed at a prompt. *liner.State implements it
+// for terminals, and scannerInput for other input.
+type lineInput interface {
+	Prompt(prompt string) (string, error)
+	AppendHistory(item string)
+}
+
+// scannerInput reads lines without prompting, or keeping history.
+type scannerInput struct {
+	scanner *bufio.Scanner
+}
+
+func newScannerInput(r io.Reader) *scannerInput {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	return &scannerInput{scanner: scanner}
+}
+
+func (in *scannerInput) Prompt(string) (string, error) {
+	if in.scanner.Scan() {
+		return in.scanner.Text(), nil
+	}
+	if err := in.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+func (in *scannerInput) AppendHistory(string) {}
+
+type shell struct {
+	client     *influxdbiox.Client
+	namespace  string
+	input      lineInput
+	stdout     io.Writer
+	stderr     io.Writer
+	timeFormat string
+	timing     bool
+	// interrupts cancel the running query.
+	interrupts <-chan os.Signal
+
+	// pager is the command that pages results longer than pageHeight lines;
+	// paging is disabled if pager is "" or pageHeight is nil.
+	pager      string
+	pageHeight func() int
+	pagerOff   bool
+
+	// schema of namespace, for completion; nil until loaded.
+	schema *influxdbiox.NamespaceSchema
+}
+
+// run reads and executes statements and shell commands until the input
+// ends or \q, returning the number of statements and commands that failed.
+func (s *shell) run(ctx context.Context) int {
+	var (
+		buffer   string // text of unfinished statements
+		failures int
+	)
+	for {
+		prompt := s.namespace + "> "
+		if buffer != "" {
+			prompt = strings.Repeat(" ", len(prompt)-3) + "-> "
+		}
+		line, err := s.input.Prompt(prompt)
+		if errors.Is(err, liner.ErrPromptAborted) {
+			buffer = ""
+			continue
+		} else if err != nil {
+			if !errors.Is(err, io.EOF) {
+				fmt.Fprintf(s.stderr, "ERROR: %s\n", err)
+				failures++
+			} else if strings.TrimSpace(buffer) != "" {
+				// Execute a final statement without a semicolon.
+				if !s.execute(ctx, strings.TrimSpace(buffer)) {
+					failures++
+				}
+			}
+			return failures
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if buffer == "" {
+			if trimmed == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, `\`) {
+				s.input.AppendHistory(trimmed)
+				quit, err := s.command(ctx, trimmed)
+				if err != nil {
+					fmt.Fprintf(s.stderr, "ERROR: %s\n", err)
+					failures++
+				}
+				if quit {
+					return failures
+				}
+				continue
+			}
+			if trimmed == "exit" || trimmed == "quit" {
+				return failures
+			}
+		}
+
+		buffer += line + "\n"
+		statements, rest := splitStatements(buffer)
+		for _, statement := range statements {
+			s.input.AppendHistory(strings.Join(strings.Fields(statement), " ") + ";")
+			if !s.execute(ctx, statement) {
+				failures++
+			}
+		}
+		buffer = rest
+		if strings.TrimSpace(buffer) == "" {
+			buffer = ""
+		}
+	}
+}
+
+// splitStatements splits text at each semicolon that is not in a string
+// literal, quoted identifier or comment, returning the complete statements,
+// without semicolons or empty statements, and the remaining text.
+func splitStatements(text string) ([]string, string) {
+	var (
+		statements []string
+		start      int
+		quote      byte // ' or " while in a literal or identifier
+		comment    bool // in a -- comment
+	)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case comment:
+			comment = c != '\n'
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '-' && i+1 < len(text) && text[i+1] == '-':
+			comment = true
+		case c == ';':
+			if statement := strings.TrimSpace(text[start:i]); statement != "" {
+				statements = append(statements, statement)
+			}
+			start = i + 1
+		}
+	}
+	return statements, text[start:]
+}
+
+// execute runs a query and prints its result, reporting whether it
+// succeeded.
+func (s *shell) execute(ctx context.Context, query string) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.interrupts:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	start := time.Now()
+	out := s.newPagedWriter()
+	err := s.query(ctx, query, out)
+	elapsed := time.Since(start)
+	out.close()
+	if errors.Is(err, errPagerClosed) {
+		// The pager was quit before the end of the result.
+		err = nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = errors.New("query canceled")
+		}
+		fmt.Fprintf(s.stderr, "ERROR: %s\n", err)
+		return false
+	}
+	if s.timing {
+		fmt.Fprintf(s.stdout, "Time: %.3f ms\n", float64(elapsed)/float64(time.Millisecond))
+	}
+	return true
+}
+
+// query runs a query, and writes its result to w as a table, one record
+// batch at a time.
+func (s *shell) query(ctx context.Context, query string, w io.Writer) error {
+	request, err := s.client.PrepareQuery(ctx, s.namespace, query)
+	if err != nil {
+		return err
+	}
+	reader, err := request.Query(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+
+	table := newRecordTable(w, reader.Schema())
+	for reader.Next() {
+		rows, err := formatRecord(reader.Record(), s.timeFormat)
+		if err != nil {
+			return err
+		}
+		if err = table.writeRows(rows); err != nil {
+			return err
+		}
+	}
+	if err = reader.Err(); err != nil {
+		return err
+	}
+	return table.close()
+}
+
+// errPagerClosed is returned by pagedWriter.Write once the pager has exited.
+var errPagerClosed = errors.New("pager closed")
+
+// pagedWriter writes to stdout, or through the pager if the output is longer
+// than the terminal. Output is held back until it is known which, that is,
+// until it fills the terminal or the writer is closed.
+type pagedWriter struct {
+	s      *shell
+	height int       // lines that fill the terminal; 0 disables paging
+	out    io.Writer // stdout or the pager input; nil while held back
+	buffer bytes.Buffer
+	lines  int
+
+	pager   *exec.Cmd
+	pagerIn io.WriteCloser
+}
+
+func (s *shell) newPagedWriter() *pagedWriter {
+	w := &pagedWriter{s: s}
+	if s.pager != "" && !s.pagerOff && s.pageHeight != nil {
+		w.height = s.pageHeight()
+	}
+	if w.height <= 0 {
+		w.out = s.stdout
+	}
+	return w
+}
+
+func (w *pagedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if w.out == nil {
+		w.buffer.Write(p)
+		if w.lines += bytes.Count(p, []byte("\n")); w.lines < w.height {
+			return n, nil
+		}
+		w.out = w.startPager()
+		p = w.buffer.Bytes()
+		w.buffer = bytes.Buffer{}
+	}
+	if _, err := w.out.Write(p); err != nil {
+		if w.pager != nil {
+			return 0, errPagerClosed
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// startPager starts the pager, and returns its input, or stdout if it
+// cannot be started.
+func (w *pagedWriter) startPager() io.Writer {
+	cmd := exec.Command("/bin/sh", "-c", w.s.pager)
+	cmd.Stdout = w.s.stdout
+	cmd.Stderr = w.s.stderr
+	in, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		fmt.Fprintf(w.s.stderr, "pager %q failed: %s\n", w.s.pager, err)
+		return w.s.stdout
+	}
+	w.pager, w.pagerIn = cmd, in
+	return in
+}
+
+// close writes the output held back to stdout, or waits for the pager to
+// exit.
+func (w *pagedWriter) close() {
+	if w.out == nil {
+		_, _ = w.buffer.WriteTo(w.s.stdout)
+		return
+	}
+	if w.pager == nil {
+		return
+	}
+	_ = w.pagerIn.Close()
+	if err := w.pager.Wait(); err != nil {
+		fmt.Fprintf(w.s.stderr, "pager %q failed: %s\n", w.s.pager, err)
+	}
+}
+
+const helpText = `Statements end with a semicolon, and may span several lines.
+
+  \d           list the tables of the namespace
+  \d <table>   describe the columns of a table
+  \c <name>    use another namespace
+  \timing      toggle printing the duration of each query
+  \pager       toggle paging of results longer than the terminal
+  \?           show this help
+  \q           quit
+
+Press tab to complete table names, column names and SQL keywords.
+`
+
+// command runs a shell command, reporting whether the shell should quit.
+func (s *shell) command(ctx context.Context, line string) (bool, error) {
+	fields := strings.Fields(line)
+	switch name, args := fields[0], fields[1:]; name {
+	case `\q`, `\quit`:
+		return true, nil
+	case `\?`, `\h`, `\help`:
+		fmt.Fprint(s.stdout, helpText)
+	case `\d`:
+		if len(args) > 1 {
+			return false, fmt.Errorf(`usage: \d [table]`)
+		}
+		if len(args) == 0 {
+			return false, s.listTables(ctx)
+		}
+		return false, s.describeTable(ctx, strings.Trim(args[0], `"`))
+	case `\c`, `\connect`:
+		if len(args) != 1 {
+			return false, fmt.Errorf(`usage: \c <namespace>`)
+		}
+		s.namespace = args[0]
+		s.schema = nil
+		fmt.Fprintf(s.stdout, "Using namespace %s.\n", s.namespace)
+	case `\timing`:
+		on, err := toggle(s.timing, args)
+		if err != nil {
+			return false, err
+		}
+		s.timing = on
+		fmt.Fprintf(s.stdout, "Timing is %s.\n", onOff(on))
+	case `\pager`:
+		on, err := toggle(!s.pagerOff, args)
+		if err != nil {
+			return false, err
+		}
+		s.pagerOff = !on
+		fmt.Fprintf(s.stdout, "Pager is %s.\n", onOff(on))
+	default:
+		return false, fmt.Errorf(`unknown command %s; type \? for help`, name)
+	}
+	return false, nil
+}
+
+// toggle returns the new state of a setting: the opposite of on, or the
+// state named by args.
+func toggle(on bool, args []string) (bool, error) {
+	switch {
+	case len(args) == 0:
+		return !on, nil
+	case len(args) == 1 && args[0] == "on":
+		return true, nil
+	case len(args) == 1 && args[0] == "off":
+		return false, nil
+	default:
+		return on, errors.New("expected on or off")
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func (s *shell) requireNamespace() error {
+	if s.namespace == "" {
+		return errors.New(`no namespace; use \c <namespace>`)
+	}
+	return nil
+}
+
+// loadSchema fetches the schema of the namespace, caching it for completion.
+func (s *shell) loadSchema(ctx context.Context) (*influxdbiox.NamespaceSchema, error) {
+	if err := s.requireNamespace(); err != nil {
+		return nil, err
+	}
+	namespaceSchema, err := s.client.GetNamespaceSchema(ctx, s.namespace)
+	if err != nil {
+		return nil, err
+	}
+	s.schema = namespaceSchema
+	return namespaceSchema, nil
+}
+
+func (s *shell) listTables(ctx context.Context) error {
+	namespaceSchema, err := s.loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(namespaceSchema.Tables))
+	for _, name := range namespaceSchema.TableNames() {
+		rows = append(rows, []string{name, fmt.Sprint(len(namespaceSchema.Tables[name].Columns))})
+	}
+	fmt.Fprint(s.stdout, formatTable([]string{"table", "columns"}, nil, rows))
+	return nil
+}
+
+func (s *shell) describeTable(ctx context.Context, table string) error {
+	if err := s.requireNamespace(); err != nil {
+		return err
+	}
+	columns, err := s.client.GetSchema(ctx, s.namespace, table)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([][]string, len(names))
+	for i, name := range names {
+		rows[i] = []string{name, columns[name].String()}
+	}
+	fmt.Fprint(s.stdout, formatTable([]string{"column", "type"}, nil, rows))
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/internal/clitest"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func newTestShell(t *testing.T, server *ioxtest.Server, input string) (*shell, *bytes.Buffer, *bytes.Buffer) {
+	client, err := influxdbiox.NewClient(context.Background(), server.ClientConfig("mydb"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	var stdout, stderr bytes.Buffer
+	s := &shell{
+		client:     client,
+		namespace:  "mydb",
+		input:      newScannerInput(strings.NewReader(input)),
+		stdout:     &stdout,
+		stderr:     &stderr,
+		timeFormat: time.RFC3339Nano,
+	}
+	return s, &stdout, &stderr
+}
+
+func newTestServer(t *testing.T) *ioxtest.Server {
+	server := clitest.NewServer(t)
+	server.AddTable("mydb", "cpu", map[string]influxdbiox.ColumnType{
+		"time":  influxdbiox.ColumnType_TIME,
+		"host":  influxdbiox.ColumnType_TAG,
+		"usage": influxdbiox.ColumnType_F64,
+	})
+	server.AddTable("mydb", "Disk", map[string]influxdbiox.ColumnType{
+		"time":      influxdbiox.ColumnType_TIME,
+		"free":      influxdbiox.ColumnType_U64,
+		"mount-dir": influxdbiox.ColumnType_STRING,
+	})
+	return server
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements, rest := splitStatements("select 1; select ';' as \"a;b\"; -- c;\nselect\n")
+	assert.Equal(t, []string{"select 1", "select ';' as \"a;b\""}, statements)
+	assert.Equal(t, " -- c;\nselect\n", rest)
+
+	statements, rest = splitStatements(";;  ;")
+	assert.Empty(t, statements)
+	assert.Equal(t, "", rest)
+}
+
+func TestShell_query(t *testing.T) {
+	server := newTestServer(t)
+	server.HandleQuery("select *\nfrom cpu", clitest.NewRecord(t))
+
+	s, stdout, stderr := newTestShell(t, server, "select *\nfrom cpu;\nselect nope;\nselect *\nfrom cpu")
+	assert.Equal(t, 1, s.run(context.Background()))
+	expect := `time                 | host     | usage
+timestamp[ns]        | utf8     | float64
+---------------------+----------+--------
+2021-04-15T00:00:00Z | server-a | 0.5
+2021-04-15T00:00:01Z |          | 12.25
+(2 rows)
+`
+	// The final statement runs at the end of the input without a semicolon.
+	assert.Equal(t, expect+expect, stdout.String())
+	assert.Contains(t, stderr.String(), "ERROR: ")
+}
+
+func TestShell_pager(t *testing.T) {
+	server := newTestServer(t)
+	record := clitest.NewRecord(t)
+	server.HandleQuery("select * from cpu", record, record)
+
+	s, stdout, stderr := newTestShell(t, server, "select * from cpu;\n\\pager off\nselect * from cpu;\n")
+	s.pager = `sed 's/^/> /'`
+	s.pageHeight = func() int { return 8 }
+	assert.Equal(t, 0, s.run(context.Background()), stderr.String())
+	table := `time                 | host     | usage
+timestamp[ns]        | utf8     | float64
+---------------------+----------+--------
+2021-04-15T00:00:00Z | server-a | 0.5
+2021-04-15T00:00:01Z |          | 12.25
+2021-04-15T00:00:00Z | server-a | 0.5
+2021-04-15T00:00:01Z |          | 12.25
+(4 rows)
+`
+	// The result fills the terminal, so it is paged, unless paging is off.
+	paged := "> " + strings.ReplaceAll(strings.TrimSuffix(table, "\n"), "\n", "\n> ") + "\n"
+	assert.Equal(t, paged+"Pager is off.\n"+table, stdout.String())
+
+	s, stdout, _ = newTestShell(t, server, "select * from cpu;\n")
+	s.pager = `sed 's/^/> /'`
+	s.pageHeight = func() int { return 9 }
+	assert.Equal(t, 0, s.run(context.Background()))
+	assert.Equal(t, table, stdout.String())
+}
+
+func TestTableWriter(t *testing.T) {
+	var b strings.Builder
+	tw := newTableWriter(&b, []string{"a", "b"}, nil)
+	require.NoError(t, tw.writeRows([][]string{{"x", "y"}}))
+	// Widths are set by the first rows; later rows are not realigned.
+	require.NoError(t, tw.writeRows([][]string{{"long", "z"}, {"", "w"}}))
+	require.NoError(t, tw.close())
+	assert.Equal(t, `a | b
+--+--
+x | y
+long | z
+  | w
+(3 rows)
+`, b.String())
+}
+
+func TestShell_commands(t *testing.T) {
+	server := newTestServer(t)
+	server.AddNamespace("other")
+	server.HandleQuery("select 1", clitest.NewRecord(t))
+
+	s, stdout, stderr := newTestShell(t, server, `\d
+\d cpu
+\timing
+select 1;
+\timing off
+\c other
+\d
+\d cpu
+\nope
+\q
+select 1;
+`)
+	assert.Equal(t, 2, s.run(context.Background()))
+	out := stdout.String()
+	assert.Contains(t, out, `table | columns
+------+--------
+Disk  | 3
+cpu   | 3
+(2 rows)
+`)
+	assert.Contains(t, out, `column | type
+-------+----------
+host   | tag
+time   | timestamp
+usage  | float64
+(3 rows)
+`)
+	assert.Contains(t, out, "Timing is on.\n")
+	assert.Regexp(t, `\(2 rows\)\nTime: \d+\.\d{3} ms\n`, out)
+	assert.Contains(t, out, "Timing is off.\n")
+	assert.Contains(t, out, "Using namespace other.\n")
+	assert.Contains(t, out, "table | columns\n------+--------\n(0 rows)\n")
+	assert.Contains(t, stderr.String(), "ERROR: table not found")
+	assert.Contains(t, stderr.String(), `ERROR: unknown command \nope`)
+	assert.Len(t, server.Queries(), 1, `statements after \q must not run`)
+}
+
+func TestShell_complete(t *testing.T) {
+	server := newTestServer(t)
+	s, _, _ := newTestShell(t, server, "")
+
+	for _, tc := range []struct {
+		line        string
+		head        string
+		completions []string
+	}{
+		{line: `\t`, head: "", completions: []string{`\timing`}},
+		{line: "select * from ", head: "select * from ", completions: []string{`"Disk"`, "cpu"}},
+		{line: "SELECT * FROM c", head: "SELECT * FROM ", completions: []string{"cpu"}},
+		{line: `\d "d`, head: `\d `, completions: []string{`"Disk"`}},
+		{line: "select us", head: "select ", completions: []string{"usage"}},
+		{line: "select m", head: "select ", completions: []string{`"mount-dir"`, "MAX", "MIN"}},
+		{line: "select count(f", head: "select count(", completions: []string{"free", "FALSE", "FROM", "FULL"}},
+		{line: "select cpu.", head: "select cpu.", completions: []string{"host", "time", "usage"}},
+		{line: `select "Disk".m`, head: `select "Disk".`, completions: []string{`"mount-dir"`}},
+		{line: "sel", head: "", completions: []string{"SELECT"}},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			head, completions, tail := s.complete(tc.line+" limit 1", len(tc.line))
+			assert.Equal(t, tc.head, head)
+			assert.Equal(t, tc.completions, completions)
+			assert.Equal(t, " limit 1", tail)
+		})
+	}
+
+	// The schema is fetched again for a new namespace.
+	s.schema = nil
+	s.namespace = "nope"
+	_, completions, _ := s.complete("select * from ", 14)
+	assert.Empty(t, completions)
+}
@@ -0,0 +1,191 @@
+// Command iox is a command-line client for InfluxDB IOx.
+//
+// Usage:
+//
+//	iox <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	query         run a SQL or InfluxQL query
+//	schema        list the tables and columns of a namespace
+//	retention     set the retention period of a namespace
+//	write         write line protocol from files or stdin
+//	ping          check that IOx responds to a Flight handshake
+//	parquet-meta  print the IOx metadata of Parquet files
+//
+// Run "iox <command> -h" for the flags and arguments of each command.
+//
+// Every command that connects to IOx builds its client config from these
+// sources, in increasing order of precedence:
+//
+//   - environment variables INFLUXDB_IOX_ADDRESS, INFLUXDB_IOX_NAMESPACE and
+//     the rest, as read by influxdbiox.ClientConfigFromEnv
+//   - the config file named by -config, and its profile named by -profile,
+//     as read by influxdbiox.ClientConfigFromFile
+//   - the data source name given by -dsn, in any format supported by
+//     influxdbiox.ClientConfigFromString
+//   - flags such as -address, -namespace and -token
+//
+// For example:
+//
+//	export INFLUXDB_IOX_ADDRESS=localhost:8082
+//	iox query -namespace mydb 'select * from cpu limit 10'
+//	iox query -dsn 'iox://localhost/mydb' -o cpu.parquet 'select * from cpu'
+//	iox write -namespace myorg_mybucket -write-url http://localhost:8080 -wait readable metrics.lp
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/internal/cli"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := (&app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}).run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+// errUsage is returned by a command whose arguments are invalid, after the
+// problem has been reported.
+var errUsage = errors.New("usage")
+
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	// dialOptions are added to every client config, so that tests can
+	// connect to an ioxtest.Server.
+	dialOptions []grpc.DialOption
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(a *app, ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"query", "run a SQL or InfluxQL query", (*app).query},
+	{"schema", "list the tables and columns of a namespace", (*app).schema},
+	{"retention", "set the retention period of a namespace", (*app).retention},
+	{"write", "write line protocol from files or stdin", (*app).write},
+	{"ping", "check that IOx responds to a Flight handshake", (*app).ping},
+	{"parquet-meta", "print the IOx metadata of Parquet files", (*app).parquetMeta},
+}
+
+// run runs the command named by args[0], returning the process exit code:
+// 0 on success, 1 if the command failed, or 2 if it was used incorrectly.
+func (a *app) run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		a.usage()
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(a, ctx, args[1:])
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			return 2
+		default:
+			fmt.Fprintf(a.stderr, "iox %s: %s\n", c.name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(a.stderr, "iox: unknown command %q\n", args[0])
+	a.usage()
+	return 2
+}
+
+func (a *app) usage() {
+	fmt.Fprintf(a.stderr, "Usage: iox <command> [flags] [arguments]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(a.stderr, "  %-13s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(a.stderr, "\nRun \"iox <command> -h\" for the flags and arguments of a command.\n")
+}
+
+// newFlagSet returns a flag set for a command, whose usage message shows
+// the arguments and description.
+func (a *app) newFlagSet(name, arguments, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet("iox "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: iox %s [flags] %s\n\n%s\n\nFlags:\n", name, arguments, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args, and checks that the number of remaining arguments
+// is between min and max; a negative max means no limit.
+func parseFlags(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fmt.Fprintf(fs.Output(), "%s: wrong number of arguments\n", fs.Name())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// addConfigFlags defines the client config flags in fs.
+func (a *app) addConfigFlags(fs *flag.FlagSet) *cli.ConfigFlags {
+	f := cli.AddConfigFlags(fs)
+	f.DialOptions = a.dialOptions
+	return f
+}
+
+// newClient connects to IOx with the config from f.
+func (a *app) newClient(ctx context.Context, f *cli.ConfigFlags) (*influxdbiox.Client, error) {
+	config, err := f.ClientConfig(a.stderr)
+	if err != nil {
+		return nil, err
+	}
+	return influxdbiox.NewClient(ctx, config)
+}
+
+func (a *app) ping(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("ping", "", "Check that IOx responds to a Flight handshake, and print the round trip time.")
+	config := a.addConfigFlags(fs)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := a.newClient(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	start := time.Now()
+	if err = client.Handshake(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "OK %s (%s)\n", client.Config().Address, time.Since(start).Round(time.Microsecond))
+	return nil
+}
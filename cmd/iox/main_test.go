@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/file"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/internal/clitest"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+	"github.com/influxdata/influxdb-iox-client-go/v2/parquetmeta"
+)
+
+// runIOx runs the iox command with args against server, returning the exit
+// code, stdout and stderr.
+func runIOx(t *testing.T, server *ioxtest.Server, stdin string, args ...string) (int, string, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	a := &app{
+		stdin:       strings.NewReader(stdin),
+		stdout:      &stdout,
+		stderr:      &stderr,
+		dialOptions: []grpc.DialOption{grpc.WithContextDialer(server.DialContext)},
+	}
+	code := a.run(ctx, args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsage(t *testing.T) {
+	server := clitest.NewServer(t)
+
+	code, _, stderr := runIOx(t, server, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: iox <command>")
+
+	code, _, stderr = runIOx(t, server, "", "nope")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "nope"`)
+
+	code, _, stderr = runIOx(t, server, "", "query", "-h")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr, "Usage: iox query [flags] <query>")
+	assert.Contains(t, stderr, "-influxql")
+
+	code, _, stderr = runIOx(t, server, "", "query")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "wrong number of arguments")
+
+	code, _, stderr = runIOx(t, server, "", "query", "-format", "xml", "select 1")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown export format "xml"`)
+
+	code, _, stderr = runIOx(t, server, "", "retention", "get")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: iox retention set")
+}
+
+func TestQuery(t *testing.T) {
+	server := clitest.NewServer(t)
+	server.HandleQuery("select * from cpu", clitest.NewRecord(t))
+
+	code, stdout, stderr := runIOx(t, server, "", "query", "-namespace", "mydb", "select * from cpu")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, `time                  host      usage
+2021-04-15T00:00:00Z  server-a  0.5
+2021-04-15T00:00:01Z            12.25
+`, stdout)
+
+	code, stdout, stderr = runIOx(t, server, "", "query", "-namespace", "mydb", "-format", "csv", "-time-format", "2006-01-02 15:04:05", "select * from cpu")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "time,host,usage\n2021-04-15 00:00:00,server-a,0.5\n2021-04-15 00:00:01,,12.25\n", stdout)
+
+	// The query can be read from stdin, and the namespace from a DSN.
+	code, stdout, stderr = runIOx(t, server, "select * from cpu", "query", "-dsn", "bufnet:8082/mydb", "-format", "json", "-")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, `{"time":"2021-04-15T00:00:00Z","host":"server-a","usage":0.5}
+{"time":"2021-04-15T00:00:01Z","host":null,"usage":12.25}
+`, stdout)
+
+	code, _, stderr = runIOx(t, server, "", "query", "-namespace", "mydb", "-influxql", "select * from cpu")
+	require.Equal(t, 0, code, stderr)
+	queries := server.Queries()
+	require.Len(t, queries, 4)
+	assert.Equal(t, influxdbiox.QueryTypeInfluxQL, queries[3].QueryType)
+	for _, query := range queries {
+		assert.Equal(t, "mydb", query.Namespace)
+	}
+
+	code, _, stderr = runIOx(t, server, "", "query", "-namespace", "mydb", "select nothing")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "iox query: ")
+}
+
+func TestQuery_parquet(t *testing.T) {
+	server := clitest.NewServer(t)
+	server.HandleQuery("select * from cpu", clitest.NewRecord(t))
+
+	filename := filepath.Join(t.TempDir(), "cpu.parquet")
+	code, stdout, stderr := runIOx(t, server, "", "query", "-namespace", "mydb", "-o", filename, "-compression", "gzip", "select * from cpu")
+	require.Equal(t, 0, code, stderr)
+	assert.Empty(t, stdout)
+
+	reader, err := file.OpenParquetFile(filename, false)
+	require.NoError(t, err)
+	defer func() { _ = reader.Close() }()
+	assert.EqualValues(t, 2, reader.NumRows())
+	assert.Equal(t, 3, reader.MetaData().Schema.NumColumns())
+}
+
+func TestClientConfig(t *testing.T) {
+	server := clitest.NewServer(t)
+	server.AddTable("from_file", "cpu", map[string]influxdbiox.ColumnType{"time": influxdbiox.ColumnType_TIME})
+	server.AddTable("from_flag", "mem", map[string]influxdbiox.ColumnType{"time": influxdbiox.ColumnType_TIME})
+
+	configFile := filepath.Join(t.TempDir(), "iox.yaml")
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("address: bufnet:8082\nprofiles:\n  test:\n    namespace: from_file\n"), 0o600))
+
+	code, stdout, stderr := runIOx(t, server, "", "schema", "-config", configFile, "-profile", "test")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "cpu")
+
+	// Flags take precedence over the config file.
+	code, stdout, stderr = runIOx(t, server, "", "schema", "-config", configFile, "-profile", "test", "-namespace", "from_flag")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "mem")
+
+	code, _, stderr = runIOx(t, server, "", "schema", "-config", configFile, "-profile", "nope")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `profile "nope" not found`)
+
+	code, _, stderr = runIOx(t, server, "", "schema", "-profile", "test")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "-profile requires -config")
+
+	// A config file without an address uses the address of the environment.
+	namespaceFile := filepath.Join(t.TempDir(), "namespace.toml")
+	require.NoError(t, ioutil.WriteFile(namespaceFile, []byte("namespace = \"from_file\"\n"), 0o600))
+	code, stdout, stderr = runIOx(t, server, "", "schema", "-config", namespaceFile)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "cpu")
+
+	// The environment is overridden by every other source.
+	t.Setenv("INFLUXDB_IOX_NAMESPACE", "from_flag")
+	code, stdout, stderr = runIOx(t, server, "", "schema", "-config", configFile, "-profile", "test")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "cpu")
+}
+
+func TestSchema(t *testing.T) {
+	server := clitest.NewServer(t)
+	server.AddTable("mydb", "cpu", map[string]influxdbiox.ColumnType{
+		"time":  influxdbiox.ColumnType_TIME,
+		"host":  influxdbiox.ColumnType_TAG,
+		"usage": influxdbiox.ColumnType_F64,
+	})
+	server.AddTable("mydb", "disk", map[string]influxdbiox.ColumnType{
+		"time": influxdbiox.ColumnType_TIME,
+		"free": influxdbiox.ColumnType_U64,
+	})
+
+	code, stdout, stderr := runIOx(t, server, "", "schema", "-namespace", "mydb")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, `table  column  type
+cpu    host    tag
+cpu    time    timestamp
+cpu    usage   float64
+disk   free    uint64
+disk   time    timestamp
+`, stdout)
+
+	code, stdout, stderr = runIOx(t, server, "", "schema", "-namespace", "mydb", "disk")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "column  type\nfree    uint64\ntime    timestamp\n", stdout)
+
+	code, _, stderr = runIOx(t, server, "", "schema", "-namespace", "mydb", "nope")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "table not found")
+
+	code, _, stderr = runIOx(t, server, "", "schema")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "namespace is required")
+}
+
+func TestRetention(t *testing.T) {
+	server := clitest.NewServer(t)
+	server.AddNamespace("mydb")
+
+	code, stdout, stderr := runIOx(t, server, "", "retention", "set", "-namespace", "mydb", "30d")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "namespace mydb retention 720h0m0s\n", stdout)
+	retention, _ := server.NamespaceRetention("mydb")
+	assert.Equal(t, 30*24*time.Hour, retention)
+
+	code, stdout, stderr = runIOx(t, server, "", "retention", "set", "-namespace", "mydb", "infinite")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "namespace mydb retention infinite\n", stdout)
+
+	code, _, stderr = runIOx(t, server, "", "retention", "set", "-namespace", "mydb", "90m")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "whole number of hours")
+
+	code, _, stderr = runIOx(t, server, "", "retention", "set", "-namespace", "mydb", "soon")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `invalid retention period "soon"`)
+}
+
+func TestWrite(t *testing.T) {
+	server := clitest.NewServer(t)
+	server.SetWriteInfo("token-1", []ioxtest.ShardStatus{ioxtest.ShardStatusDurable}, []ioxtest.ShardStatus{ioxtest.ShardStatusReadable})
+
+	var requests []*http.Request
+	var bodies []string
+	writeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.Header().Set("X-IOx-Write-Token", "token-1")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(writeServer.Close)
+
+	lpFile := filepath.Join(t.TempDir(), "cpu.lp")
+	require.NoError(t, ioutil.WriteFile(lpFile, []byte("cpu usage=1 1"), 0o600))
+
+	code, stdout, stderr := runIOx(t, server, "cpu usage=2 2\n",
+		"write", "-namespace", "myorg_mybucket", "-write-url", writeServer.URL, "-precision", "s", "-wait", "readable", lpFile, "-")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "token-1\n", stdout)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "myorg", requests[0].URL.Query().Get("org"))
+	assert.Equal(t, "mybucket", requests[0].URL.Query().Get("bucket"))
+	assert.Equal(t, "s", requests[0].URL.Query().Get("precision"))
+	assert.Equal(t, "cpu usage=1 1\ncpu usage=2 2\n", bodies[0])
+
+	code, _, stderr = runIOx(t, server, "", "write", "-namespace", "myorg_mybucket", "-write-url", writeServer.URL)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no line protocol to write")
+
+	code, _, stderr = runIOx(t, server, "", "write", "-wait", "forever", lpFile)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `invalid -wait condition "forever"`)
+}
+
+func TestPing(t *testing.T) {
+	server := clitest.NewServer(t)
+
+	code, stdout, stderr := runIOx(t, server, "", "ping", "-debug")
+	require.Equal(t, 0, code, stderr)
+	assert.True(t, strings.HasPrefix(stdout, "OK bufnet:8082 ("), stdout)
+	assert.Contains(t, stderr, "dialing IOx")
+
+	// A JSON DSN is accepted.
+	dsn, err := json.Marshal(map[string]string{"address": "bufnet:8082"})
+	require.NoError(t, err)
+	t.Setenv("INFLUXDB_IOX_ADDRESS", "")
+	code, _, stderr = runIOx(t, server, "", "ping", "-dsn", string(dsn))
+	require.Equal(t, 0, code, stderr)
+
+	code, _, stderr = runIOx(t, server, "", "ping")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "address is required")
+}
+
+func TestParquetMeta(t *testing.T) {
+	metadata := &parquetmeta.Metadata{
+		ObjectStoreID: "0a2d5e1c-7b3f-4a52-9f1e-6c0d2b8e4f11",
+		NamespaceID:   1,
+		NamespaceName: "mydb",
+		TableID:       2,
+		TableName:     "cpu",
+		PartitionID:   3,
+		PartitionKey:  "2021-04-15",
+		SortKey:       []parquetmeta.SortKeyExpr{{Column: "host"}, {Column: "time"}},
+	}
+	value, err := metadata.Encode()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "2"), 0o755))
+	keyValueMetadata := arrow.NewMetadata([]string{parquetmeta.Key}, []string{value})
+	record := clitest.NewRecord(t)
+	schema := arrow.NewSchema(record.Schema().Fields(), &keyValueMetadata)
+	f, err := os.Create(filepath.Join(dir, "2", metadata.ObjectStoreID+".parquet"))
+	require.NoError(t, err)
+	w, err := pqarrow.NewFileWriter(schema, f, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
+	require.NoError(t, err)
+	require.NoError(t, w.Write(array.NewRecord(schema, record.Columns(), record.NumRows())))
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not parquet"), 0o644))
+
+	code, stdout, stderr := runIOx(t, nil, "", "parquet-meta", dir)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, filepath.Join(dir, "2", metadata.ObjectStoreID+".parquet")+"\nobject_store_id:     "+metadata.ObjectStoreID+"\n")
+	assert.Contains(t, stdout, "sort_key:            host, time\n")
+
+	code, stdout, stderr = runIOx(t, nil, "", "parquet-meta", "-json", dir)
+	require.Equal(t, 0, code, stderr)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &decoded))
+	assert.Equal(t, "cpu", decoded["table_name"])
+	assert.Equal(t, filepath.Join(dir, "2", metadata.ObjectStoreID+".parquet"), decoded["path"])
+
+	// Files named as arguments are read whatever their name.
+	code, _, stderr = runIOx(t, nil, "", "parquet-meta", filepath.Join(dir, "notes.txt"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "notes.txt: ")
+	assert.Contains(t, stderr, "failed to read 1 files")
+}
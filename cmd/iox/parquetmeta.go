@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2/parquetmeta"
+)
+
+func (a *app) parquetMeta(_ context.Context, args []string) error {
+	flags := a.newFlagSet("parquet-meta", "<file or directory>...",
+		"Print the IOx metadata of Parquet files, such as those persisted to object storage.\n"+
+			"Directories are searched recursively for files named *.parquet.")
+	asJSON := flags.Bool("json", false, "print one JSON object per file, with a \"path\" member")
+	if err := parseFlags(flags, args, 1, -1); err != nil {
+		return err
+	}
+
+	var failed int
+	first := true
+	report := func(path string) {
+		metadata, err := parquetmeta.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(a.stderr, "iox parquet-meta: %s: %s\n", path, err)
+			failed++
+			return
+		}
+		if *asJSON {
+			b, _ := json.Marshal(struct {
+				Path string `json:"path"`
+				*parquetmeta.Metadata
+			}{path, metadata})
+			fmt.Fprintf(a.stdout, "%s\n", b)
+			return
+		}
+		if !first {
+			fmt.Fprintln(a.stdout)
+		}
+		first = false
+		fmt.Fprintf(a.stdout, "%s\n", path)
+		_ = metadata.Print(a.stdout)
+	}
+
+	for _, root := range flags.Args() {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Files named as arguments are read whatever their extension.
+			if path == root && !entry.IsDir() || !entry.IsDir() && strings.HasSuffix(path, ".parquet") {
+				report(path)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(a.stderr, "iox parquet-meta: %s\n", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to read %d files", failed)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow/array"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/export"
+	"github.com/influxdata/influxdb-iox-client-go/v2/internal/cli"
+)
+
+// tableFormat is the query output format for terminals: columns aligned
+// with spaces, under a header of column names.
+const tableFormat export.Format = "table"
+
+// parseQueryFormat parses the -format flag of the query command. Without
+// one, the format is identified by the output file extension, or is
+// tableFormat for stdout.
+func parseQueryFormat(format, output string) (export.Format, error) {
+	switch strings.ToLower(format) {
+	case "":
+		if output == "" {
+			return tableFormat, nil
+		}
+		return export.FormatFromFilename(output)
+	case string(tableFormat):
+		return tableFormat, nil
+	case "json":
+		return export.NDJSON, nil
+	default:
+		return export.ParseFormat(format)
+	}
+}
+
+func (a *app) query(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("query", "<query>",
+		"Run a SQL or InfluxQL query, and print the result. If the query is \"-\", it is read from stdin.")
+	config := a.addConfigFlags(fs)
+	influxQL := fs.Bool("influxql", false, "the query is InfluxQL, rather than SQL")
+	format := fs.String("format", "", "output `format`: table, csv, json, arrow or parquet (default table, or from the -o file extension)")
+	output := fs.String("o", "", "write the result to `file`, instead of stdout")
+	timeFormat := fs.String("time-format", time.RFC3339Nano, "`layout` of timestamps in table, csv and json output, as for Go's time.Format")
+	compression := fs.String("compression", string(export.CompressionSnappy), "parquet compression `codec`: none, snappy, gzip, brotli or zstd")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	outputFormat, err := parseQueryFormat(*format, *output)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "%s: %s\n", fs.Name(), err)
+		return errUsage
+	}
+	parquetCompression, err := export.ParseCompression(*compression)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "%s: %s\n", fs.Name(), err)
+		return errUsage
+	}
+
+	query := fs.Arg(0)
+	if query == "-" {
+		b, err := ioutil.ReadAll(a.stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read query from stdin: %w", err)
+		}
+		query = string(b)
+	}
+
+	client, err := a.newClient(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var request *influxdbiox.QueryRequest
+	if *influxQL {
+		request, err = client.PrepareInfluxQLQuery(ctx, "", query)
+	} else {
+		request, err = client.PrepareQuery(ctx, "", query)
+	}
+	if err != nil {
+		return err
+	}
+	reader, err := request.Query(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+
+	write := func(w io.Writer) error {
+		if outputFormat == tableFormat {
+			return writeTable(w, reader, *timeFormat)
+		}
+		_, err := export.Write(w, reader, outputFormat, &export.Options{
+			TimeFormat:         *timeFormat,
+			ParquetCompression: parquetCompression,
+		})
+		return err
+	}
+	if *output == "" {
+		return write(a.stdout)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeTable writes the records of reader as a table, aligned with spaces.
+// NULL values are empty, and tabs and newlines in strings are escaped.
+func writeTable(w io.Writer, reader array.RecordReader, timeFormat string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	escaper := strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+	for i, field := range reader.Schema().Fields() {
+		if i > 0 {
+			fmt.Fprint(tw, "\t")
+		}
+		fmt.Fprint(tw, escaper.Replace(field.Name))
+	}
+	fmt.Fprintln(tw)
+
+	for reader.Next() {
+		record := reader.Record()
+		for row := 0; row < int(record.NumRows()); row++ {
+			for i, column := range record.Columns() {
+				value, err := influxdbiox.ValueFromArrowColumn(column, row)
+				if err != nil {
+					return fmt.Errorf("column %q: %w", record.ColumnName(i), err)
+				}
+				if i > 0 {
+					fmt.Fprint(tw, "\t")
+				}
+				fmt.Fprint(tw, escaper.Replace(cli.FormatValue(value, timeFormat)))
+			}
+			fmt.Fprintln(tw)
+		}
+	}
+	if r, ok := reader.(interface{ Err() error }); ok {
+		if err := r.Err(); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+// errNoNamespace is returned by commands that require a namespace when the
+// client config has none.
+var errNoNamespace = errors.New("namespace is required; set -namespace, or the namespace of -dsn or -config")
+
+func (a *app) schema(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("schema", "[table]",
+		"List the tables of the namespace and their columns, or the columns of one table.")
+	config := a.addConfigFlags(fs)
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	client, err := a.newClient(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	namespace := client.Config().Namespace
+	if namespace == "" {
+		return errNoNamespace
+	}
+
+	tw := tabwriter.NewWriter(a.stdout, 0, 8, 2, ' ', 0)
+	if table := fs.Arg(0); table != "" {
+		columns, err := client.GetSchema(ctx, namespace, table)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(columns))
+		for name := range columns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(tw, "column\ttype")
+		for _, name := range names {
+			fmt.Fprintf(tw, "%s\t%s\n", name, columns[name])
+		}
+		return tw.Flush()
+	}
+
+	namespaceSchema, err := client.GetNamespaceSchema(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(tw, "table\tcolumn\ttype")
+	for _, tableName := range namespaceSchema.TableNames() {
+		table := namespaceSchema.Tables[tableName]
+		for _, name := range table.ColumnNames() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", tableName, name, table.Columns[name].Type)
+		}
+	}
+	return tw.Flush()
+}
+
+func (a *app) retention(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "set" {
+		fmt.Fprintf(a.stderr, "Usage: iox retention set [flags] <period>\n\nRun \"iox retention set -h\" for the flags.\n")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			return flag.ErrHelp
+		}
+		return errUsage
+	}
+
+	fs := a.newFlagSet("retention set", "<period>",
+		"Set the retention period of the namespace, as a whole number of hours such as 720h, days such as 30d, or \"infinite\".")
+	config := a.addConfigFlags(fs)
+	if err := parseFlags(fs, args[1:], 1, 1); err != nil {
+		return err
+	}
+	retention, err := parseRetention(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(a.stderr, "%s: %s\n", fs.Name(), err)
+		return errUsage
+	}
+
+	client, err := a.newClient(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	namespace := client.Config().Namespace
+	if namespace == "" {
+		return errNoNamespace
+	}
+
+	updated, err := client.SetNamespaceRetention(ctx, namespace, retention)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "namespace %s retention %s\n", updated.Name, retentionString(updated.Retention))
+	return nil
+}
+
+// parseRetention parses a retention period: a time.Duration, a whole number
+// of days with the suffix "d", or "infinite".
+func parseRetention(s string) (time.Duration, error) {
+	switch {
+	case strings.EqualFold(s, "infinite"):
+		return influxdbiox.InfiniteRetention, nil
+	case strings.HasSuffix(s, "d"):
+		days, err := strconv.ParseInt(strings.TrimSuffix(s, "d"), 10, 64)
+		if err != nil || days <= 0 || days > int64(influxdbiox.InfiniteRetention/(24*time.Hour)) {
+			return 0, fmt.Errorf("invalid retention period %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	default:
+		retention, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention period %q", s)
+		}
+		return retention, nil
+	}
+}
+
+func retentionString(retention time.Duration) string {
+	if retention == influxdbiox.InfiniteRetention {
+		return "infinite"
+	}
+	return retention.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+func (a *app) write(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("write", "[file ...]",
+		"Write line protocol from files, or from stdin if there are none or a file is \"-\", to the namespace, which must have the form org_bucket.\n"+
+			"If IOx returns a write token, it is printed, after waiting for the -wait condition.")
+	config := a.addConfigFlags(fs)
+	precision := fs.String("precision", "ns", "`precision` of timestamps: ns, us, ms or s")
+	gzip := fs.Bool("gzip", false, "compress the request body with gzip")
+	wait := fs.String("wait", "none", "`condition` to wait for after writing: none, durable, readable or persisted")
+	if err := parseFlags(fs, args, 0, -1); err != nil {
+		return err
+	}
+	writePrecision, err := parsePrecision(*precision)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "%s: %s\n", fs.Name(), err)
+		return errUsage
+	}
+	switch *wait {
+	case "none", "durable", "readable", "persisted":
+	default:
+		fmt.Fprintf(a.stderr, "%s: invalid -wait condition %q\n", fs.Name(), *wait)
+		return errUsage
+	}
+
+	filenames := fs.Args()
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+	var lines []byte
+	for _, filename := range filenames {
+		var b []byte
+		if filename == "-" {
+			b, err = ioutil.ReadAll(a.stdin)
+		} else {
+			b, err = ioutil.ReadFile(filename)
+		}
+		if err != nil {
+			return err
+		}
+		lines = append(lines, b...)
+		if len(b) > 0 && b[len(b)-1] != '\n' {
+			lines = append(lines, '\n')
+		}
+	}
+	if len(lines) == 0 {
+		return errors.New("no line protocol to write")
+	}
+
+	client, err := a.newClient(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	writeToken, err := client.PrepareWrite("").WithPrecision(writePrecision).WithGzip(*gzip).Write(ctx, lines)
+	if err != nil {
+		return err
+	}
+	if writeToken == "" {
+		if *wait != "none" {
+			return fmt.Errorf("cannot wait until %s: IOx returned no write token", *wait)
+		}
+		return nil
+	}
+
+	switch *wait {
+	case "durable":
+		err = client.WaitForDurable(ctx, writeToken)
+	case "readable":
+		err = client.WaitForReadable(ctx, writeToken)
+	case "persisted":
+		err = client.WaitForPersisted(ctx, writeToken)
+	}
+	if err != nil {
+		return fmt.Errorf("wrote line protocol with write token %s, but failed to wait until %s: %w", writeToken, *wait, err)
+	}
+	fmt.Fprintln(a.stdout, writeToken)
+	return nil
+}
+
+func parsePrecision(s string) (lineprotocol.Precision, error) {
+	switch s {
+	case "ns":
+		return lineprotocol.Nanosecond, nil
+	case "us":
+		return lineprotocol.Microsecond, nil
+	case "ms":
+		return lineprotocol.Millisecond, nil
+	case "s":
+		return lineprotocol.Second, nil
+	default:
+		return 0, fmt.Errorf("invalid precision %q", s)
+	}
+}
@@ -1,2 +1,7 @@
 // Package influxdbiox is a client library for InfluxDB/IOx.
+//
+// This module supports Go 1.18 and later. For that reason,
+// ClientConfig.Logger is a Logger, an interface with the DebugContext method
+// of *slog.Logger, rather than a *slog.Logger, because the log/slog package
+// requires Go 1.21. On Go 1.21 and later, a *slog.Logger can be used as is.
 package influxdbiox
@@ -0,0 +1,187 @@
+// Package export streams the results of IOx queries to CSV, JSON Lines,
+// Arrow IPC and Parquet files.
+//
+// Records are written as they are read from the query, so a result set
+// larger than memory can be exported:
+//
+//	request, err := client.PrepareQuery(ctx, "", "select * from cpu")
+//	f, err := os.Create("cpu.parquet")
+//	rows, err := export.Query(ctx, f, request, export.Parquet, nil)
+//	err = f.Close()
+//
+// Write exports a reader that was already opened, such as the
+// *influxdbiox.QueryReader returned by influxdbiox.QueryRequest.Query.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	// CSV writes comma-separated values with a header row of column names.
+	CSV Format = "csv"
+	// NDJSON writes one JSON object per row, separated by newlines, with a
+	// member for each column. Also known as JSON Lines.
+	NDJSON Format = "ndjson"
+	// ArrowIPC writes an Arrow IPC file, also known as Feather version 2.
+	ArrowIPC Format = "arrow"
+	// Parquet writes an Apache Parquet file.
+	Parquet Format = "parquet"
+)
+
+// ParseFormat parses the names of the Format constants, and the aliases
+// "jsonl" for NDJSON, and "ipc" and "feather" for ArrowIPC.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(s)); format {
+	case CSV, NDJSON, ArrowIPC, Parquet:
+		return format, nil
+	case "jsonl":
+		return NDJSON, nil
+	case "ipc", "feather":
+		return ArrowIPC, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q", s)
+	}
+}
+
+// FormatFromFilename returns the Format for the extension of filename:
+// .csv, .ndjson, .jsonl, .arrow, .ipc, .feather or .parquet.
+func FormatFromFilename(filename string) (Format, error) {
+	ext := filepath.Ext(filename)
+	if ext == "" {
+		return "", fmt.Errorf("cannot determine export format of %q without a file extension", filename)
+	}
+	format, err := ParseFormat(ext[1:])
+	if err != nil {
+		return "", fmt.Errorf("cannot determine export format of %q: %w", filename, err)
+	}
+	return format, nil
+}
+
+// Options configures an export. The zero value, or a nil *Options, uses the
+// defaults.
+type Options struct {
+	// TimeFormat is the layout of timestamps and dates in CSV and NDJSON, as
+	// for time.Time.Format. The default is time.RFC3339Nano.
+	TimeFormat string
+	// CSVComma is the CSV field delimiter. The default is ','.
+	CSVComma rune
+	// CSVNoHeader omits the CSV header row of column names.
+	CSVNoHeader bool
+	// CSVNull is written for NULL values in CSV. The default is the empty
+	// string.
+	CSVNull string
+
+	// ParquetCompression is the Parquet column compression codec. The
+	// default is CompressionSnappy.
+	ParquetCompression Compression
+	// ParquetCompressionLevel is the level for codecs that support one.
+	// Zero uses the codec's default level.
+	ParquetCompressionLevel int
+	// ParquetRowGroupLength is the maximum number of rows in each Parquet
+	// row group. A row group is buffered in memory, encoded and compressed,
+	// until it is full. The default is DefaultParquetRowGroupLength.
+	ParquetRowGroupLength int64
+
+	// Allocator allocates the memory used to convert records for Arrow IPC
+	// and Parquet. The default is memory.DefaultAllocator.
+	Allocator memory.Allocator
+}
+
+// DefaultParquetRowGroupLength is the default Options.ParquetRowGroupLength.
+const DefaultParquetRowGroupLength = 1024 * 1024
+
+func (o *Options) withDefaults() Options {
+	var options Options
+	if o != nil {
+		options = *o
+	}
+	if options.TimeFormat == "" {
+		options.TimeFormat = time.RFC3339Nano
+	}
+	if options.CSVComma == 0 {
+		options.CSVComma = ','
+	}
+	if options.ParquetCompression == "" {
+		options.ParquetCompression = CompressionSnappy
+	}
+	if options.ParquetRowGroupLength <= 0 {
+		options.ParquetRowGroupLength = DefaultParquetRowGroupLength
+	}
+	if options.Allocator == nil {
+		options.Allocator = memory.DefaultAllocator
+	}
+	return options
+}
+
+// Query sends a query with args and writes its result to w in format,
+// returning the number of rows written.
+func Query(ctx context.Context, w io.Writer, request *influxdbiox.QueryRequest, format Format, options *Options, args ...interface{}) (int64, error) {
+	reader, err := request.Query(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Release()
+	return Write(w, reader, format, options)
+}
+
+// Write reads every record from reader, which is typically the
+// *influxdbiox.QueryReader returned by influxdbiox.QueryRequest.Query, and
+// writes them to w in format, returning the number of rows written.
+// If reader has an Err method, as *influxdbiox.QueryReader does, its error is
+// returned.
+//
+// Write does not close w. A Parquet or Arrow IPC file is only complete when
+// Write returns without error.
+func Write(w io.Writer, reader array.RecordReader, format Format, options *Options) (int64, error) {
+	o := options.withDefaults()
+	var (
+		writer recordWriter
+		err    error
+	)
+	switch format {
+	case CSV:
+		writer, err = newCSVWriter(w, reader.Schema(), &o)
+	case NDJSON:
+		writer, err = newNDJSONWriter(w, reader.Schema(), &o)
+	case ArrowIPC:
+		writer, err = newArrowWriter(w, reader.Schema(), &o)
+	case Parquet:
+		writer, err = newParquetWriter(w, reader.Schema(), &o)
+	default:
+		return 0, fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	for reader.Next() {
+		record := reader.Record()
+		if err = writer.write(record); err != nil {
+			_ = writer.close()
+			return rows, err
+		}
+		rows += record.NumRows()
+	}
+	if r, ok := reader.(interface{ Err() error }); ok {
+		if err = r.Err(); err != nil {
+			_ = writer.close()
+			return rows, err
+		}
+	}
+	return rows, writer.close()
+}
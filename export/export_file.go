@@ -0,0 +1,217 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/compress"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+)
+
+// Compression is a Parquet compression codec.
+type Compression string
+
+// The supported Parquet compression codecs.
+const (
+	CompressionNone   Compression = "none"
+	CompressionSnappy Compression = "snappy"
+	CompressionGzip   Compression = "gzip"
+	CompressionBrotli Compression = "brotli"
+	CompressionZstd   Compression = "zstd"
+)
+
+var compressionCodecs = map[Compression]compress.Compression{
+	CompressionNone:   compress.Codecs.Uncompressed,
+	CompressionSnappy: compress.Codecs.Snappy,
+	CompressionGzip:   compress.Codecs.Gzip,
+	CompressionBrotli: compress.Codecs.Brotli,
+	CompressionZstd:   compress.Codecs.Zstd,
+}
+
+// ParseCompression parses the names of the Compression constants, and the
+// alias "uncompressed" for CompressionNone. The empty string is parsed as
+// CompressionSnappy.
+func ParseCompression(s string) (Compression, error) {
+	switch compression := Compression(strings.ToLower(s)); compression {
+	case "":
+		return CompressionSnappy, nil
+	case "uncompressed":
+		return CompressionNone, nil
+	default:
+		if _, ok := compressionCodecs[compression]; !ok {
+			return "", fmt.Errorf("unknown parquet compression %q", s)
+		}
+		return compression, nil
+	}
+}
+
+// arrowWriter writes an Arrow IPC file. The file format allows only one
+// dictionary per column, but IOx sends a new dictionary with each record,
+// so dictionary-encoded columns are written with their value type.
+type arrowWriter struct {
+	w      *ipc.FileWriter
+	schema *arrow.Schema
+	mem    memory.Allocator
+}
+
+func newArrowWriter(w io.Writer, schema *arrow.Schema, options *Options) (*arrowWriter, error) {
+	schema = decodedSchema(schema)
+	fw, err := ipc.NewFileWriter(&positionWriter{w: w}, ipc.WithSchema(schema), ipc.WithAllocator(options.Allocator))
+	if err != nil {
+		return nil, err
+	}
+	return &arrowWriter{w: fw, schema: schema, mem: options.Allocator}, nil
+}
+
+func (aw *arrowWriter) write(record arrow.Record) error {
+	record, err := decodeRecord(aw.mem, aw.schema, record)
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+	return aw.w.Write(record)
+}
+
+func (aw *arrowWriter) close() error {
+	return aw.w.Close()
+}
+
+// positionWriter satisfies the io.WriteSeeker required by ipc.NewFileWriter,
+// which only seeks to find the current position.
+type positionWriter struct {
+	w   io.Writer
+	pos int64
+}
+
+func (pw *positionWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.pos += int64(n)
+	return n, err
+}
+
+func (pw *positionWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, errors.New("export: seek not supported")
+	}
+	return pw.pos, nil
+}
+
+// decodedSchema replaces the dictionary types in schema with their value
+// types.
+func decodedSchema(schema *arrow.Schema) *arrow.Schema {
+	fields := make([]arrow.Field, len(schema.Fields()))
+	for i, field := range schema.Fields() {
+		if dictType, ok := field.Type.(*arrow.DictionaryType); ok {
+			field.Type = dictType.ValueType
+		}
+		fields[i] = field
+	}
+	metadata := schema.Metadata()
+	return arrow.NewSchema(fields, &metadata)
+}
+
+// decodeRecord returns record with its dictionary-encoded columns decoded,
+// to match schema, as returned by decodedSchema.
+func decodeRecord(mem memory.Allocator, schema *arrow.Schema, record arrow.Record) (arrow.Record, error) {
+	columns := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, column := range columns {
+			if column != nil {
+				column.Release()
+			}
+		}
+	}()
+	for i, column := range record.Columns() {
+		if dictionary, ok := column.(*array.Dictionary); ok {
+			decoded, err := decodeDictionary(mem, dictionary)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", record.ColumnName(i), err)
+			}
+			columns[i] = decoded
+		} else {
+			column.Retain()
+			columns[i] = column
+		}
+	}
+	return array.NewRecord(schema, columns, record.NumRows()), nil
+}
+
+// decodeDictionary returns the values of a dictionary-encoded array, which
+// must have string or binary values, as IOx tag columns do.
+func decodeDictionary(mem memory.Allocator, dictionary *array.Dictionary) (arrow.Array, error) {
+	builder := array.NewBuilder(mem, dictionary.Dictionary().DataType())
+	defer builder.Release()
+	builder.Reserve(dictionary.Len())
+	for row := 0; row < dictionary.Len(); row++ {
+		if dictionary.IsNull(row) {
+			builder.AppendNull()
+			continue
+		}
+		i := dictionary.GetValueIndex(row)
+		switch values := dictionary.Dictionary().(type) {
+		case *array.String:
+			builder.(*array.StringBuilder).Append(values.Value(i))
+		case *array.LargeString:
+			builder.(*array.LargeStringBuilder).Append(values.Value(i))
+		case *array.Binary:
+			builder.(*array.BinaryBuilder).Append(values.Value(i))
+		default:
+			return nil, fmt.Errorf("unsupported dictionary value type %q", values.DataType().Name())
+		}
+	}
+	return builder.NewArray(), nil
+}
+
+// parquetWriter writes a Parquet file, buffering each row group until it
+// has Options.ParquetRowGroupLength rows. Dictionary-encoded columns are
+// written with their value type, since pqarrow does not support them; the
+// Parquet encoder dictionary-encodes columns again.
+type parquetWriter struct {
+	w      *pqarrow.FileWriter
+	schema *arrow.Schema
+	mem    memory.Allocator
+}
+
+func newParquetWriter(w io.Writer, schema *arrow.Schema, options *Options) (*parquetWriter, error) {
+	codec, ok := compressionCodecs[options.ParquetCompression]
+	if !ok {
+		return nil, fmt.Errorf("unknown parquet compression %q", options.ParquetCompression)
+	}
+	level := compress.DefaultCompressionLevel
+	if options.ParquetCompressionLevel != 0 {
+		level = options.ParquetCompressionLevel
+	}
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(codec),
+		parquet.WithCompressionLevel(level),
+		parquet.WithMaxRowGroupLength(options.ParquetRowGroupLength),
+	)
+	arrowProps := pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema())
+	schema = decodedSchema(schema)
+	// Hide any Close method of w, which pqarrow would call.
+	fw, err := pqarrow.NewFileWriter(schema, struct{ io.Writer }{w}, props, arrowProps)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetWriter{w: fw, schema: schema, mem: options.Allocator}, nil
+}
+
+func (pw *parquetWriter) write(record arrow.Record) error {
+	record, err := decodeRecord(pw.mem, pw.schema, record)
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+	return pw.w.WriteBuffered(record)
+}
+
+func (pw *parquetWriter) close() error {
+	return pw.w.Close()
+}
@@ -0,0 +1,286 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet/compress"
+	"github.com/apache/arrow/go/v10/parquet/file"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/export"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func ExampleQuery() {
+	ctx := context.Background()
+	config := &influxdbiox.ClientConfig{Address: "localhost:8082", Namespace: "mydb"}
+	client, _ := influxdbiox.NewClient(ctx, config)
+	request, _ := client.PrepareQuery(ctx, "", "select * from cpu")
+
+	f, _ := os.Create("cpu.csv")
+	_, _ = export.Query(ctx, f, request, export.CSV, &export.Options{TimeFormat: time.RFC3339})
+	_ = f.Close()
+}
+
+var exportTestSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "time", Type: &arrow.TimestampType{Unit: arrow.Nanosecond}},
+	{Name: "host", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}, Nullable: true},
+	{Name: "usage", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "n", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "ok", Type: arrow.FixedWidthTypes.Boolean},
+}, nil)
+
+// newExportTestRecords returns two records whose host columns have different
+// dictionaries, as IOx sends them.
+func newExportTestRecords(t *testing.T, mem memory.Allocator) []arrow.Record {
+	b := array.NewRecordBuilder(mem, exportTestSchema)
+	defer b.Release()
+
+	b.Field(0).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1618444800000000000, 1618444801500000000}, nil)
+	require.NoError(t, b.Field(1).(*array.BinaryDictionaryBuilder).AppendString("a"))
+	b.Field(1).AppendNull()
+	b.Field(2).(*array.Float64Builder).AppendValues([]float64{0.5, 0}, []bool{true, false})
+	b.Field(3).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	b.Field(4).(*array.BooleanBuilder).AppendValues([]bool{true, false}, nil)
+	record1 := b.NewRecord()
+
+	b.Field(0).(*array.TimestampBuilder).Append(1618444802000000000)
+	require.NoError(t, b.Field(1).(*array.BinaryDictionaryBuilder).AppendString("b,\"c\""))
+	b.Field(2).(*array.Float64Builder).Append(math.NaN())
+	b.Field(3).(*array.Int64Builder).Append(3)
+	b.Field(4).(*array.BooleanBuilder).Append(true)
+	record2 := b.NewRecord()
+
+	return []arrow.Record{record1, record2}
+}
+
+func newExportTestReader(t *testing.T) (array.RecordReader, *memory.CheckedAllocator) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	t.Cleanup(func() { mem.AssertSize(t, 0) })
+
+	records := newExportTestRecords(t, mem)
+	reader, err := array.NewRecordReader(exportTestSchema, records)
+	require.NoError(t, err)
+	for _, record := range records {
+		record.Release()
+	}
+	return reader, mem
+}
+
+func TestWrite_csv(t *testing.T) {
+	reader, _ := newExportTestReader(t)
+	defer reader.Release()
+
+	var buffer bytes.Buffer
+	rows, err := export.Write(&buffer, reader, export.CSV, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, rows)
+	assert.Equal(t, `time,host,usage,n,ok
+2021-04-15T00:00:00Z,a,0.5,1,true
+2021-04-15T00:00:01.5Z,,,2,false
+2021-04-15T00:00:02Z,"b,""c""",NaN,3,true
+`, buffer.String())
+}
+
+func TestWrite_csvOptions(t *testing.T) {
+	reader, _ := newExportTestReader(t)
+	defer reader.Release()
+
+	var buffer bytes.Buffer
+	options := &export.Options{
+		TimeFormat:  time.Kitchen,
+		CSVComma:    '\t',
+		CSVNoHeader: true,
+		CSVNull:     "NULL",
+	}
+	_, err := export.Write(&buffer, reader, export.CSV, options)
+	require.NoError(t, err)
+	assert.Equal(t, "12:00AM\ta\t0.5\t1\ttrue\n12:00AM\tNULL\tNULL\t2\tfalse\n12:00AM\t\"b,\"\"c\"\"\"\tNaN\t3\ttrue\n", buffer.String())
+}
+
+func TestWrite_ndjson(t *testing.T) {
+	reader, _ := newExportTestReader(t)
+	defer reader.Release()
+
+	var buffer bytes.Buffer
+	rows, err := export.Write(&buffer, reader, export.NDJSON, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, rows)
+	assert.Equal(t, `{"time":"2021-04-15T00:00:00Z","host":"a","usage":0.5,"n":1,"ok":true}
+{"time":"2021-04-15T00:00:01.5Z","host":null,"usage":null,"n":2,"ok":false}
+{"time":"2021-04-15T00:00:02Z","host":"b,\"c\"","usage":"NaN","n":3,"ok":true}
+`, buffer.String())
+}
+
+func TestWrite_arrow(t *testing.T) {
+	reader, mem := newExportTestReader(t)
+	defer reader.Release()
+
+	var buffer bytes.Buffer
+	rows, err := export.Write(&buffer, reader, export.ArrowIPC, &export.Options{Allocator: mem})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, rows)
+
+	fileReader, err := ipc.NewFileReader(bytes.NewReader(buffer.Bytes()))
+	require.NoError(t, err)
+	defer func() { _ = fileReader.Close() }()
+	require.Equal(t, 2, fileReader.NumRecords())
+	assert.Equal(t, arrow.BinaryTypes.String, fileReader.Schema().Field(1).Type)
+
+	var hosts []string
+	for i := 0; i < fileReader.NumRecords(); i++ {
+		record, err := fileReader.Record(i)
+		require.NoError(t, err)
+		host := record.Column(1).(*array.String)
+		for row := 0; row < host.Len(); row++ {
+			if host.IsNull(row) {
+				hosts = append(hosts, "NULL")
+			} else {
+				hosts = append(hosts, host.Value(row))
+			}
+		}
+	}
+	assert.Equal(t, []string{"a", "NULL", "b,\"c\""}, hosts)
+}
+
+// closeRecorder is a bytes.Buffer that records whether it was closed.
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWrite_parquet(t *testing.T) {
+	reader, mem := newExportTestReader(t)
+	defer reader.Release()
+
+	var buffer closeRecorder
+	options := &export.Options{
+		ParquetCompression:    export.CompressionZstd,
+		ParquetRowGroupLength: 2,
+		Allocator:             mem,
+	}
+	rows, err := export.Write(&buffer, reader, export.Parquet, options)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, rows)
+	assert.False(t, buffer.closed, "Write closed its writer")
+
+	parquetReader, err := file.NewParquetReader(bytes.NewReader(buffer.Bytes()))
+	require.NoError(t, err)
+	defer func() { _ = parquetReader.Close() }()
+	assert.Equal(t, 2, parquetReader.NumRowGroups())
+	columnChunk, err := parquetReader.MetaData().RowGroup(0).ColumnChunk(0)
+	require.NoError(t, err)
+	assert.Equal(t, compress.Codecs.Zstd, columnChunk.Compression())
+
+	fileReader, err := pqarrow.NewFileReader(parquetReader, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	table, err := fileReader.ReadTable(context.Background())
+	require.NoError(t, err)
+	defer table.Release()
+	assert.EqualValues(t, 3, table.NumRows())
+	assert.Equal(t, []string{"time", "host", "usage", "n", "ok"}, []string{
+		table.Schema().Field(0).Name,
+		table.Schema().Field(1).Name,
+		table.Schema().Field(2).Name,
+		table.Schema().Field(3).Name,
+		table.Schema().Field(4).Name,
+	})
+
+	var hosts []interface{}
+	tableReader := array.NewTableReader(table, -1)
+	defer tableReader.Release()
+	for tableReader.Next() {
+		record := tableReader.Record()
+		for row := 0; row < int(record.NumRows()); row++ {
+			host, err := influxdbiox.ValueFromArrowColumn(record.Column(1), row)
+			require.NoError(t, err)
+			hosts = append(hosts, host)
+		}
+	}
+	assert.Equal(t, []interface{}{"a", nil, "b,\"c\""}, hosts)
+}
+
+func TestQuery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	records := newExportTestRecords(t, memory.DefaultAllocator)
+	server.HandleQuery("select * from cpu", records...)
+	for _, record := range records {
+		record.Release()
+	}
+
+	client, err := influxdbiox.NewClient(ctx, server.ClientConfig("mydb"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	request, err := client.PrepareQuery(ctx, "", "select * from cpu")
+	require.NoError(t, err)
+
+	var buffer bytes.Buffer
+	rows, err := export.Query(ctx, &buffer, request, export.NDJSON, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, rows)
+	assert.Contains(t, buffer.String(), `"host":"b,\"c\""`)
+
+	request, err = client.PrepareQuery(ctx, "", "select nothing")
+	require.NoError(t, err)
+	_, err = export.Query(ctx, &buffer, request, export.CSV, nil)
+	assert.Error(t, err)
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	for filename, expect := range map[string]export.Format{
+		"out.csv":          export.CSV,
+		"out.ndjson":       export.NDJSON,
+		"out.JSONL":        export.NDJSON,
+		"dir.d/out.arrow":  export.ArrowIPC,
+		"out.feather":      export.ArrowIPC,
+		"out.ipc":          export.ArrowIPC,
+		"/tmp/out.parquet": export.Parquet,
+	} {
+		format, err := export.FormatFromFilename(filename)
+		if assert.NoError(t, err, filename) {
+			assert.Equal(t, expect, format, filename)
+		}
+	}
+	for _, filename := range []string{"out", "out.txt", "dir.d/out"} {
+		_, err := export.FormatFromFilename(filename)
+		assert.Error(t, err, filename)
+	}
+
+	_, err := export.ParseFormat("xml")
+	assert.EqualError(t, err, `unknown export format "xml"`)
+	compression, err := export.ParseCompression("")
+	require.NoError(t, err)
+	assert.Equal(t, export.CompressionSnappy, compression)
+	compression, err = export.ParseCompression("Uncompressed")
+	require.NoError(t, err)
+	assert.Equal(t, export.CompressionNone, compression)
+	_, err = export.ParseCompression("lzo")
+	assert.EqualError(t, err, `unknown parquet compression "lzo"`)
+
+	var buffer bytes.Buffer
+	reader, _ := newExportTestReader(t)
+	defer reader.Release()
+	_, err = export.Write(&buffer, reader, export.Parquet, &export.Options{ParquetCompression: "lzo"})
+	assert.EqualError(t, err, `unknown parquet compression "lzo"`)
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+// recordWriter writes records in one export format.
+type recordWriter interface {
+	write(record arrow.Record) error
+	close() error
+}
+
+// csvWriter writes each value with formatText, so that NULL is written as
+// Options.CSVNull, timestamps with Options.TimeFormat, and binary values as
+// standard base64.
+type csvWriter struct {
+	w       *csv.Writer
+	options *Options
+	row     []string
+}
+
+func newCSVWriter(w io.Writer, schema *arrow.Schema, options *Options) (*csvWriter, error) {
+	cw := &csvWriter{
+		w:       csv.NewWriter(w),
+		options: options,
+		row:     make([]string, len(schema.Fields())),
+	}
+	cw.w.Comma = options.CSVComma
+	if !options.CSVNoHeader {
+		for i, field := range schema.Fields() {
+			cw.row[i] = field.Name
+		}
+		if err := cw.w.Write(cw.row); err != nil {
+			return nil, err
+		}
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) write(record arrow.Record) error {
+	for row := 0; row < int(record.NumRows()); row++ {
+		for i, column := range record.Columns() {
+			value, err := influxdbiox.ValueFromArrowColumn(column, row)
+			if err != nil {
+				return fmt.Errorf("column %q: %w", record.ColumnName(i), err)
+			}
+			if value == nil {
+				cw.row[i] = cw.options.CSVNull
+			} else {
+				cw.row[i] = formatText(value, cw.options.TimeFormat)
+			}
+		}
+		if err := cw.w.Write(cw.row); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// formatText formats a non-nil value returned by
+// influxdbiox.ValueFromArrowColumn.
+func formatText(value interface{}, timeFormat string) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(timeFormat)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ndjsonWriter writes each row as a JSON object with members in column
+// order. Timestamps are strings formatted with Options.TimeFormat, binary
+// values are base64 strings, and NaN and infinite floats are written as the
+// strings "NaN", "+Inf" and "-Inf", which JSON numbers cannot represent.
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	options *Options
+	names   [][]byte // JSON-encoded column names
+}
+
+func newNDJSONWriter(w io.Writer, schema *arrow.Schema, options *Options) (*ndjsonWriter, error) {
+	nw := &ndjsonWriter{
+		w:       bufio.NewWriter(w),
+		options: options,
+		names:   make([][]byte, len(schema.Fields())),
+	}
+	for i, field := range schema.Fields() {
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		nw.names[i] = name
+	}
+	return nw, nil
+}
+
+func (nw *ndjsonWriter) write(record arrow.Record) error {
+	for row := 0; row < int(record.NumRows()); row++ {
+		_ = nw.w.WriteByte('{')
+		for i, column := range record.Columns() {
+			value, err := influxdbiox.ValueFromArrowColumn(column, row)
+			if err != nil {
+				return fmt.Errorf("column %q: %w", record.ColumnName(i), err)
+			}
+			switch v := value.(type) {
+			case time.Time:
+				value = v.Format(nw.options.TimeFormat)
+			case float64:
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					value = formatText(v, "")
+				}
+			}
+			b, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("column %q: %w", record.ColumnName(i), err)
+			}
+			if i > 0 {
+				_ = nw.w.WriteByte(',')
+			}
+			_, _ = nw.w.Write(nw.names[i])
+			_ = nw.w.WriteByte(':')
+			_, _ = nw.w.Write(b)
+		}
+		_, _ = nw.w.WriteString("}\n")
+	}
+	return nw.w.Flush()
+}
+
+func (nw *ndjsonWriter) close() error {
+	return nw.w.Flush()
+}
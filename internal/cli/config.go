@@ -0,0 +1,140 @@
+// Package cli contains the client configuration flags shared by the
+// commands in cmd.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+// ConfigSourcesDoc describes where ConfigFlags.ClientConfig gets the client
+// config, for command documentation.
+const ConfigSourcesDoc = `The client config is built from these sources, in increasing order of
+precedence:
+
+  - environment variables INFLUXDB_IOX_ADDRESS, INFLUXDB_IOX_NAMESPACE and
+    the rest, as read by influxdbiox.ClientConfigFromEnv
+  - the config file named by -config, and its profile named by -profile,
+    as read by influxdbiox.ClientConfigFromFile
+  - the data source name given by -dsn, in any format supported by
+    influxdbiox.ClientConfigFromString
+  - flags such as -address, -namespace and -token`
+
+// ConfigFlags are the command-line flags that configure the client.
+type ConfigFlags struct {
+	configFile            string
+	profile               string
+	dsn                   string
+	address               string
+	namespace             string
+	token                 string
+	tls                   bool
+	tlsCA                 string
+	tlsInsecureSkipVerify bool
+	allowInsecureToken    bool
+	writeURL              string
+	timeout               time.Duration
+	flightSQL             bool
+	debug                 bool
+
+	// DialOptions are added to the client config, so that tests can
+	// connect to an ioxtest.Server.
+	DialOptions []grpc.DialOption
+}
+
+// AddConfigFlags defines the client config flags in fs.
+func AddConfigFlags(fs *flag.FlagSet) *ConfigFlags {
+	f := &ConfigFlags{}
+	fs.StringVar(&f.configFile, "config", os.Getenv("INFLUXDB_IOX_CONFIG"), "client config `file` in JSON, TOML or YAML format (default $INFLUXDB_IOX_CONFIG)")
+	fs.StringVar(&f.profile, "profile", os.Getenv("INFLUXDB_IOX_PROFILE"), "`name` of the profile to use from the config file (default $INFLUXDB_IOX_PROFILE)")
+	fs.StringVar(&f.dsn, "dsn", os.Getenv("INFLUXDB_IOX_DSN"), "data source `name` in JSON, URL or address format (default $INFLUXDB_IOX_DSN)")
+	fs.StringVar(&f.address, "address", "", "IOx gRPC `address` as host:port")
+	fs.StringVar(&f.namespace, "namespace", "", "`namespace` to use")
+	fs.StringVar(&f.token, "token", "", "bearer `token` sent with every request")
+	fs.BoolVar(&f.allowInsecureToken, "allow-insecure-token", false, "send the token without TLS")
+	fs.BoolVar(&f.tls, "tls", false, "connect with TLS")
+	fs.StringVar(&f.tlsCA, "tls-ca", "", "PEM `file` of the certificate authority that verifies the server")
+	fs.BoolVar(&f.tlsInsecureSkipVerify, "tls-insecure-skip-verify", false, "do not verify the server certificate")
+	fs.StringVar(&f.writeURL, "write-url", "", "base `URL` of the IOx HTTP write API, such as http://localhost:8080")
+	fs.DurationVar(&f.timeout, "timeout", 0, "timeout for each request, such as 30s")
+	fs.BoolVar(&f.flightSQL, "flight-sql", false, "query with the Arrow Flight SQL protocol")
+	fs.BoolVar(&f.debug, "debug", false, "log client events to stderr")
+	return f
+}
+
+// ClientConfig builds the client config from the environment, config file,
+// DSN and flags, as described by ConfigSourcesDoc. With -debug, client
+// events are logged to stderr.
+func (f *ConfigFlags) ClientConfig(stderr io.Writer) (*influxdbiox.ClientConfig, error) {
+	config, err := influxdbiox.ReadClientConfigFromEnv("")
+	if err != nil {
+		return nil, err
+	}
+	if f.configFile != "" {
+		fileConfig, err := influxdbiox.ReadClientConfigFromFile(f.configFile, f.profile)
+		if err != nil {
+			return nil, err
+		}
+		config.Merge(fileConfig)
+	} else if f.profile != "" {
+		return nil, errors.New("-profile requires -config")
+	}
+	if f.dsn != "" {
+		dsnConfig, err := influxdbiox.ClientConfigFromString(f.dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -dsn: %w", err)
+		}
+		config.Merge(dsnConfig)
+	}
+	config.Merge(&influxdbiox.ClientConfig{
+		Address:               f.address,
+		Namespace:             f.namespace,
+		Token:                 f.token,
+		TLS:                   f.tls,
+		TLSCA:                 f.tlsCA,
+		TLSInsecureSkipVerify: f.tlsInsecureSkipVerify,
+		AllowInsecureToken:    f.allowInsecureToken,
+		WriteURL:              f.writeURL,
+		Timeout:               f.timeout,
+		FlightSQL:             f.flightSQL,
+		DialOptions:           f.DialOptions,
+	})
+	if f.debug {
+		config.Logger = &textLogger{w: stderr}
+	}
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// textLogger is an influxdbiox.Logger that writes each event to w as a line
+// of its time, message and key=value arguments.
+type textLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *textLogger) DebugContext(_ context.Context, msg string, args ...interface{}) {
+	var line strings.Builder
+	fmt.Fprintf(&line, "%s DEBUG %s", time.Now().Format(time.RFC3339Nano), msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&line, " %v=%q", args[i], fmt.Sprint(args[i+1]))
+	}
+	line.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.w, line.String())
+}
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FormatValue formats a value returned by influxdbiox.ValueFromArrowColumn
+// for display: NULL is empty, timestamps are formatted with timeFormat, and
+// binary values are hexadecimal.
+func FormatValue(value interface{}, timeFormat string) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(timeFormat)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		return fmt.Sprintf("%x", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,46 @@
+// Package clitest contains the test fixtures shared by the iox and iox-shell
+// commands.
+package clitest
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+// NewServer starts an ioxtest.Server, which is closed when the test ends.
+// The INFLUXDB_IOX_ environment variables are cleared, so that they do not
+// leak into the test, except for INFLUXDB_IOX_ADDRESS, which is set to an
+// address that commands dial with Server.DialContext.
+func NewServer(t *testing.T) *ioxtest.Server {
+	t.Setenv("INFLUXDB_IOX_ADDRESS", "bufnet:8082")
+	for _, name := range []string{"INFLUXDB_IOX_NAMESPACE", "INFLUXDB_IOX_DSN", "INFLUXDB_IOX_CONFIG", "INFLUXDB_IOX_PROFILE", "INFLUXDB_IOX_WRITE_URL"} {
+		t.Setenv(name, "")
+	}
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// NewRecord returns two rows of a cpu table, with columns time, host and
+// usage; host is NULL in the second row. The record is released when the
+// test ends.
+func NewRecord(t *testing.T) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "time", Type: &arrow.TimestampType{Unit: arrow.Nanosecond}},
+		{Name: "host", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "usage", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Field(0).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1618444800000000000, 1618444801000000000}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"server-a", ""}, []bool{true, false})
+	b.Field(2).(*array.Float64Builder).AppendValues([]float64{0.5, 12.25}, nil)
+	record := b.NewRecord()
+	t.Cleanup(record.Release)
+	return record
+}
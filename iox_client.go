@@ -2,6 +2,8 @@ package influxdbiox
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
 
 	"github.com/apache/arrow/go/v10/arrow/flight"
 	ingester "github.com/influxdata/influxdb-iox-client-go/v2/internal/ingester"
@@ -9,12 +11,44 @@ import (
 	"google.golang.org/grpc/connectivity"
 )
 
+// grpcConn is a *grpc.ClientConn, or a *balancedConn.
+type grpcConn interface {
+	grpc.ClientConnInterface
+	GetState() connectivity.State
+	Close() error
+}
+
 // Client is the primary handle to interact with InfluxDB/IOx.
+//
+// With ClientConfig.TracerProvider, Client creates these spans:
+//
+//   - "influxdbiox.Query" for each QueryRequest.Query, ending with the
+//     result stream, with attributes iox.namespace, iox.query.type,
+//     iox.query.hash (of the query text), iox.query.rows,
+//     iox.query.batches and iox.query.bytes
+//   - "influxdbiox.WaitForToken" for each WaitForDurable, WaitForReadable
+//     and WaitForPersisted, with attribute iox.write_token.condition
+//   - one span for each gRPC request, such as
+//     "arrow.flight.protocol.FlightService/DoGet", named after its method
+//
+// With ClientConfig.MeterProvider, Client records these metrics:
+//
+//   - iox.client.query.duration, a histogram in seconds
+//   - iox.client.query.rows, iox.client.query.batches and
+//     iox.client.query.bytes, counters of query results
+//   - iox.client.write_token.wait.duration, a histogram in seconds
+//
+// Query metrics have attributes iox.namespace, iox.query.type and
+// rpc.grpc.status_code; the wait histogram has iox.write_token.condition and
+// rpc.grpc.status_code.
 type Client struct {
 	config                  *ClientConfig
-	grpcClient              *grpc.ClientConn
+	grpcClient              grpcConn
 	flightClient            flight.FlightServiceClient
 	ingesterWriteInfoClient ingester.WriteInfoServiceClient
+	httpClient              *http.Client
+	tlsConfig               *tls.Config // shared by every connection
+	telemetry               *telemetry
 }
 
 // NewClient instantiates a connection with the InfluxDB/IOx gRPC services.
@@ -23,8 +57,19 @@ type Client struct {
 // ClientConfig.DialOptions includes grpc.WithBlock.
 // For use of the context.Context object in this function, see grpc.DialContext.
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
+	tlsConfig, err := config.getTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	telemetry, err := newTelemetry(config)
+	if err != nil {
+		return nil, err
+	}
 	c := &Client{
-		config: config,
+		config:     config,
+		httpClient: config.newHTTPClient(tlsConfig),
+		tlsConfig:  tlsConfig,
+		telemetry:  telemetry,
 	}
 	if err := c.Reconnect(ctx); err != nil {
 		return nil, err
@@ -35,11 +80,13 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 // Reconnect closes the gRPC connection, if open, and creates a new connection.
 func (c *Client) Reconnect(ctx context.Context) error {
 	if c.grpcClient != nil {
+		c.telemetry.logger.DebugContext(ctx, "reconnecting to IOx", "state", c.grpcClient.GetState().String())
 		_ = c.grpcClient.Close()
 	}
 
-	grpcClient, err := c.config.newGRPCClient(ctx)
+	grpcClient, err := c.config.newGRPCClient(ctx, c.telemetry, c.tlsConfig)
 	if err != nil {
+		c.telemetry.logger.DebugContext(ctx, "failed to connect to IOx", "error", err)
 		return err
 	}
 	c.grpcClient = grpcClient
@@ -49,7 +96,13 @@ func (c *Client) Reconnect(ctx context.Context) error {
 	return nil
 }
 
-// GetState gets the state of the wrapped gRPC client.
+// Config returns the ClientConfig used to create this Client.
+func (c *Client) Config() *ClientConfig {
+	return c.config
+}
+
+// GetState gets the state of the wrapped gRPC client. With several
+// addresses, the state is connectivity.Ready if any connection is ready.
 func (c *Client) GetState() connectivity.State {
 	return c.grpcClient.GetState()
 }
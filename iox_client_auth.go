@@ -0,0 +1,104 @@
+package influxdbiox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// TokenSource supplies the authorization token sent with each request.
+// Implementations must be safe for concurrent use.
+type TokenSource interface {
+	// Token returns the current token.
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc is an adapter to allow the use of ordinary functions as a
+// TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token calls f(ctx).
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// RefreshingTokenSource is a TokenSource for rotating credentials.
+// It caches the token returned by Refresh until shortly before the token
+// expires, then calls Refresh again.
+type RefreshingTokenSource struct {
+	// Refresh fetches a new token and its expiry time. A zero expiry time
+	// means the token does not expire.
+	Refresh func(ctx context.Context) (token string, expiry time.Time, err error)
+	// Refresh the token this long before it expires; default 10s
+	EarlyExpiry time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	valid  bool
+}
+
+// Token returns the cached token, refreshing it if it has expired.
+func (s *RefreshingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	earlyExpiry := s.EarlyExpiry
+	if earlyExpiry <= 0 {
+		earlyExpiry = 10 * time.Second
+	}
+	if s.valid && (s.expiry.IsZero() || time.Now().Add(earlyExpiry).Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	if s.Refresh == nil {
+		return "", errors.New("RefreshingTokenSource.Refresh is nil")
+	}
+	token, expiry, err := s.Refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.expiry, s.valid = token, expiry, true
+	return token, nil
+}
+
+// Invalidate discards the cached token, so that the next call to Token
+// refreshes it. Call it when the server rejects the token.
+func (s *RefreshingTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token, s.expiry, s.valid = "", time.Time{}, false
+}
+
+// errInsecureToken is returned when a token would be sent without TLS.
+var errInsecureToken = errors.New("a token requires TLS; configure TLS, or set AllowInsecureToken to send it in plaintext")
+
+// tokenCredentials sends the token from a TokenSource as a bearer token with
+// every gRPC request.
+type tokenCredentials struct {
+	source                   TokenSource
+	requireTransportSecurity bool
+}
+
+var _ credentials.PerRPCCredentials = tokenCredentials{}
+
+func (c tokenCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		// Without a status code, gRPC would report codes.Unavailable, and
+		// requests could wait for the connection to become ready.
+		return nil, status.Errorf(codes.Unauthenticated, "failed to get authorization token: %s", err)
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity returns true unless ClientConfig.AllowInsecureToken
+// is set, for plaintext connections to local gateways.
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return c.requireTransportSecurity
+}
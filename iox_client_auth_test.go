@@ -0,0 +1,145 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func ExampleRefreshingTokenSource() {
+	config := &influxdbiox.ClientConfig{
+		Address: "localhost:8082",
+		TokenSource: &influxdbiox.RefreshingTokenSource{
+			Refresh: func(ctx context.Context) (string, time.Time, error) {
+				// Fetch a short-lived token from a secret store.
+				return "my-token", time.Now().Add(time.Hour), nil
+			},
+		},
+	}
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+	_ = client.Handshake(context.Background())
+}
+
+func TestClientConfig_Token(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.RequireToken("secret")
+	server.AddNamespace("mydb")
+
+	client := newTestClient(ctx, t, server.ClientConfig("mydb"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(client.Handshake(ctx)))
+
+	// The server connection is in memory, without TLS.
+	config := server.ClientConfig("mydb")
+	config.Token = "secret"
+	config.AllowInsecureToken = false
+	_, err := influxdbiox.NewClient(ctx, config)
+	assert.ErrorContains(t, err, "a token requires TLS")
+
+	config = server.ClientConfig("mydb")
+	config.Token = "secret"
+	client = newTestClient(ctx, t, config)
+	require.NoError(t, client.Handshake(ctx))
+	_, err = client.GetNamespaceSchema(ctx, "mydb")
+	require.NoError(t, err)
+}
+
+func TestClientConfig_TokenSource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.RequireToken("first")
+
+	var refreshes int
+	tokenSource := &influxdbiox.RefreshingTokenSource{
+		Refresh: func(ctx context.Context) (string, time.Time, error) {
+			refreshes++
+			if refreshes == 1 {
+				return "first", time.Time{}, nil
+			}
+			return "second", time.Now().Add(time.Hour), nil
+		},
+	}
+	config := server.ClientConfig("mydb")
+	config.Token = "ignored"
+	config.TokenSource = tokenSource
+	client := newTestClient(ctx, t, config)
+
+	require.NoError(t, client.Handshake(ctx))
+	require.NoError(t, client.Handshake(ctx))
+	assert.Equal(t, 1, refreshes)
+
+	// Rotate the token.
+	server.RequireToken("second")
+	assert.Equal(t, codes.Unauthenticated, status.Code(client.Handshake(ctx)))
+	tokenSource.Invalidate()
+	require.NoError(t, client.Handshake(ctx))
+	assert.Equal(t, 2, refreshes)
+
+	config = server.ClientConfig("mydb")
+	config.TokenSource = influxdbiox.TokenSourceFunc(func(context.Context) (string, error) {
+		return "", errors.New("secret store unavailable")
+	})
+	client = newTestClient(ctx, t, config)
+	assert.ErrorContains(t, client.Handshake(ctx), "secret store unavailable")
+}
+
+func TestClient_Write_token(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	// A token requires TLS, unless AllowInsecureToken is set.
+	config := &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "myorg_mybucket",
+		WriteURL:  server.URL,
+		Token:     "secret",
+	}
+	_, err := influxdbiox.NewClient(ctx, config)
+	assert.ErrorContains(t, err, "a token requires TLS")
+	config.AllowInsecureToken = true
+	client := newTestClient(ctx, t, config)
+
+	_, err = client.Write(ctx, "", []byte("t v=1i 1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", authorization)
+
+	// The gRPC connection uses TLS, but writes do not.
+	authorization = ""
+	config = &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "myorg_mybucket",
+		WriteURL:  server.URL,
+		Token:     "secret",
+		TLS:       true,
+	}
+	client, err = influxdbiox.NewClient(ctx, config)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	_, err = client.Write(ctx, "", []byte("t v=1i 1\n"))
+	assert.ErrorContains(t, err, "a token requires TLS")
+	assert.Empty(t, authorization)
+}
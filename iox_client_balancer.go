@@ -0,0 +1,527 @@
+package influxdbiox
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// LoadBalancingPolicy defines how a Client with several addresses chooses
+// the address for each request.
+type LoadBalancingPolicy string
+
+const (
+	// LoadBalancingRoundRobin sends each request to the next healthy address
+	// in turn. This is the default.
+	LoadBalancingRoundRobin LoadBalancingPolicy = "round_robin"
+	// LoadBalancingLeastOutstanding sends each request to the healthy address
+	// with the fewest requests in progress, including unfinished queries.
+	LoadBalancingLeastOutstanding LoadBalancingPolicy = "least_outstanding"
+	// LoadBalancingFailover sends every request to one healthy address,
+	// starting with ClientConfig.Address. When that address fails, requests
+	// are sent to the next healthy address, which is then used until it
+	// fails; requests do not move back when a failed address recovers.
+	LoadBalancingFailover LoadBalancingPolicy = "failover"
+)
+
+// ParseLoadBalancingPolicy parses the names of the LoadBalancingPolicy
+// constants. The empty string is parsed as LoadBalancingRoundRobin.
+func ParseLoadBalancingPolicy(s string) (LoadBalancingPolicy, error) {
+	switch policy := LoadBalancingPolicy(strings.ToLower(s)); policy {
+	case "":
+		return LoadBalancingRoundRobin, nil
+	case LoadBalancingRoundRobin, LoadBalancingLeastOutstanding, LoadBalancingFailover:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown load balancing policy %q", s)
+	}
+}
+
+const (
+	// DNSAddressScheme prefixes an address, as in "dns:///iox.example.com:8082",
+	// whose host name is resolved to every IP address in its DNS records.
+	DNSAddressScheme = "dns:///"
+	// DNSSRVAddressScheme prefixes a DNS SRV record name, as in
+	// "dns+srv:///_iox._tcp.example.com", which is resolved to the host:port
+	// of every target in the record.
+	DNSSRVAddressScheme = "dns+srv:///"
+
+	defaultHealthCheckInterval = 10 * time.Second
+)
+
+// Replaced by tests.
+var (
+	lookupHost = net.DefaultResolver.LookupHost
+	lookupSRV  = net.DefaultResolver.LookupSRV
+)
+
+// addresses returns Address followed by Addresses.
+func (dc *ClientConfig) addresses() []string {
+	return append([]string{dc.Address}, dc.Addresses...)
+}
+
+// balanced reports whether requests are balanced across several addresses.
+func (dc *ClientConfig) balanced() bool {
+	if len(dc.Addresses) > 0 {
+		return true
+	}
+	return strings.HasPrefix(dc.Address, DNSAddressScheme) || strings.HasPrefix(dc.Address, DNSSRVAddressScheme)
+}
+
+// validateAddress checks an address in any of the formats accepted by
+// ClientConfig.Address.
+func validateAddress(address string) error {
+	switch {
+	case strings.HasPrefix(address, DNSSRVAddressScheme):
+		if strings.TrimPrefix(address, DNSSRVAddressScheme) == "" {
+			return fmt.Errorf("address %s: missing SRV record name", address)
+		}
+		return nil
+	case strings.HasPrefix(address, DNSAddressScheme):
+		_, _, err := net.SplitHostPort(strings.TrimPrefix(address, DNSAddressScheme))
+		return err
+	default:
+		_, _, err := net.SplitHostPort(address)
+		return err
+	}
+}
+
+// resolvedAddress is an address to dial, with the authority to use for TLS
+// and the :authority header, if it differs from the address.
+type resolvedAddress struct {
+	address   string
+	authority string
+}
+
+// resolveAddresses resolves every DNS and DNS SRV address, removing
+// duplicates.
+func resolveAddresses(ctx context.Context, addresses []string) ([]resolvedAddress, error) {
+	var resolved []resolvedAddress
+	seen := make(map[resolvedAddress]bool)
+	add := func(r resolvedAddress) {
+		if !seen[r] {
+			seen[r] = true
+			resolved = append(resolved, r)
+		}
+	}
+
+	for _, address := range addresses {
+		switch {
+		case strings.HasPrefix(address, DNSSRVAddressScheme):
+			_, records, err := lookupSRV(ctx, "", "", strings.TrimPrefix(address, DNSSRVAddressScheme))
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve address %s: %w", address, err)
+			}
+			for _, record := range records {
+				add(resolvedAddress{address: net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))})
+			}
+		case strings.HasPrefix(address, DNSAddressScheme):
+			authority := strings.TrimPrefix(address, DNSAddressScheme)
+			host, port, err := net.SplitHostPort(authority)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve address %s: %w", address, err)
+			}
+			ips, err := lookupHost(ctx, host)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve address %s: %w", address, err)
+			}
+			for _, ip := range ips {
+				add(resolvedAddress{address: net.JoinHostPort(ip, port), authority: authority})
+			}
+		default:
+			add(resolvedAddress{address: address})
+		}
+	}
+
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("addresses %s resolved to no hosts", strings.Join(addresses, ", "))
+	}
+	return resolved, nil
+}
+
+// endpoint is a connection to one resolved address.
+type endpoint struct {
+	resolvedAddress
+	conn         *grpc.ClientConn
+	flightClient flight.FlightServiceClient
+
+	outstanding int64 // requests in progress, accessed atomically
+	unhealthy   int32 // 1 if ejected, accessed atomically
+}
+
+func (e *endpoint) healthy() bool {
+	return atomic.LoadInt32(&e.unhealthy) == 0
+}
+
+func (e *endpoint) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&e.unhealthy, 0)
+	} else {
+		atomic.StoreInt32(&e.unhealthy, 1)
+	}
+}
+
+// balancedConn implements grpc.ClientConnInterface by sending each request
+// to one of several connections, chosen by a LoadBalancingPolicy.
+//
+// Every ClientConfig.HealthCheckInterval, DNS addresses are resolved again,
+// and each connection is checked with a Flight Handshake. Connections that
+// fail the check, or fail a request with codes.Unavailable, are ejected:
+// they receive no requests until they pass a check. If every connection is
+// ejected, requests are sent to all of them.
+//
+// Unary requests are retried according to ClientConfig.RetryPolicy, each
+// attempt choosing a connection again, so that a retry after
+// codes.Unavailable fails over to another connection.
+type balancedConn struct {
+	addresses           []string
+	policy              LoadBalancingPolicy
+	retryPolicy         *RetryPolicy
+	timeout             time.Duration
+	healthCheckInterval time.Duration
+	dialOptions         []grpc.DialOption
+	logger              Logger
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int       // index of the next endpoint for round robin
+	current   *endpoint // endpoint in use for failover
+	closed    bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ grpc.ClientConnInterface = (*balancedConn)(nil)
+
+func newBalancedConn(ctx context.Context, dc *ClientConfig, dialOptions []grpc.DialOption, logger Logger) (*balancedConn, error) {
+	b := &balancedConn{
+		addresses:           dc.addresses(),
+		policy:              dc.LoadBalancing,
+		retryPolicy:         dc.RetryPolicy,
+		timeout:             dc.Timeout,
+		healthCheckInterval: dc.HealthCheckInterval,
+		dialOptions:         dialOptions,
+		logger:              logger,
+		stop:                make(chan struct{}),
+		done:                make(chan struct{}),
+	}
+	if b.healthCheckInterval == 0 {
+		b.healthCheckInterval = defaultHealthCheckInterval
+	}
+
+	resolved, err := resolveAddresses(ctx, b.addresses)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.updateEndpoints(ctx, resolved); err != nil {
+		return nil, err
+	}
+
+	if b.healthCheckInterval > 0 {
+		go b.healthCheckLoop()
+	} else {
+		close(b.done)
+	}
+	return b, nil
+}
+
+// updateEndpoints connects to new addresses, and disconnects from addresses
+// that are no longer resolved. It is not called concurrently.
+func (b *balancedConn) updateEndpoints(ctx context.Context, resolved []resolvedAddress) error {
+	b.mu.Lock()
+	removed := make(map[resolvedAddress]*endpoint, len(b.endpoints))
+	for _, e := range b.endpoints {
+		removed[e.resolvedAddress] = e
+	}
+	b.mu.Unlock()
+
+	var endpoints, created []*endpoint
+	for _, r := range resolved {
+		if e, ok := removed[r]; ok {
+			endpoints = append(endpoints, e)
+			delete(removed, r)
+			continue
+		}
+		e, err := b.dial(ctx, r)
+		if err != nil {
+			closeEndpoints(created)
+			return err
+		}
+		endpoints = append(endpoints, e)
+		created = append(created, e)
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		closeEndpoints(created)
+		return nil
+	}
+	b.endpoints = endpoints
+	if b.current != nil && removed[b.current.resolvedAddress] == b.current {
+		b.current = nil
+	}
+	b.mu.Unlock()
+
+	// Requests in progress to removed endpoints are canceled.
+	for _, e := range removed {
+		_ = e.conn.Close()
+	}
+	return nil
+}
+
+func (b *balancedConn) dial(ctx context.Context, r resolvedAddress) (*endpoint, error) {
+	dialOptions := b.dialOptions
+	if r.authority != "" {
+		dialOptions = append(dialOptions[:len(dialOptions):len(dialOptions)], grpc.WithAuthority(r.authority))
+	}
+	b.logger.DebugContext(ctx, "dialing IOx endpoint", "address", r.address, "authority", r.authority)
+	conn, err := grpc.DialContext(ctx, r.address, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return &endpoint{
+		resolvedAddress: r,
+		conn:            conn,
+		flightClient:    flight.NewFlightServiceClient(conn),
+	}, nil
+}
+
+func closeEndpoints(endpoints []*endpoint) {
+	for _, e := range endpoints {
+		_ = e.conn.Close()
+	}
+}
+
+func (b *balancedConn) healthCheckLoop() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		b.healthCheck()
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// healthCheck resolves the addresses again, and checks every endpoint.
+func (b *balancedConn) healthCheck() {
+	ctx, cancel := context.WithTimeout(withoutTracing(context.Background()), b.healthCheckInterval)
+	defer cancel()
+	go func() {
+		select {
+		case <-b.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// If resolution fails, the previous endpoints remain in use.
+	if resolved, err := resolveAddresses(ctx, b.addresses); err != nil {
+		b.logger.DebugContext(ctx, "failed to resolve IOx addresses", "error", err)
+	} else if err = b.updateEndpoints(ctx, resolved); err != nil {
+		b.logger.DebugContext(ctx, "failed to connect to resolved IOx addresses", "error", err)
+	}
+
+	b.mu.Lock()
+	endpoints := b.endpoints
+	b.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			err := handshake(ctx, e.flightClient)
+			if err != nil && ctx.Err() == nil {
+				b.logger.DebugContext(ctx, "IOx endpoint failed health check", "address", e.address, "error", err)
+			}
+			e.setHealthy(err == nil)
+		}(e)
+	}
+	wg.Wait()
+}
+
+// pick chooses the endpoint for a request, which must be passed to release
+// when the request is done.
+func (b *balancedConn) pick() *endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	candidates := make([]*endpoint, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		if e.healthy() {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = b.endpoints
+	}
+
+	var picked *endpoint
+	switch b.policy {
+	case LoadBalancingFailover:
+		if b.current == nil || !b.current.healthy() {
+			// Move to the next healthy endpoint after the current one, in
+			// the order of the configured addresses.
+			start := 0
+			for i, e := range b.endpoints {
+				if e == b.current {
+					start = i + 1
+				}
+			}
+			b.current = candidates[0]
+			for i := 0; i < len(b.endpoints); i++ {
+				if e := b.endpoints[(start+i)%len(b.endpoints)]; e.healthy() {
+					b.current = e
+					break
+				}
+			}
+		}
+		picked = b.current
+	case LoadBalancingLeastOutstanding:
+		// Ties are broken in round robin order.
+		for i := range candidates {
+			e := candidates[(b.next+i)%len(candidates)]
+			if picked == nil || atomic.LoadInt64(&e.outstanding) < atomic.LoadInt64(&picked.outstanding) {
+				picked = e
+			}
+		}
+		b.next++
+	default:
+		picked = candidates[b.next%len(candidates)]
+		b.next++
+	}
+
+	atomic.AddInt64(&picked.outstanding, 1)
+	return picked
+}
+
+// release records the end of a request to e, and observes its error.
+func (b *balancedConn) release(e *endpoint, err error) {
+	atomic.AddInt64(&e.outstanding, -1)
+	b.observe(e, err)
+}
+
+// observe ejects e if err is codes.Unavailable, until e passes a health
+// check.
+func (b *balancedConn) observe(e *endpoint, err error) {
+	if status.Code(err) == codes.Unavailable && b.healthCheckInterval > 0 && e.healthy() {
+		b.logger.DebugContext(context.Background(), "ejecting IOx endpoint", "address", e.address, "error", err)
+		e.setHealthy(false)
+	}
+}
+
+func (b *balancedConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	// The timeout applies to all attempts.
+	ctx, cancel := withDefaultTimeout(ctx, b.timeout)
+	defer cancel()
+	r := newRetryer(b.retryPolicy, b.logger, method)
+	for {
+		e := b.pick()
+		err := e.conn.Invoke(ctx, method, args, reply, opts...)
+		b.release(e, err)
+		if err == nil || !r.retry(ctx, err) {
+			return err
+		}
+	}
+}
+
+func (b *balancedConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	e := b.pick()
+	stream, err := e.conn.NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		b.release(e, err)
+		return nil, err
+	}
+	s := &balancedClientStream{ClientStream: stream, b: b, e: e}
+	// gRPC cancels the stream context when the stream finishes, whether it
+	// is read to the end, fails or is canceled by the caller, which releases
+	// the endpoint.
+	go func() {
+		<-stream.Context().Done()
+		s.done()
+	}()
+	return s, nil
+}
+
+// GetState returns connectivity.Ready if any connection is ready, or else
+// the most hopeful state of any connection.
+func (b *balancedConn) GetState() connectivity.State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return connectivity.Shutdown
+	}
+	state := connectivity.Shutdown
+	rank := map[connectivity.State]int{
+		connectivity.Shutdown:         0,
+		connectivity.TransientFailure: 1,
+		connectivity.Idle:             2,
+		connectivity.Connecting:       3,
+		connectivity.Ready:            4,
+	}
+	for _, e := range b.endpoints {
+		if s := e.conn.GetState(); rank[s] > rank[state] {
+			state = s
+		}
+	}
+	return state
+}
+
+// Close stops health checks and closes every connection.
+func (b *balancedConn) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	endpoints := b.endpoints
+	b.mu.Unlock()
+
+	close(b.stop)
+	<-b.done
+	var err error
+	for _, e := range endpoints {
+		if closeErr := e.conn.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// balancedClientStream releases its endpoint when the stream ends.
+type balancedClientStream struct {
+	grpc.ClientStream
+	b    *balancedConn
+	e    *endpoint
+	once sync.Once
+}
+
+func (s *balancedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.b.observe(s.e, err)
+		s.done()
+	}
+	return err
+}
+
+func (s *balancedClientStream) done() {
+	s.once.Do(func() { s.b.release(s.e, nil) })
+}
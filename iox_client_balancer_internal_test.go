@@ -0,0 +1,64 @@
+package influxdbiox
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAddresses(t *testing.T) {
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		switch host {
+		case "iox.example.com":
+			return []string{"10.0.0.1", "10.0.0.2", "fd00::1"}, nil
+		default:
+			return nil, errors.New("no such host")
+		}
+	}
+	lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		assert.Empty(t, service)
+		assert.Empty(t, proto)
+		switch name {
+		case "_iox._tcp.example.com":
+			return name, []*net.SRV{
+				{Target: "iox-1.example.com.", Port: 8082},
+				{Target: "iox-2.example.com.", Port: 9000},
+			}, nil
+		case "_empty._tcp.example.com":
+			return name, nil, nil
+		default:
+			return "", nil, errors.New("no such host")
+		}
+	}
+	t.Cleanup(func() {
+		lookupHost = net.DefaultResolver.LookupHost
+		lookupSRV = net.DefaultResolver.LookupSRV
+	})
+	ctx := context.Background()
+
+	resolved, err := resolveAddresses(ctx, []string{
+		"localhost:8082",
+		"dns:///iox.example.com:8082",
+		"dns+srv:///_iox._tcp.example.com",
+		"iox-1.example.com:8082",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []resolvedAddress{
+		{address: "localhost:8082"},
+		{address: "10.0.0.1:8082", authority: "iox.example.com:8082"},
+		{address: "10.0.0.2:8082", authority: "iox.example.com:8082"},
+		{address: "[fd00::1]:8082", authority: "iox.example.com:8082"},
+		{address: "iox-1.example.com:8082"},
+		{address: "iox-2.example.com:9000"},
+	}, resolved)
+
+	_, err = resolveAddresses(ctx, []string{"localhost:8082", "dns:///iox.invalid:8082"})
+	assert.EqualError(t, err, "failed to resolve address dns:///iox.invalid:8082: no such host")
+
+	_, err = resolveAddresses(ctx, []string{"dns+srv:///_empty._tcp.example.com"})
+	assert.EqualError(t, err, "addresses dns+srv:///_empty._tcp.example.com resolved to no hosts")
+}
@@ -0,0 +1,231 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func ExampleLoadBalancingPolicy() {
+	config := &influxdbiox.ClientConfig{
+		Address:       "iox-1.example.com:8082",
+		Addresses:     []string{"iox-2.example.com:8082", "dns:///iox-pool.example.com:8082"},
+		Namespace:     "mydb",
+		LoadBalancing: influxdbiox.LoadBalancingLeastOutstanding,
+	}
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+	_, _ = client.ListTables(context.Background(), "mydb")
+}
+
+// newBalancerTestServers starts n servers, which answer every query, at
+// addresses "server-0:8082", "server-1:8082", and so on.
+func newBalancerTestServers(t *testing.T, n int) ([]*ioxtest.Server, *influxdbiox.ClientConfig) {
+	record := newRetryTestRecord(1, 2, 3)
+	defer record.Release()
+
+	var servers []*ioxtest.Server
+	config := &influxdbiox.ClientConfig{Namespace: "mydb"}
+	for i := 0; i < n; i++ {
+		server := ioxtest.NewServer()
+		t.Cleanup(server.Close)
+		record.Retain()
+		server.HandleDefault(record)
+		servers = append(servers, server)
+
+		address := fmt.Sprintf("server-%d:8082", i)
+		if i == 0 {
+			config.Address = address
+		} else {
+			config.Addresses = append(config.Addresses, address)
+		}
+	}
+
+	dialer := func(ctx context.Context, address string) (net.Conn, error) {
+		var i int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(address, ":8082"), "server-%d", &i); err != nil || i >= n {
+			return nil, fmt.Errorf("unknown address %s", address)
+		}
+		return servers[i].DialContext(ctx, address)
+	}
+	config.DialOptions = []grpc.DialOption{grpc.WithContextDialer(dialer)}
+	return servers, config
+}
+
+func balancerTestQueryCounts(servers []*ioxtest.Server) []int {
+	counts := make([]int, len(servers))
+	for i, server := range servers {
+		counts[i] = len(server.Queries())
+	}
+	return counts
+}
+
+func TestClient_loadBalancing_roundRobin(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	servers, config := newBalancerTestServers(t, 2)
+	client := newTestClient(ctx, t, config)
+
+	for i := 0; i < 4; i++ {
+		values, err := readRetryTestQuery(ctx, t, client)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, values)
+	}
+	assert.Equal(t, []int{2, 2}, balancerTestQueryCounts(servers))
+	assert.Equal(t, connectivity.Ready, client.GetState())
+
+	require.NoError(t, client.Close())
+	assert.Equal(t, connectivity.Shutdown, client.GetState())
+}
+
+func TestClient_loadBalancing_leastOutstanding(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	servers, config := newBalancerTestServers(t, 2)
+	config.LoadBalancing = influxdbiox.LoadBalancingLeastOutstanding
+	client := newTestClient(ctx, t, config)
+
+	// The first query is held open on server 0.
+	heldCtx, cancelHeld := context.WithCancel(ctx)
+	request, err := client.PrepareQuery(heldCtx, "", "select v from t")
+	require.NoError(t, err)
+	held, err := request.Query(heldCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, balancerTestQueryCounts(servers))
+
+	for i := 0; i < 2; i++ {
+		_, err = readRetryTestQuery(ctx, t, client)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{1, 2}, balancerTestQueryCounts(servers))
+
+	held.Release()
+	cancelHeld()
+
+	// Once the held query ends, the servers are balanced again.
+	require.Eventually(t, func() bool {
+		_, err = readRetryTestQuery(ctx, t, client)
+		require.NoError(t, err)
+		counts := balancerTestQueryCounts(servers)
+		return counts[0] > 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestClient_loadBalancing_failover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	servers, config := newBalancerTestServers(t, 3)
+	config.LoadBalancing = influxdbiox.LoadBalancingFailover
+	config.RetryPolicy = &influxdbiox.RetryPolicy{InitialBackoff: time.Millisecond}
+	client := newTestClient(ctx, t, config)
+
+	for i := 0; i < 2; i++ {
+		_, err := readRetryTestQuery(ctx, t, client)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{2, 0, 0}, balancerTestQueryCounts(servers))
+
+	// The retry fails over to the next server, which is then used.
+	servers[0].InjectFailure(ioxtest.Failure{Method: "DoGet", Err: status.Error(codes.Unavailable, "restarting"), Count: 1})
+	for i := 0; i < 3; i++ {
+		_, err := readRetryTestQuery(ctx, t, client)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{2, 3, 0}, balancerTestQueryCounts(servers))
+}
+
+func TestClient_loadBalancing_healthCheck(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	servers, config := newBalancerTestServers(t, 2)
+	config.HealthCheckInterval = 10 * time.Millisecond
+	client := newTestClient(ctx, t, config)
+
+	_, err := readRetryTestQuery(ctx, t, client)
+	require.NoError(t, err)
+
+	// Server 0 fails its health checks, and is ejected.
+	servers[0].Close()
+	require.Eventually(t, func() bool {
+		for i := 0; i < 4; i++ {
+			if _, err := readRetryTestQuery(ctx, t, client); err != nil {
+				return false
+			}
+		}
+		return true
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, connectivity.Ready, client.GetState())
+
+	// Without health checks, failed servers are not ejected.
+	servers, config = newBalancerTestServers(t, 2)
+	config.HealthCheckInterval = -1
+	client = newTestClient(ctx, t, config)
+	servers[0].Close()
+	var failed int
+	for i := 0; i < 4; i++ {
+		if _, err = readRetryTestQuery(ctx, t, client); err != nil {
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+			failed++
+		}
+	}
+	assert.Equal(t, 2, failed)
+}
+
+func TestClient_loadBalancing_unresolved(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	_, err := influxdbiox.NewClient(ctx, &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Addresses: []string{"dns:///iox.invalid:8082"},
+	})
+	assert.ErrorContains(t, err, "failed to resolve address dns:///iox.invalid:8082")
+}
+
+func TestClientConfig_loadBalancing(t *testing.T) {
+	config, err := influxdbiox.ClientConfigFromJSONString(`{
+  "address": "iox-1:8082",
+  "addresses": ["iox-2:8082", "dns+srv:///_iox._tcp.example.com"],
+  "load_balancing": "failover",
+  "health_check_interval": "30s"
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, &influxdbiox.ClientConfig{
+		Address:             "iox-1:8082",
+		Addresses:           []string{"iox-2:8082", "dns+srv:///_iox._tcp.example.com"},
+		LoadBalancing:       influxdbiox.LoadBalancingFailover,
+		HealthCheckInterval: 30 * time.Second,
+	}, config)
+	require.NoError(t, config.Validate())
+
+	s, err := config.ToJSONString()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"address":"iox-1:8082","addresses":["iox-2:8082","dns+srv:///_iox._tcp.example.com"],"load_balancing":"failover","health_check_interval":"30s"}`, s)
+
+	config.Addresses = append(config.Addresses, "iox-3", "dns:///iox.example.com")
+	config.LoadBalancing = "random"
+	assert.EqualError(t, config.Validate(), "invalid client config: "+
+		"invalid address: address iox-3: missing port in address; "+
+		"invalid address: address iox.example.com: missing port in address; "+
+		`unknown load balancing policy "random"`)
+
+	policy, err := influxdbiox.ParseLoadBalancingPolicy("Least_Outstanding")
+	require.NoError(t, err)
+	assert.Equal(t, influxdbiox.LoadBalancingLeastOutstanding, policy)
+}
@@ -4,47 +4,129 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net"
+	"net/http"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc/credentials/insecure"
 
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 )
 
 // ClientConfig contains all the options used to establish a connection.
 type ClientConfig struct {
-	// Address string as host:port
+	// Address string as host:port; or "dns:///host:port" to connect to every
+	// IP address of host, or "dns+srv:///name" to connect to every target of
+	// a DNS SRV record, balancing requests as with Addresses
 	Address string `json:"address"`
+	// Addresses of more IOx servers, in the same formats as Address; requests
+	// are balanced across all addresses according to LoadBalancing
+	Addresses []string `json:"addresses,omitempty"`
+	// How requests are balanced across several addresses: "round_robin"
+	// (default), "least_outstanding" or "failover"
+	LoadBalancing LoadBalancingPolicy `json:"load_balancing,omitempty"`
+	// Interval between health checks of each address with a Flight
+	// Handshake, and between DNS resolutions, when there are several
+	// addresses; default 10s. Negative disables health checks.
+	// Serialized to JSON as a duration string, such as "10s".
+	HealthCheckInterval time.Duration `json:"health_check_interval,omitempty"`
 	// Default namespace; optional unless using sql.Open
 	Namespace string `json:"namespace,omitempty"`
+	// Default query language for prepared queries; "sql" (default) or "influxql"
+	QueryType QueryType `json:"query_type,omitempty"`
+	// Use the Arrow Flight SQL protocol for queries, instead of the
+	// IOx-specific DoGet ticket
+	FlightSQL bool `json:"flight_sql,omitempty"`
+	// Timeout for each request whose context has no deadline, including
+	// reading the complete result of a query; zero means no timeout.
+	// Serialized to JSON as a duration string, such as "30s".
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Retry idempotent requests after transient failures, such as
+	// codes.Unavailable while an IOx querier restarts; nil disables retries.
+	// See RetryPolicy for the requests that are retried.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
 
+	// Use TLS, verifying the server certificate with the system root
+	// certificate authorities unless other TLS fields are set
+	TLS bool `json:"tls,omitempty"`
 	// Filename containing PEM encoded certificate for root certificate authority
 	// to use when verifying server certificates.
 	TLSCA string `json:"tls_ca,omitempty"`
 	// Filename of certificate to present to service. TODO say more here
 	TLSCert string `json:"tls_cert,omitempty"`
 	TLSKey  string `json:"tls_key,omitempty"`
+	// TLSCA, TLSCert and TLSKey are reloaded when they change, for example
+	// when short-lived certificates are rotated, and used by new connections.
+	// If a changed file cannot be loaded, OnTLSReloadError is called with the
+	// error, and the previous files remain in use until the files change
+	// again.
+	OnTLSReloadError func(err error) `json:"-"`
 	// Do not verify the server's certificate chain and host name
 	TLSInsecureSkipVerify bool `json:"tls_insecure_skip_verify,omitempty"`
 	// Used to verify the server's hostname on the returned certificates
 	// unless TLSInsecureSkipVerify is true
 	TLSServerName string `json:"tls_server_name,omitempty"`
 
+	// Token sent as "Authorization: Bearer <token>" with every gRPC and HTTP
+	// request; redacted by ToJSONString
+	Token string `json:"token,omitempty"`
+	// Send Token, or the token of TokenSource, over connections without TLS,
+	// such as to a local gateway; by default, a token requires TLS
+	AllowInsecureToken bool `json:"allow_insecure_token,omitempty"`
+
+	// Base URL of the IOx HTTP API used for writes, such as
+	// http://localhost:8080; required by Client.Write
+	WriteURL string `json:"write_url,omitempty"`
+
 	// DialOptions are passed to grpc.DialContext when a new gRPC connection
 	// is created.
 	DialOptions []grpc.DialOption `json:"-"`
 
+	// HTTPClient is used for writes; if nil, a client is created that uses
+	// the TLS config for https URLs.
+	HTTPClient *http.Client `json:"-"`
+
 	// Use this TLS config, instead of allowing this library to generate one
 	// from fields named with prefix "TLS".
 	TLSConfig *tls.Config `json:"-"`
+
+	// TokenSource supplies the token for every request, instead of Token;
+	// use it for credentials that rotate, such as RefreshingTokenSource.
+	TokenSource TokenSource `json:"-"`
+
+	// TracerProvider, if set, creates a span for each query, each write
+	// token wait, and each gRPC request, and W3C trace context is sent with
+	// every gRPC request, so that IOx traces join those of the caller.
+	// See Client for the span and metric names.
+	TracerProvider trace.TracerProvider `json:"-"`
+	// MeterProvider, if set, records histograms of query and write token
+	// wait durations, and counters of the rows, batches and bytes received.
+	MeterProvider metric.MeterProvider `json:"-"`
+
+	// Logger, if set, receives debug-level events for dials, reconnects,
+	// handshakes, health checks, retries, the start and end of each query,
+	// and each poll of a write token. A *slog.Logger from the standard
+	// library's log/slog package is a Logger.
+	Logger Logger `json:"-"`
+	// Log the text of queries; by default, a query is logged as a hash of
+	// its text, since query literals may contain sensitive data.
+	LogQueryText bool `json:"log_query_text,omitempty"`
+
+	// Time zone in which the ioxsql driver presents timestamps, as a name
+	// accepted by time.LoadLocation, such as "UTC", "Local" or
+	// "America/New_York"; by default, timestamps are presented in the time
+	// zone of their Arrow type, if any, or else in UTC
+	Location string `json:"location,omitempty"`
 }
 
+// redactedToken replaces ClientConfig.Token in the output of ToJSONString.
+const redactedToken = "REDACTED"
+
 // ToJSONString converts this instance of *ClientConfig to a JSON string,
 // which can be used as an argument for sql.Open().
 //
@@ -52,18 +134,79 @@ type ClientConfig struct {
 //
 //	{"address":"localhost:8082","namespace":"mydb"}
 //
-// To customize the way the JSON string is constructed, call json.Marshal
-// with a *ClientConfig.
+// Token is replaced with "REDACTED", so that the output can be logged.
+// To customize the way the JSON string is constructed, or to include the
+// token, call json.Marshal with a *ClientConfig.
 func (dc *ClientConfig) ToJSONString() (string, error) {
+	redacted := *dc
+	if redacted.Token != "" {
+		redacted.Token = redactedToken
+	}
 	b := bytes.NewBuffer(nil)
 	enc := json.NewEncoder(b)
 	enc.SetEscapeHTML(false)
-	if err := enc.Encode(dc); err != nil {
+	if err := enc.Encode(&redacted); err != nil {
 		return "", err
 	}
 	return b.String(), nil
 }
 
+// MarshalJSON encodes Timeout and HealthCheckInterval as duration strings.
+func (dc ClientConfig) MarshalJSON() ([]byte, error) {
+	type plainClientConfig ClientConfig
+	aux := struct {
+		plainClientConfig
+		Timeout             string `json:"timeout,omitempty"`
+		HealthCheckInterval string `json:"health_check_interval,omitempty"`
+	}{plainClientConfig: plainClientConfig(dc)}
+	if dc.Timeout != 0 {
+		aux.Timeout = dc.Timeout.String()
+	}
+	if dc.HealthCheckInterval != 0 {
+		aux.HealthCheckInterval = dc.HealthCheckInterval.String()
+	}
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON decodes Timeout and HealthCheckInterval from duration
+// strings, such as "30s", or from numbers of nanoseconds.
+func (dc *ClientConfig) UnmarshalJSON(b []byte) error {
+	type plainClientConfig ClientConfig
+	aux := struct {
+		*plainClientConfig
+		Timeout             json.RawMessage `json:"timeout,omitempty"`
+		HealthCheckInterval json.RawMessage `json:"health_check_interval,omitempty"`
+	}{plainClientConfig: (*plainClientConfig)(dc)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	var err error
+	if dc.Timeout, err = unmarshalDuration(aux.Timeout); err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+	if dc.HealthCheckInterval, err = unmarshalDuration(aux.HealthCheckInterval); err != nil {
+		return fmt.Errorf("invalid health_check_interval: %w", err)
+	}
+	return nil
+}
+
+// unmarshalDuration decodes a duration from a JSON string, such as "30s", or
+// from a JSON number of nanoseconds. Empty input and null decode to zero.
+func unmarshalDuration(b json.RawMessage) (time.Duration, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var nanoseconds int64
+		if err = json.Unmarshal(b, &nanoseconds); err != nil {
+			return 0, fmt.Errorf("%s is not a duration", b)
+		}
+		return time.Duration(nanoseconds), nil
+	}
+	return time.ParseDuration(s)
+}
+
 // ClientConfigFromJSONString constructs an instance of *ClientConfig from a JSON string.
 //
 // See ConfigClient for a description of all fields.
@@ -79,7 +222,7 @@ func ClientConfigFromJSONString(s string) (*ClientConfig, error) {
 	if err := json.Unmarshal([]byte(s), &dc); err != nil {
 		return nil, fmt.Errorf("failed to parse client config from JSON string: %w", err)
 	}
-	if _, err := dc.getTLSConfig(); err != nil {
+	if _, err := dc.newTLSConfig(); err != nil {
 		return nil, fmt.Errorf("TLS config parse failed: %w", err)
 	}
 	return &dc, nil
@@ -101,38 +244,91 @@ func ClientConfigFromJSONString(s string) (*ClientConfig, error) {
 // Example:
 //
 //	localhost:8082/mydb
+//
+// To balance requests across several servers, separate their addresses with
+// commas. Addresses may use DNSAddressScheme or DNSSRVAddressScheme.
+//
+// Example:
+//
+//	iox-1:8082,iox-2:8082/mydb
 func ClientConfigFromAddressString(s string) (*ClientConfig, error) {
+	// The slashes of address schemes do not end the address.
+	masked := strings.ReplaceAll(s, DNSSRVAddressScheme, strings.Repeat("#", len(DNSSRVAddressScheme)))
+	masked = strings.ReplaceAll(masked, DNSAddressScheme, strings.Repeat("#", len(DNSAddressScheme)))
 	var address, namespace string
-	if index := strings.IndexRune(s, '/'); index >= 0 {
+	if index := strings.IndexRune(masked, '/'); index >= 0 {
 		address = s[:index]
 		namespace = s[index+1:]
 	} else {
 		address = s
 	}
 
-	_, _, err := net.SplitHostPort(address)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse client config from address string: %w", err)
+	addresses := strings.Split(address, ",")
+	for _, address := range addresses {
+		if err := validateAddress(address); err != nil {
+			return nil, fmt.Errorf("failed to parse client config from address string: %w", err)
+		}
 	}
-	return &ClientConfig{
-		Address:   address,
+	dc := &ClientConfig{
+		Address:   addresses[0],
 		Namespace: namespace,
-	}, nil
+	}
+	if len(addresses) > 1 {
+		dc.Addresses = addresses[1:]
+	}
+	return dc, nil
 }
 
-// newGRPCClient returns a *grpc.ClientConn based on the config, or returns
-// the instance already set as ClientConfig.GRPCClient.
-func (dc *ClientConfig) newGRPCClient(ctx context.Context) (*grpc.ClientConn, error) {
+// newGRPCClient returns a *grpc.ClientConn based on the config, or a
+// *balancedConn if the config has several addresses, which uses tlsConfig,
+// as returned by getTLSConfig.
+func (dc *ClientConfig) newGRPCClient(ctx context.Context, t *telemetry, tlsConfig *tls.Config) (grpcConn, error) {
 	var creds credentials.TransportCredentials
-	if tlsConfig, err := dc.getTLSConfig(); err != nil {
-		return nil, err
-	} else if tlsConfig != nil {
-		creds = credentials.NewTLS(tlsConfig)
+	if tlsConfig != nil {
+		creds = newTLSCredentials(tlsConfig)
+	} else if dc.tokenSource() != nil && !dc.AllowInsecureToken {
+		return nil, errInsecureToken
 	} else {
 		creds = insecure.NewCredentials()
 	}
-	dialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, dc.DialOptions...)
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if tokenSource := dc.tokenSource(); tokenSource != nil {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(tokenCredentials{
+			source:                   tokenSource,
+			requireTransportSecurity: !dc.AllowInsecureToken,
+		}))
+	}
+	if dc.Timeout > 0 {
+		dialOptions = append(dialOptions,
+			grpc.WithChainUnaryInterceptor(timeoutUnaryInterceptor(dc.Timeout)),
+			grpc.WithChainStreamInterceptor(timeoutStreamInterceptor(dc.Timeout)))
+	}
+
+	if dc.balanced() {
+		t.logger.DebugContext(ctx, "dialing IOx",
+			"addresses", dc.addresses(),
+			"load_balancing", string(dc.LoadBalancing),
+			"tls", creds.Info().SecurityProtocol == "tls")
+		// balancedConn retries requests itself, on any connection.
+		dialOptions = append(dialOptions, t.dialOptions()...)
+		b, err := newBalancedConn(ctx, dc, append(dialOptions, dc.DialOptions...), t.logger)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
 
+	if dc.RetryPolicy != nil {
+		// Chained after the timeout, which therefore applies to all attempts.
+		dialOptions = append(dialOptions, grpc.WithChainUnaryInterceptor(retryUnaryInterceptor(dc.RetryPolicy, t.logger)))
+	}
+	// Chained after retries, so that each attempt is traced.
+	dialOptions = append(dialOptions, t.dialOptions()...)
+	dialOptions = append(dialOptions, dc.DialOptions...)
+
+	t.logger.DebugContext(ctx, "dialing IOx",
+		"address", dc.Address,
+		"tls", creds.Info().SecurityProtocol == "tls")
 	grpcClient, err := grpc.DialContext(ctx, dc.Address, dialOptions...)
 	if err != nil {
 		return nil, err
@@ -141,12 +337,50 @@ func (dc *ClientConfig) newGRPCClient(ctx context.Context) (*grpc.ClientConn, er
 	return grpcClient, nil
 }
 
+// tokenSource returns ClientConfig.TokenSource, or a TokenSource for
+// ClientConfig.Token, or nil if neither is set.
+func (dc *ClientConfig) tokenSource() TokenSource {
+	if dc.TokenSource != nil {
+		return dc.TokenSource
+	}
+	if dc.Token != "" {
+		token := dc.Token
+		return TokenSourceFunc(func(context.Context) (string, error) { return token, nil })
+	}
+	return nil
+}
+
+// newHTTPClient returns ClientConfig.HTTPClient, or creates a new
+// *http.Client for the HTTP write API that uses tlsConfig, as returned by
+// getTLSConfig.
+func (dc *ClientConfig) newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	if dc.HTTPClient != nil {
+		return dc.HTTPClient
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+		if tlsConfig.VerifyConnection != nil {
+			transport.DialTLSContext = dialTLS(tlsConfig)
+		}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// getTLSConfig returns ClientConfig.TLSConfig, or else a TLS config
+// generated by newTLSConfig. The config is not changed, so that later
+// changes to its TLS fields are not hidden.
 func (dc *ClientConfig) getTLSConfig() (*tls.Config, error) {
 	if dc.TLSConfig != nil {
 		return dc.TLSConfig, nil
 	}
+	return dc.newTLSConfig()
+}
 
-	if dc.TLSCA == "" && dc.TLSKey == "" && dc.TLSCert == "" && !dc.TLSInsecureSkipVerify && dc.TLSServerName == "" {
+// newTLSConfig generates a TLS config from the fields named with prefix
+// "TLS", reading the files they name, or returns nil if none is set.
+func (dc *ClientConfig) newTLSConfig() (*tls.Config, error) {
+	if !dc.TLS && dc.TLSCA == "" && dc.TLSKey == "" && dc.TLSCert == "" && !dc.TLSInsecureSkipVerify && dc.TLSServerName == "" {
 		return nil, nil
 	}
 
@@ -155,30 +389,25 @@ func (dc *ClientConfig) getTLSConfig() (*tls.Config, error) {
 		Renegotiation:      tls.RenegotiateNever,
 	}
 
-	if dc.TLSCA != "" {
-		pool := x509.NewCertPool()
-		pem, err := ioutil.ReadFile(dc.TLSCA)
+	if dc.TLSCA != "" || dc.TLSCert != "" && dc.TLSKey != "" {
+		files, err := newTLSFiles(dc.TLSCA, dc.TLSCert, dc.TLSKey, dc.OnTLSReloadError)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read root certificate file %q: %w", dc.TLSCA, err)
+			return nil, err
 		}
-		if ok := pool.AppendCertsFromPEM(pem); !ok {
-			return nil, fmt.Errorf("failed to parse PEM certificate in root certificate file %q: %w", dc.TLSCA, err)
+		if files.cert != nil {
+			tlsConfig.GetClientCertificate = files.getClientCertificate
 		}
-		tlsConfig.RootCAs = pool
-	}
-
-	if dc.TLSCert != "" && dc.TLSKey != "" {
-		cert, err := tls.LoadX509KeyPair(dc.TLSCert, dc.TLSKey)
-		if err != nil {
-			return nil, err
+		if files.rootCAs != nil && !dc.TLSInsecureSkipVerify {
+			// The standard verification is replaced by verifyConnection,
+			// which uses the reloaded root certificate authorities.
+			tlsConfig.InsecureSkipVerify = true
+			tlsConfig.VerifyConnection = files.verifyConnection
 		}
-		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
 
 	if dc.TLSServerName != "" {
 		tlsConfig.ServerName = dc.TLSServerName
 	}
-	dc.TLSConfig = tlsConfig
 
 	return tlsConfig, nil
 }
@@ -0,0 +1,328 @@
+package influxdbiox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvPrefix is the environment variable prefix used by
+// ClientConfigFromEnv when the prefix is "".
+const DefaultEnvPrefix = "INFLUXDB_IOX"
+
+// configFieldNames lists the JSON names of the ClientConfig fields that can
+// be set from a string by setField.
+var configFieldNames = []string{
+	"address",
+	"addresses",
+	"allow_insecure_token",
+	"load_balancing",
+	"health_check_interval",
+	"location",
+	"namespace",
+	"query_type",
+	"flight_sql",
+	"log_query_text",
+	"timeout",
+	"tls",
+	"tls_ca",
+	"tls_cert",
+	"tls_key",
+	"tls_insecure_skip_verify",
+	"tls_server_name",
+	"token",
+	"write_url",
+}
+
+// setField sets the field with the given JSON name from a string value.
+func (dc *ClientConfig) setField(name, value string) error {
+	var err error
+	switch name {
+	case "address":
+		dc.Address = value
+	case "addresses":
+		dc.Addresses = strings.Split(value, ",")
+	case "allow_insecure_token":
+		dc.AllowInsecureToken, err = strconv.ParseBool(value)
+	case "load_balancing":
+		dc.LoadBalancing, err = ParseLoadBalancingPolicy(value)
+	case "health_check_interval":
+		dc.HealthCheckInterval, err = time.ParseDuration(value)
+	case "location":
+		if _, err = time.LoadLocation(value); err == nil {
+			dc.Location = value
+		}
+	case "namespace":
+		dc.Namespace = value
+	case "query_type":
+		dc.QueryType, err = ParseQueryType(value)
+	case "flight_sql":
+		dc.FlightSQL, err = strconv.ParseBool(value)
+	case "log_query_text":
+		dc.LogQueryText, err = strconv.ParseBool(value)
+	case "timeout":
+		dc.Timeout, err = time.ParseDuration(value)
+	case "tls":
+		dc.TLS, err = strconv.ParseBool(value)
+	case "tls_ca":
+		dc.TLSCA = value
+	case "tls_cert":
+		dc.TLSCert = value
+	case "tls_key":
+		dc.TLSKey = value
+	case "tls_insecure_skip_verify":
+		dc.TLSInsecureSkipVerify, err = strconv.ParseBool(value)
+	case "tls_server_name":
+		dc.TLSServerName = value
+	case "token":
+		dc.Token = value
+	case "write_url":
+		dc.WriteURL = value
+	default:
+		return errors.New("unknown parameter")
+	}
+	return err
+}
+
+// ClientConfigFromEnv constructs an instance of *ClientConfig from
+// environment variables named with prefix, or DefaultEnvPrefix if prefix is
+// "", followed by an underscore and the upper case JSON field name:
+//
+//	INFLUXDB_IOX_ADDRESS=localhost:8082
+//	INFLUXDB_IOX_NAMESPACE=mydb
+//	INFLUXDB_IOX_TOKEN=...
+//	INFLUXDB_IOX_TLS_CA=/etc/ca.pem
+//	INFLUXDB_IOX_TIMEOUT=30s
+//
+// Empty variables are ignored. Each non-nil override is then merged into the
+// config, in order, with ClientConfig.Merge, and the result is validated
+// with ClientConfig.Validate.
+func ClientConfigFromEnv(prefix string, overrides ...*ClientConfig) (*ClientConfig, error) {
+	dc, err := ReadClientConfigFromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return dc.mergeAndValidate(overrides)
+}
+
+// ReadClientConfigFromEnv is like ClientConfigFromEnv, but the config is
+// neither merged nor validated, so that it can be merged with other sources
+// before it is validated.
+func ReadClientConfigFromEnv(prefix string) (*ClientConfig, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	var dc ClientConfig
+	for _, name := range configFieldNames {
+		envName := prefix + "_" + strings.ToUpper(name)
+		if value := os.Getenv(envName); value != "" {
+			if err := dc.setField(name, value); err != nil {
+				return nil, fmt.Errorf("environment variable %s: %w", envName, err)
+			}
+		}
+	}
+	return &dc, nil
+}
+
+// ClientConfigFromFile constructs an instance of *ClientConfig from a JSON,
+// TOML or YAML file, identified by the file extension: .json, .toml, .yaml
+// or .yml.
+//
+// Fields have the same names as in the JSON data source name. Top-level
+// fields are the defaults for every profile. Named profiles are listed under
+// "profiles", and override the defaults when selected by a non-empty profile
+// argument:
+//
+//	address: localhost:8082
+//	namespace: mydb
+//	profiles:
+//	  prod:
+//	    address: iox.example.com:8082
+//	    tls: true
+//	    timeout: 30s
+//
+// Each non-nil override is then merged into the config, in order, with
+// ClientConfig.Merge, and the result is validated with ClientConfig.Validate.
+func ClientConfigFromFile(filename, profile string, overrides ...*ClientConfig) (*ClientConfig, error) {
+	dc, err := ReadClientConfigFromFile(filename, profile)
+	if err != nil {
+		return nil, err
+	}
+	return dc.mergeAndValidate(overrides)
+}
+
+// ReadClientConfigFromFile is like ClientConfigFromFile, but the config is
+// neither merged nor validated, so that it can be merged with other sources
+// before it is validated.
+func ReadClientConfigFromFile(filename, profile string) (*ClientConfig, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client config file: %w", err)
+	}
+
+	var document map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		err = json.Unmarshal(b, &document)
+	case ".toml":
+		err = toml.Unmarshal(b, &document)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &document)
+	default:
+		return nil, fmt.Errorf("client config file %q: unsupported file extension %q; must be .json, .toml, .yaml or .yml", filename, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client config file %q: %w", filename, err)
+	}
+
+	fields := make(map[string]interface{})
+	for key, value := range document {
+		if key != "profiles" {
+			fields[key] = value
+		}
+	}
+	if err = checkConfigFields(fields); err != nil {
+		return nil, fmt.Errorf("client config file %q: %w", filename, err)
+	}
+
+	if profile != "" {
+		profiles, ok := document["profiles"].(map[string]interface{})
+		if !ok && document["profiles"] != nil {
+			return nil, fmt.Errorf("client config file %q: profiles must be a map of profile name to fields", filename)
+		}
+		profileFields, ok := profiles[profile].(map[string]interface{})
+		if !ok && len(profiles) == 0 {
+			return nil, fmt.Errorf("client config file %q: profile %q not found; no profiles defined", filename, profile)
+		} else if !ok {
+			return nil, fmt.Errorf("client config file %q: profile %q not found; available profiles: %s", filename, profile, strings.Join(sortedKeys(profiles), ", "))
+		}
+		if err = checkConfigFields(profileFields); err != nil {
+			return nil, fmt.Errorf("client config file %q: profile %q: %w", filename, profile, err)
+		}
+		for key, value := range profileFields {
+			fields[key] = value
+		}
+	}
+
+	b, err = json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("client config file %q: %w", filename, err)
+	}
+	var dc ClientConfig
+	if err = json.Unmarshal(b, &dc); err != nil {
+		return nil, fmt.Errorf("client config file %q: %w", filename, err)
+	}
+	return &dc, nil
+}
+
+// checkConfigFields returns an error if fields contains a key that is not a
+// ClientConfig JSON field name.
+func checkConfigFields(fields map[string]interface{}) error {
+	known := map[string]bool{"retry_policy": true}
+	for _, name := range configFieldNames {
+		known[name] = true
+	}
+	var unknown []string
+	for key := range fields {
+		if !known[key] {
+			unknown = append(unknown, strconv.Quote(key))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown field %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func (dc *ClientConfig) mergeAndValidate(overrides []*ClientConfig) (*ClientConfig, error) {
+	for _, override := range overrides {
+		if override != nil {
+			dc.Merge(override)
+		}
+	}
+	if err := dc.Validate(); err != nil {
+		return nil, err
+	}
+	return dc, nil
+}
+
+// Merge copies every field of override that is not the zero value into dc.
+// A field cannot be reset to its zero value, such as false, by a merge.
+func (dc *ClientConfig) Merge(override *ClientConfig) {
+	dst := reflect.ValueOf(dc).Elem()
+	src := reflect.ValueOf(override).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+}
+
+// Validate checks that the config can be used to create a Client, returning
+// an error that describes every problem found. TLS files are read, so that
+// a missing or invalid file is reported before dialing.
+func (dc *ClientConfig) Validate() error {
+	var problems []string
+
+	addresses := dc.Addresses
+	if dc.Address == "" {
+		problems = append(problems, "address is required")
+	} else {
+		addresses = dc.addresses()
+	}
+	for _, address := range addresses {
+		if err := validateAddress(address); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid address: %s", err))
+		}
+	}
+	if _, err := ParseLoadBalancingPolicy(string(dc.LoadBalancing)); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if dc.QueryType.String() == "unknown" {
+		problems = append(problems, fmt.Sprintf("invalid query type %d", dc.QueryType))
+	} else if dc.FlightSQL && dc.QueryType != QueryTypeSQL {
+		problems = append(problems, fmt.Sprintf("query type %s is not supported with Flight SQL", dc.QueryType))
+	}
+	if dc.Location != "" {
+		if _, err := time.LoadLocation(dc.Location); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid location: %s", err))
+		}
+	}
+	if dc.Timeout < 0 {
+		problems = append(problems, fmt.Sprintf("timeout %s must not be negative", dc.Timeout))
+	}
+	if dc.RetryPolicy != nil {
+		problems = append(problems, dc.RetryPolicy.validate()...)
+	}
+	if (dc.TLSCert == "") != (dc.TLSKey == "") {
+		problems = append(problems, "tls_cert and tls_key must be set together")
+	}
+	if dc.WriteURL != "" {
+		if u, err := url.Parse(dc.WriteURL); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid write URL: %s", err))
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			problems = append(problems, fmt.Sprintf("invalid write URL %q: scheme must be http or https", dc.WriteURL))
+		}
+	}
+	if _, err := dc.newTLSConfig(); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid TLS config: %s", err))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid client config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
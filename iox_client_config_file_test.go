@@ -0,0 +1,204 @@
+package influxdbiox_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+func ExampleClientConfigFromFile() {
+	// Settings from the file are overridden by environment variables, which
+	// are overridden by command line flags.
+	envConfig, _ := influxdbiox.ClientConfigFromEnv("MYAPP", nil)
+	flagConfig := &influxdbiox.ClientConfig{Namespace: "from_flag"}
+	config, err := influxdbiox.ClientConfigFromFile("/etc/myapp/iox.yaml", "prod", envConfig, flagConfig)
+	if err != nil {
+		panic(err)
+	}
+	_ = config
+}
+
+func TestClientConfigFromEnv(t *testing.T) {
+	t.Setenv("INFLUXDB_IOX_ADDRESS", "localhost:8082")
+	t.Setenv("INFLUXDB_IOX_NAMESPACE", "mydb")
+	t.Setenv("INFLUXDB_IOX_TOKEN", "secret")
+	t.Setenv("INFLUXDB_IOX_TIMEOUT", "30s")
+	t.Setenv("INFLUXDB_IOX_QUERY_TYPE", "influxql")
+	t.Setenv("INFLUXDB_IOX_TLS_SERVER_NAME", "")
+
+	config, err := influxdbiox.ClientConfigFromEnv("")
+	require.NoError(t, err)
+	assert.Equal(t, &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "mydb",
+		Token:     "secret",
+		Timeout:   30 * time.Second,
+		QueryType: influxdbiox.QueryTypeInfluxQL,
+	}, config)
+
+	config, err = influxdbiox.ClientConfigFromEnv("INFLUXDB_IOX", &influxdbiox.ClientConfig{Namespace: "other"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "other", config.Namespace)
+	assert.Equal(t, "secret", config.Token)
+
+	t.Setenv("MYAPP_ADDRESS", "localhost:8082")
+	t.Setenv("MYAPP_FLIGHT_SQL", "maybe")
+	_, err = influxdbiox.ClientConfigFromEnv("MYAPP")
+	assert.EqualError(t, err, `environment variable MYAPP_FLIGHT_SQL: strconv.ParseBool: parsing "maybe": invalid syntax`)
+
+	t.Setenv("MYAPP_FLIGHT_SQL", "")
+	t.Setenv("MYAPP_ADDRESS", "localhost")
+	_, err = influxdbiox.ClientConfigFromEnv("MYAPP")
+	assert.EqualError(t, err, "invalid client config: invalid address: address localhost: missing port in address")
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+	return filename
+}
+
+func TestClientConfigFromFile(t *testing.T) {
+	expectDefault := &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "mydb",
+	}
+	expectProd := &influxdbiox.ClientConfig{
+		Address:   "iox.example.com:8082",
+		Namespace: "mydb",
+		Timeout:   30 * time.Second,
+		FlightSQL: true,
+		WriteURL:  "https://iox.example.com:8080",
+	}
+
+	for _, file := range []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "iox.json",
+			content: `{
+  "address": "localhost:8082",
+  "namespace": "mydb",
+  "profiles": {
+    "prod": {"address": "iox.example.com:8082", "timeout": "30s", "flight_sql": true, "write_url": "https://iox.example.com:8080"}
+  }
+}`,
+		},
+		{
+			name: "iox.toml",
+			content: `address = "localhost:8082"
+namespace = "mydb"
+
+[profiles.prod]
+address = "iox.example.com:8082"
+timeout = "30s"
+flight_sql = true
+write_url = "https://iox.example.com:8080"
+`,
+		},
+		{
+			name: "iox.yml",
+			content: `address: localhost:8082
+namespace: mydb
+profiles:
+  prod:
+    address: iox.example.com:8082
+    timeout: 30s
+    flight_sql: true
+    write_url: https://iox.example.com:8080
+`,
+		},
+	} {
+		t.Run(file.name, func(t *testing.T) {
+			filename := writeConfigFile(t, file.name, file.content)
+
+			config, err := influxdbiox.ClientConfigFromFile(filename, "")
+			require.NoError(t, err)
+			assert.Equal(t, expectDefault, config)
+
+			config, err = influxdbiox.ClientConfigFromFile(filename, "prod")
+			require.NoError(t, err)
+			assert.Equal(t, expectProd, config)
+
+			config, err = influxdbiox.ClientConfigFromFile(filename, "prod", &influxdbiox.ClientConfig{Token: "secret"})
+			require.NoError(t, err)
+			assert.Equal(t, "secret", config.Token)
+			assert.Equal(t, "iox.example.com:8082", config.Address)
+
+			_, err = influxdbiox.ClientConfigFromFile(filename, "staging")
+			assert.EqualError(t, err, `client config file "`+filename+`": profile "staging" not found; available profiles: prod`)
+		})
+	}
+}
+
+func TestClientConfigFromFile_errors(t *testing.T) {
+	filename := writeConfigFile(t, "iox.yaml", "address: localhost:8082\nadress: typo\nprofiles:\n  prod:\n    tokn: x\n")
+	_, err := influxdbiox.ClientConfigFromFile(filename, "")
+	assert.EqualError(t, err, `client config file "`+filename+`": unknown field "adress"`)
+
+	filename = writeConfigFile(t, "iox.yaml", "address: localhost:8082\nprofiles:\n  prod:\n    tokn: x\n")
+	_, err = influxdbiox.ClientConfigFromFile(filename, "prod")
+	assert.EqualError(t, err, `client config file "`+filename+`": profile "prod": unknown field "tokn"`)
+
+	filename = writeConfigFile(t, "iox.toml", `address = "localhost:8082"`+"\n"+`timeout = "soon"`)
+	_, err = influxdbiox.ClientConfigFromFile(filename, "")
+	assert.EqualError(t, err, `client config file "`+filename+`": invalid timeout: time: invalid duration "soon"`)
+
+	filename = writeConfigFile(t, "iox.json", `{"address": "localhost:8082"}`)
+	_, err = influxdbiox.ClientConfigFromFile(filename, "prod")
+	assert.EqualError(t, err, `client config file "`+filename+`": profile "prod" not found; no profiles defined`)
+
+	filename = writeConfigFile(t, "iox.ini", "address=localhost:8082")
+	_, err = influxdbiox.ClientConfigFromFile(filename, "")
+	assert.ErrorContains(t, err, `unsupported file extension ".ini"`)
+
+	filename = writeConfigFile(t, "iox.json", `{"address": `)
+	_, err = influxdbiox.ClientConfigFromFile(filename, "")
+	assert.ErrorContains(t, err, "failed to parse client config file")
+}
+
+func TestClientConfig_Validate(t *testing.T) {
+	config := &influxdbiox.ClientConfig{Address: "localhost:8082"}
+	assert.NoError(t, config.Validate())
+
+	config = &influxdbiox.ClientConfig{
+		QueryType: influxdbiox.QueryTypeInfluxQL,
+		FlightSQL: true,
+		Location:  "Mars/Olympus",
+		Timeout:   -time.Second,
+		TLSCert:   "cert.pem",
+		WriteURL:  "localhost:8080",
+	}
+	assert.EqualError(t, config.Validate(), "invalid client config: address is required; "+
+		"query type influxql is not supported with Flight SQL; "+
+		"invalid location: unknown time zone Mars/Olympus; "+
+		"timeout -1s must not be negative; "+
+		"tls_cert and tls_key must be set together; "+
+		`invalid write URL "localhost:8080": scheme must be http or https`)
+
+	config = &influxdbiox.ClientConfig{Address: "localhost:8082", TLSCA: filepath.Join(t.TempDir(), "missing.pem")}
+	assert.ErrorContains(t, config.Validate(), "invalid TLS config: failed to read root certificate file")
+}
+
+func TestClientConfig_Merge(t *testing.T) {
+	config := &influxdbiox.ClientConfig{Address: "localhost:8082", Namespace: "mydb", FlightSQL: true}
+	config.Merge(&influxdbiox.ClientConfig{Namespace: "other", Timeout: time.Second})
+	assert.Equal(t, &influxdbiox.ClientConfig{Address: "localhost:8082", Namespace: "other", FlightSQL: true, Timeout: time.Second}, config)
+
+	// TLS fields merged into a parsed config are not hidden by a TLS config
+	// generated from it, and Validate does not change the config.
+	config, err := influxdbiox.ClientConfigFromString("iox+tls://localhost/mydb")
+	require.NoError(t, err)
+	require.NoError(t, config.Validate())
+	assert.Nil(t, config.TLSConfig)
+	config.Merge(&influxdbiox.ClientConfig{TLSCA: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorContains(t, config.Validate(), "invalid TLS config: failed to read root certificate file")
+	assert.Nil(t, config.TLSConfig)
+}
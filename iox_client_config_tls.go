@@ -0,0 +1,222 @@
+package influxdbiox
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// tlsFiles holds the root certificate authorities and client certificate
+// loaded from ClientConfig.TLSCA, TLSCert and TLSKey, and reloads them when
+// the files change.
+//
+// Files are checked for changes, by modification time and size, at the start
+// of every TLS handshake, so that new connections use the current files.
+// Established connections are not affected.
+type tlsFiles struct {
+	caFile   string
+	certFile string
+	keyFile  string
+	onError  func(error)
+
+	mu      sync.Mutex
+	stamps  map[string]fileStamp
+	rootCAs *x509.CertPool
+	cert    *tls.Certificate
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newTLSFiles(caFile, certFile, keyFile string, onError func(error)) (*tlsFiles, error) {
+	f := &tlsFiles{
+		caFile:   caFile,
+		certFile: certFile,
+		keyFile:  keyFile,
+		onError:  onError,
+	}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// load reads every file, replacing the loaded root certificate authorities
+// and client certificate only if all files are valid. f.mu must be held,
+// unless f is not yet shared.
+func (f *tlsFiles) load() error {
+	// Stamp the files before reading them, so that a change during the read
+	// is seen by the next check. Files that are invalid are not loaded again
+	// until they change.
+	f.stamps = f.currentStamps()
+
+	var rootCAs *x509.CertPool
+	if f.caFile != "" {
+		pem, err := ioutil.ReadFile(f.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read root certificate file %q: %w", f.caFile, err)
+		}
+		rootCAs = x509.NewCertPool()
+		if ok := rootCAs.AppendCertsFromPEM(pem); !ok {
+			return fmt.Errorf("failed to parse PEM certificate in root certificate file %q", f.caFile)
+		}
+	}
+
+	var cert *tls.Certificate
+	if f.certFile != "" && f.keyFile != "" {
+		c, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+
+	f.rootCAs = rootCAs
+	f.cert = cert
+	return nil
+}
+
+// currentStamps returns the modification time and size of every file; the
+// stamp of a file that cannot be examined is the zero value.
+func (f *tlsFiles) currentStamps() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, filename := range []string{f.caFile, f.certFile, f.keyFile} {
+		if filename == "" {
+			continue
+		}
+		if info, err := os.Stat(filename); err == nil {
+			stamps[filename] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		} else {
+			stamps[filename] = fileStamp{}
+		}
+	}
+	return stamps
+}
+
+// changed reports whether any file has changed since it was last loaded.
+func (f *tlsFiles) changed() bool {
+	for filename, stamp := range f.currentStamps() {
+		previous := f.stamps[filename]
+		if !stamp.modTime.Equal(previous.modTime) || stamp.size != previous.size {
+			return true
+		}
+	}
+	return false
+}
+
+// reload loads the files if any has changed. If the files cannot be loaded,
+// onError is called, and the previous root certificate authorities and
+// client certificate remain in use until the files change again.
+func (f *tlsFiles) reload() (*x509.CertPool, *tls.Certificate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.changed() {
+		if err := f.load(); err != nil && f.onError != nil {
+			f.onError(fmt.Errorf("failed to reload TLS files: %w", err))
+		}
+	}
+	return f.rootCAs, f.cert
+}
+
+func (f *tlsFiles) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	_, cert := f.reload()
+	return cert, nil
+}
+
+// verifyConnection verifies the server certificate chain with the reloaded
+// root certificate authorities, and the server host name.
+//
+// The TLS package omits IP addresses from ConnectionState.ServerName, so a
+// server dialed by IP address is verified against the host name set by
+// withDialedHost.
+func (f *tlsFiles) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server did not present a certificate")
+	}
+	rootCAs, _ := f.reload()
+
+	opts := x509.VerifyOptions{
+		Roots:         rootCAs,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	leaf := cs.PeerCertificates[0]
+	if _, err := leaf.Verify(opts); err != nil {
+		return err
+	}
+
+	if cs.ServerName == "" {
+		return errors.New("no server name to verify the server certificate against; set TLSServerName")
+	}
+	return leaf.VerifyHostname(cs.ServerName)
+}
+
+// withDialedHost returns config, or, if config has a VerifyConnection
+// function, a clone of config that verifies a connection without a server
+// name, such as one dialed by IP address, against host, the host dialed.
+func withDialedHost(config *tls.Config, host string) *tls.Config {
+	verify := config.VerifyConnection
+	if verify == nil {
+		return config
+	}
+	config = config.Clone()
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if cs.ServerName == "" {
+			cs.ServerName = host
+		}
+		return verify(cs)
+	}
+	return config
+}
+
+// dialedHostCredentials are gRPC TLS credentials that verify the server
+// certificate against the host of the authority dialed; see withDialedHost.
+type dialedHostCredentials struct {
+	credentials.TransportCredentials
+	config *tls.Config
+}
+
+func newTLSCredentials(config *tls.Config) credentials.TransportCredentials {
+	return &dialedHostCredentials{TransportCredentials: credentials.NewTLS(config), config: config}
+}
+
+func (c *dialedHostCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	host, _, err := net.SplitHostPort(authority)
+	if err != nil {
+		host = authority
+	}
+	return credentials.NewTLS(withDialedHost(c.config, host)).ClientHandshake(ctx, authority, rawConn)
+}
+
+func (c *dialedHostCredentials) Clone() credentials.TransportCredentials {
+	return newTLSCredentials(c.config.Clone())
+}
+
+// dialTLS returns a function for http.Transport.DialTLSContext that
+// verifies the server certificate against the host dialed; see
+// withDialedHost.
+func dialTLS(config *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: withDialedHost(config, host)}
+		return tlsDialer.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,248 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed
+// certificate authority if parent is nil. The certificate is valid for
+// dnsNames, or for 127.0.0.1 if there are none.
+func newTestCert(t *testing.T, commonName string, parent *testCert, dnsNames ...string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     dnsNames,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if len(dnsNames) > 0 {
+		template.IPAddresses = nil
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+// writeTLSFile writes a file and advances its modification time, so that
+// the change is seen regardless of file system timestamp granularity.
+func writeTLSFile(t *testing.T, filename string, content []byte, modTime time.Time) {
+	require.NoError(t, os.WriteFile(filename, content, 0o600))
+	require.NoError(t, os.Chtimes(filename, modTime, modTime))
+}
+
+func TestClientConfig_TLSReload(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	ca1 := newTestCert(t, "ca-1", nil)
+	ca2 := newTestCert(t, "ca-2", nil)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca1.cert)
+
+	var serverCert atomic.Value
+	serverCert.Store(newTestCert(t, "server-1", ca1).tlsCertificate(t))
+	var mu sync.Mutex
+	var clientName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		clientName = r.TLS.PeerCertificates[0].Subject.CommonName
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				Certificates: []tls.Certificate{serverCert.Load().(tls.Certificate)},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    clientCAs,
+			}, nil
+		},
+	}
+	// Every write uses a new connection, and so a new TLS handshake.
+	server.Config.SetKeepAlivesEnabled(false)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	modTime := time.Now().Add(-time.Hour)
+	nextModTime := func() time.Time {
+		modTime = modTime.Add(time.Second)
+		return modTime
+	}
+	client1 := newTestCert(t, "client-1", ca1)
+	writeTLSFile(t, caFile, ca1.certPEM, nextModTime())
+	writeTLSFile(t, certFile, client1.certPEM, nextModTime())
+	writeTLSFile(t, keyFile, client1.keyPEM, nextModTime())
+
+	var reloadErrors []error
+	client, err := influxdbiox.NewClient(ctx, &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "myorg_mybucket",
+		WriteURL:  server.URL,
+		TLSCA:     caFile,
+		TLSCert:   certFile,
+		TLSKey:    keyFile,
+		OnTLSReloadError: func(err error) {
+			reloadErrors = append(reloadErrors, err)
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	write := func() (string, error) {
+		_, err := client.Write(ctx, "", []byte("t v=1i 1\n"))
+		mu.Lock()
+		defer mu.Unlock()
+		return clientName, err
+	}
+
+	name, err := write()
+	require.NoError(t, err)
+	assert.Equal(t, "client-1", name)
+
+	// Rotate the client certificate.
+	client2 := newTestCert(t, "client-2", ca1)
+	writeTLSFile(t, certFile, client2.certPEM, nextModTime())
+	writeTLSFile(t, keyFile, client2.keyPEM, nextModTime())
+	name, err = write()
+	require.NoError(t, err)
+	assert.Equal(t, "client-2", name)
+
+	// Rotate the server certificate authority; the server is not trusted
+	// until the CA bundle is updated.
+	serverCert.Store(newTestCert(t, "server-2", ca2).tlsCertificate(t))
+	_, err = write()
+	assert.ErrorContains(t, err, "certificate signed by unknown authority")
+	writeTLSFile(t, caFile, append(append([]byte{}, ca1.certPEM...), ca2.certPEM...), nextModTime())
+	name, err = write()
+	require.NoError(t, err)
+	assert.Equal(t, "client-2", name)
+
+	// An invalid file is reported, and the previous files remain in use.
+	writeTLSFile(t, keyFile, []byte("not a key"), nextModTime())
+	name, err = write()
+	require.NoError(t, err)
+	assert.Equal(t, "client-2", name)
+	require.Len(t, reloadErrors, 1)
+	assert.ErrorContains(t, reloadErrors[0], "failed to reload TLS files")
+
+	// The files are loaded again when they change.
+	client3 := newTestCert(t, "client-3", ca1)
+	writeTLSFile(t, certFile, client3.certPEM, nextModTime())
+	writeTLSFile(t, keyFile, client3.keyPEM, nextModTime())
+	name, err = write()
+	require.NoError(t, err)
+	assert.Equal(t, "client-3", name)
+}
+
+func TestClientConfig_TLSReload_serverName(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeTLSFile(t, caFile, ca.certPEM, time.Now())
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{newTestCert(t, "server", ca).tlsCertificate(t)}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	// The certificate is valid for 127.0.0.1, but not for TLSServerName.
+	client, err := influxdbiox.NewClient(context.Background(), &influxdbiox.ClientConfig{
+		Address:       "localhost:8082",
+		WriteURL:      server.URL,
+		TLSCA:         caFile,
+		TLSServerName: "iox.example.com",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	_, err = client.Write(context.Background(), "myorg_mybucket", []byte("t v=1i 1\n"))
+	assert.ErrorContains(t, err, "certificate is not valid for any names, but wanted to match iox.example.com")
+}
+
+func TestClientConfig_TLSReload_dialedHost(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeTLSFile(t, caFile, ca.certPEM, time.Now())
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{newTestCert(t, "server", ca, "iox.example.com").tlsCertificate(t)}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	// The server is dialed by IP address, so its certificate must be valid
+	// for that address, not for another configured host.
+	client, err := influxdbiox.NewClient(context.Background(), &influxdbiox.ClientConfig{
+		Address:  "iox.example.com:8082",
+		WriteURL: server.URL,
+		TLSCA:    caFile,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	_, err = client.Write(context.Background(), "myorg_mybucket", []byte("t v=1i 1\n"))
+	assert.ErrorContains(t, err, "cannot validate certificate for 127.0.0.1")
+}
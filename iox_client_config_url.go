@@ -0,0 +1,280 @@
+package influxdbiox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	// URLScheme is the URL scheme for plaintext connections.
+	URLScheme = "iox"
+	// URLSchemeTLS is the URL scheme for TLS connections.
+	URLSchemeTLS = "iox+tls"
+
+	defaultURLPort = "8082"
+)
+
+// ClientConfigFromURLString constructs an instance of *ClientConfig from a URL.
+//
+// Example:
+//
+//	iox+tls://mytoken@iox.example.com:8082/mydb?tls_ca=/etc/ca.pem&timeout=30s&query_type=influxql
+//
+// The scheme is "iox" for plaintext connections, or "iox+tls" for TLS.
+// The optional user name is the bearer token, and must be percent-encoded.
+// The port defaults to 8082. The optional path is the default namespace.
+//
+// The host may be a comma-separated list of hosts, to balance requests across
+// several servers; the first is ClientConfig.Address, and the rest are
+// ClientConfig.Addresses:
+//
+//	iox://iox-1.example.com,iox-2.example.com:9000/mydb?load_balancing=failover
+//
+// The resolve parameter, "dns" or "dns+srv", prefixes every host with
+// DNSAddressScheme or DNSSRVAddressScheme; with "dns+srv", each host is the
+// name of an SRV record, without a port:
+//
+//	iox+tls://_iox._tcp.example.com/mydb?resolve=dns%2Bsrv
+//
+// These query parameters are also supported, with the same meaning as the
+// JSON fields of ClientConfig:
+//
+//	query_type, flight_sql, timeout, write_url,
+//	tls_ca, tls_cert, tls_key, tls_insecure_skip_verify, tls_server_name,
+//	load_balancing, health_check_interval, log_query_text, location,
+//	allow_insecure_token
+//
+// ClientConfig.ToURL does the reverse.
+func ClientConfigFromURLString(s string) (*ClientConfig, error) {
+	s, moreHosts := splitURLHosts(s)
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client config from URL: %w", err)
+	}
+
+	var dc ClientConfig
+	switch u.Scheme {
+	case URLScheme:
+	case URLSchemeTLS:
+		dc.TLS = true
+	default:
+		return nil, fmt.Errorf("failed to parse client config from URL: scheme must be %q or %q, got %q", URLScheme, URLSchemeTLS, u.Scheme)
+	}
+
+	query := u.Query()
+	var addressScheme string
+	if resolve := query.Get("resolve"); resolve != "" {
+		switch resolve {
+		case "dns":
+			addressScheme = DNSAddressScheme
+		case "dns+srv":
+			addressScheme = DNSSRVAddressScheme
+		default:
+			return nil, fmt.Errorf("failed to parse client config from URL: parameter \"resolve\" must be \"dns\" or \"dns+srv\", got %q", resolve)
+		}
+		query.Del("resolve")
+	}
+	if dc.Address, err = urlHostAddress(u, addressScheme); err != nil {
+		return nil, fmt.Errorf("failed to parse client config from URL: %w", err)
+	}
+	for _, host := range moreHosts {
+		hostURL, err := url.Parse("//" + host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client config from URL: %w", err)
+		}
+		address, err := urlHostAddress(hostURL, addressScheme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client config from URL: %w", err)
+		}
+		dc.Addresses = append(dc.Addresses, address)
+	}
+	dc.Namespace = strings.TrimPrefix(u.Path, "/")
+
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			return nil, fmt.Errorf("failed to parse client config from URL: password is not supported; the token is the user name")
+		}
+		dc.Token = u.User.Username()
+	}
+
+	for name, values := range query {
+		if len(values) != 1 {
+			return nil, fmt.Errorf("failed to parse client config from URL: parameter %q must be set once", name)
+		}
+		if err = dc.setURLParameter(name, values[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse client config from URL: parameter %q: %w", name, err)
+		}
+	}
+
+	if _, err = dc.newTLSConfig(); err != nil {
+		return nil, fmt.Errorf("TLS config parse failed: %w", err)
+	}
+	return &dc, nil
+}
+
+// splitURLHosts removes all but the first of a comma-separated list of hosts
+// from a URL, which url.Parse rejects, and returns the others.
+func splitURLHosts(s string) (string, []string) {
+	index := strings.Index(s, "://")
+	if index < 0 {
+		return s, nil
+	}
+	authorityStart := index + len("://")
+	authorityEnd := len(s)
+	if i := strings.IndexAny(s[authorityStart:], "/?#"); i >= 0 {
+		authorityEnd = authorityStart + i
+	}
+	hostsStart := authorityStart
+	if i := strings.LastIndex(s[authorityStart:authorityEnd], "@"); i >= 0 {
+		hostsStart = authorityStart + i + 1
+	}
+	hosts := strings.Split(s[hostsStart:authorityEnd], ",")
+	if len(hosts) == 1 {
+		return s, nil
+	}
+	return s[:hostsStart] + hosts[0] + s[authorityEnd:], hosts[1:]
+}
+
+// urlHostAddress returns the address of the host of u, with the default port,
+// prefixed by addressScheme.
+func urlHostAddress(u *url.URL, addressScheme string) (string, error) {
+	if u.Hostname() == "" {
+		return "", errors.New("missing host")
+	}
+	if addressScheme == DNSSRVAddressScheme {
+		if u.Port() != "" {
+			return "", fmt.Errorf("host %s: SRV record name must not have a port", u.Host)
+		}
+		return addressScheme + u.Hostname(), nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultURLPort
+	}
+	return addressScheme + net.JoinHostPort(u.Hostname(), port), nil
+}
+
+func (dc *ClientConfig) setURLParameter(name, value string) error {
+	switch name {
+	case "address", "addresses", "namespace", "token", "tls":
+		// Set by the host, path, user name and scheme.
+		return errors.New("unknown parameter")
+	default:
+		return dc.setField(name, value)
+	}
+}
+
+// ToURL converts this instance of *ClientConfig to a URL string, which can
+// be parsed by ClientConfigFromURLString and used as an argument for
+// sql.Open().
+//
+// Example output:
+//
+//	iox+tls://mytoken@iox.example.com:8082/mydb?timeout=30s
+//
+// Unlike ToJSONString, the URL includes the token, so treat it as a secret.
+// Fields that cannot be serialized, such as DialOptions and TokenSource, are
+// omitted, as are Addresses that do not use the same scheme as Address.
+func (dc *ClientConfig) ToURL() string {
+	addressScheme := ""
+	for _, scheme := range []string{DNSSRVAddressScheme, DNSAddressScheme} {
+		if strings.HasPrefix(dc.Address, scheme) {
+			addressScheme = scheme
+			break
+		}
+	}
+	hosts := []string{strings.TrimPrefix(dc.Address, addressScheme)}
+	for _, address := range dc.Addresses {
+		host := strings.TrimPrefix(address, addressScheme)
+		if strings.HasPrefix(address, addressScheme) && !strings.Contains(host, ":///") {
+			hosts = append(hosts, host)
+		}
+	}
+
+	u := url.URL{
+		Scheme: URLScheme,
+		Host:   strings.Join(hosts, ","),
+	}
+	if dc.TLS {
+		u.Scheme = URLSchemeTLS
+	}
+	if dc.Token != "" {
+		u.User = url.User(dc.Token)
+	}
+	if dc.Namespace != "" {
+		u.Path = "/" + dc.Namespace
+	}
+
+	query := url.Values{}
+	switch addressScheme {
+	case DNSAddressScheme:
+		query.Set("resolve", "dns")
+	case DNSSRVAddressScheme:
+		query.Set("resolve", "dns+srv")
+	}
+	if dc.QueryType != QueryTypeSQL {
+		query.Set("query_type", dc.QueryType.String())
+	}
+	if dc.FlightSQL {
+		query.Set("flight_sql", "true")
+	}
+	if dc.Timeout != 0 {
+		query.Set("timeout", dc.Timeout.String())
+	}
+	if dc.WriteURL != "" {
+		query.Set("write_url", dc.WriteURL)
+	}
+	if dc.TLSCA != "" {
+		query.Set("tls_ca", dc.TLSCA)
+	}
+	if dc.TLSCert != "" {
+		query.Set("tls_cert", dc.TLSCert)
+	}
+	if dc.TLSKey != "" {
+		query.Set("tls_key", dc.TLSKey)
+	}
+	if dc.TLSInsecureSkipVerify {
+		query.Set("tls_insecure_skip_verify", "true")
+	}
+	if dc.TLSServerName != "" {
+		query.Set("tls_server_name", dc.TLSServerName)
+	}
+	if dc.LogQueryText {
+		query.Set("log_query_text", "true")
+	}
+	if dc.AllowInsecureToken {
+		query.Set("allow_insecure_token", "true")
+	}
+	if dc.LoadBalancing != "" {
+		query.Set("load_balancing", string(dc.LoadBalancing))
+	}
+	if dc.HealthCheckInterval != 0 {
+		query.Set("health_check_interval", dc.HealthCheckInterval.String())
+	}
+	if dc.Location != "" {
+		query.Set("location", dc.Location)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ClientConfigFromString constructs an instance of *ClientConfig from any
+// supported data source name format: JSON (see ClientConfigFromJSONString),
+// URL with scheme URLScheme or URLSchemeTLS (see ClientConfigFromURLString),
+// or address (see ClientConfigFromAddressString), which may itself contain
+// "://", as in DNSAddressScheme.
+func ClientConfigFromString(s string) (*ClientConfig, error) {
+	trimmed := strings.TrimSpace(s)
+	lower := strings.ToLower(trimmed)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		return ClientConfigFromJSONString(s)
+	case strings.HasPrefix(lower, URLScheme+"://"), strings.HasPrefix(lower, URLSchemeTLS+"://"):
+		return ClientConfigFromURLString(trimmed)
+	default:
+		return ClientConfigFromAddressString(s)
+	}
+}
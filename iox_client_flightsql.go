@@ -0,0 +1,415 @@
+package influxdbiox
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	"github.com/apache/arrow/go/v10/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// flightSQLNamespaceHeader is the gRPC metadata key IOx uses to select the
+// namespace of a Flight SQL request.
+const flightSQLNamespaceHeader = "iox-namespace-name"
+
+// flightSQLTransport adapts the Client's flight.FlightServiceClient to the
+// flight.Client interface required by flightsql.Client.
+// Authentication and connection lifetime are managed by Client, not here.
+type flightSQLTransport struct {
+	flight.FlightServiceClient
+}
+
+func (flightSQLTransport) Authenticate(context.Context, ...grpc.CallOption) error {
+	return nil
+}
+
+func (flightSQLTransport) AuthenticateBasicToken(ctx context.Context, _, _ string, _ ...grpc.CallOption) (context.Context, error) {
+	return ctx, errors.New("basic token authentication is not supported")
+}
+
+func (flightSQLTransport) Close() error {
+	return nil
+}
+
+// DoAction reads the response to a ClosePreparedStatement action, which
+// flightsql.PreparedStatement.Close does not, so that the statement is
+// closed, and the stream finished, when Close returns.
+func (t flightSQLTransport) DoAction(ctx context.Context, action *flight.Action, opts ...grpc.CallOption) (flight.FlightService_DoActionClient, error) {
+	stream, err := t.FlightServiceClient.DoAction(ctx, action, opts...)
+	if err != nil || action.GetType() != flightsql.ClosePreparedStatementActionType {
+		return stream, err
+	}
+	for {
+		if _, err = stream.Recv(); err == io.EOF {
+			return stream, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// queryFlightSQL executes the query with the Flight SQL protocol.
+//
+// Without arguments, the query is sent as a CommandStatementQuery.
+// With arguments, the query is prepared on the server, and the arguments are
+// sent as parameter bindings of the prepared statement.
+// In both cases, every endpoint in the returned FlightInfo is fetched with
+// DoGet, over this Client's connection, and the streams are concatenated.
+// Endpoints at other locations are not supported. A prepared statement is
+// closed when the returned stream is closed.
+func (r *QueryRequest) queryFlightSQL(ctx context.Context, args []interface{}) (flight.DataStreamReader, error) {
+	if r.queryType != QueryTypeSQL {
+		return nil, fmt.Errorf("query type %s is not supported with Flight SQL", r.queryType)
+	}
+	if r.database != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, flightSQLNamespaceHeader, r.database)
+	}
+	client := &flightsql.Client{
+		Client: flightSQLTransport{r.client.flightClient},
+		Alloc:  r.allocator,
+	}
+
+	stream := &endpointStream{
+		ctx:             ctx,
+		flightClient:    r.client.flightClient,
+		grpcCallOptions: r.grpcCallOptions,
+		retryPolicy:     r.client.config.RetryPolicy,
+		logger:          r.client.telemetry.logger,
+	}
+	var err error
+	if len(args) == 0 {
+		stream.info, err = client.Execute(ctx, r.query, r.grpcCallOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("flight SQL statement query failed: %w", err)
+		}
+	} else {
+		stream.prepared, err = client.Prepare(ctx, r.allocator, r.query, r.grpcCallOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("flight SQL prepare failed: %w", err)
+		}
+
+		parameters, err := newParameterRecord(r.allocator, stream.prepared.ParameterSchema(), args)
+		if err != nil {
+			_ = stream.Close()
+			return nil, fmt.Errorf("failed to bind query arguments: %w", err)
+		}
+		stream.prepared.SetParameters(parameters)
+		parameters.Release()
+
+		stream.info, err = stream.prepared.Execute(ctx)
+		if err != nil {
+			_ = stream.Close()
+			return nil, fmt.Errorf("flight SQL prepared statement query failed: %w", err)
+		}
+	}
+
+	for i, endpoint := range stream.info.Endpoint {
+		if len(endpoint.Location) > 0 {
+			_ = stream.Close()
+			return nil, fmt.Errorf("flight SQL endpoint %d is at location %s; endpoints at other locations are not supported", i, endpoint.Location[0].GetUri())
+		}
+	}
+	return stream, nil
+}
+
+// endpointStream implements flight.DataStreamReader by calling DoGet on each
+// endpoint of a FlightInfo in turn.
+//
+// Every DoGet stream begins with a schema message; only the first is passed
+// through, so that the concatenation reads as a single stream.
+type endpointStream struct {
+	ctx             context.Context
+	flightClient    flight.FlightServiceClient
+	grpcCallOptions []grpc.CallOption
+	retryPolicy     *RetryPolicy
+	logger          Logger
+	info            *flight.FlightInfo
+	prepared        *flightsql.PreparedStatement // nil unless the query has arguments
+
+	next    int // index of the next endpoint to fetch
+	current flight.DataStreamReader
+	started bool // whether a schema message has been passed through
+}
+
+func (s *endpointStream) Recv() (*flight.FlightData, error) {
+	for {
+		if s.current == nil {
+			if s.next >= len(s.info.Endpoint) {
+				if !s.started && len(s.info.Schema) > 0 {
+					// No endpoints, so no DoGet stream will provide the schema.
+					s.started = true
+					return schemaFlightData(s.info.Schema)
+				}
+				return nil, io.EOF
+			}
+			endpoint := s.info.Endpoint[s.next]
+			s.next++
+			doGetClient, err := newRetryDoGetStream(s.ctx, s.flightClient, endpoint.Ticket, s.retryPolicy, s.logger, s.grpcCallOptions)
+			if err != nil {
+				return nil, fmt.Errorf("arrow Flight DoGet request failed: %w", err)
+			}
+			s.current = doGetClient
+			if s.started {
+				if _, err = s.current.Recv(); err == io.EOF {
+					s.current = nil
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		data, err := s.current.Recv()
+		if err == io.EOF {
+			s.current = nil
+			continue
+		}
+		s.started = true
+		return data, err
+	}
+}
+
+// Close closes the current DoGet stream, and the prepared statement, if any.
+// The prepared statement is closed even if the context of the query has
+// been canceled.
+func (s *endpointStream) Close() error {
+	if s.current != nil {
+		closeStream(s.current)
+		s.current = nil
+	}
+	s.next = len(s.info.GetEndpoint())
+	if s.prepared == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), flightSQLCloseTimeout)
+	defer cancel()
+	if md, ok := metadata.FromOutgoingContext(s.ctx); ok {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	err := s.prepared.Close(ctx)
+	s.prepared = nil
+	return err
+}
+
+// flightSQLCloseTimeout limits the request that closes a prepared statement.
+const flightSQLCloseTimeout = 10 * time.Second
+
+// schemaFlightData converts the IPC encapsulated schema message in
+// FlightInfo.Schema into the FlightData message that begins a DoGet stream.
+func schemaFlightData(schema []byte) (*flight.FlightData, error) {
+	const continuationToken = 0xFFFFFFFF
+	if len(schema) >= 4 && binary.LittleEndian.Uint32(schema) == continuationToken {
+		schema = schema[4:]
+	}
+	if len(schema) < 4 {
+		return nil, errors.New("invalid schema in Flight SQL response")
+	}
+	length := int(binary.LittleEndian.Uint32(schema))
+	if length > len(schema)-4 {
+		return nil, errors.New("invalid schema in Flight SQL response")
+	}
+	return &flight.FlightData{DataHeader: schema[4 : 4+length]}, nil
+}
+
+// newParameterRecord builds a single-row record containing the query
+// arguments, for binding to a Flight SQL prepared statement.
+//
+// Positional arguments are bound in order, and named after the server's
+// parameter schema, if any, or $N. Arguments constructed with Named are
+// bound by name, in the order of the parameter schema; see parameterArgs.
+func newParameterRecord(mem memory.Allocator, parameterSchema *arrow.Schema, args []interface{}) (arrow.Record, error) {
+	names, args, err := parameterArgs(parameterSchema, args)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]arrow.Field, len(args))
+	columns := make([]arrow.Array, len(args))
+	defer func() {
+		for _, column := range columns {
+			if column != nil {
+				column.Release()
+			}
+		}
+	}()
+
+	for i, arg := range args {
+		var columnType ColumnType
+		if typedArg, ok := arg.(TypedArg); ok {
+			columnType, arg = typedArg.Type, typedArg.Value
+		} else {
+			var err error
+			if columnType, err = inferColumnType(arg); err != nil {
+				return nil, fmt.Errorf("parameter %s: %w", names[i], err)
+			}
+		}
+
+		column, err := newParameterArray(mem, columnType, arg)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", names[i], err)
+		}
+		columns[i] = column
+		fields[i] = arrow.Field{Name: names[i], Type: column.DataType(), Nullable: true}
+	}
+
+	return array.NewRecord(arrow.NewSchema(fields, nil), columns, 1), nil
+}
+
+// parameterArgs returns the names of the parameters of a prepared statement
+// that args are bound to, and the value bound to each.
+//
+// Positional arguments are bound in order. Named arguments are bound to the
+// parameter of the same name, ignoring a leading $ in either, and every
+// parameter in the parameter schema must have exactly one argument. Without
+// a parameter schema, named arguments are bound in order, under their own
+// names. Named and positional arguments cannot be mixed.
+func parameterArgs(parameterSchema *arrow.Schema, args []interface{}) ([]string, []interface{}, error) {
+	var parameters []arrow.Field
+	if parameterSchema != nil {
+		parameters = parameterSchema.Fields()
+	}
+	var namedArgs []NamedArg
+	for _, arg := range args {
+		if namedArg, ok := arg.(NamedArg); ok {
+			namedArgs = append(namedArgs, namedArg)
+		}
+	}
+
+	switch {
+	case len(namedArgs) == 0:
+		names := make([]string, len(args))
+		for i := range args {
+			if i < len(parameters) {
+				names[i] = parameters[i].Name
+			} else {
+				names[i] = fmt.Sprintf("$%d", i+1)
+			}
+		}
+		return names, args, nil
+	case len(namedArgs) < len(args):
+		return nil, nil, errors.New("named and positional arguments cannot be mixed")
+	case len(parameters) == 0:
+		names := make([]string, len(args))
+		values := make([]interface{}, len(args))
+		for i, namedArg := range namedArgs {
+			names[i], values[i] = namedArg.Name, namedArg.Value
+		}
+		return names, values, nil
+	}
+
+	byName := make(map[string]interface{}, len(namedArgs))
+	for _, namedArg := range namedArgs {
+		name := strings.TrimPrefix(namedArg.Name, "$")
+		if _, ok := byName[name]; ok {
+			return nil, nil, fmt.Errorf("argument name %q is used more than once", namedArg.Name)
+		}
+		byName[name] = namedArg.Value
+	}
+	names := make([]string, len(parameters))
+	values := make([]interface{}, len(parameters))
+	for i, parameter := range parameters {
+		name := strings.TrimPrefix(parameter.Name, "$")
+		value, ok := byName[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("no argument provided for parameter %s", parameter.Name)
+		}
+		delete(byName, name)
+		names[i], values[i] = parameter.Name, value
+	}
+	for _, namedArg := range namedArgs {
+		if _, ok := byName[strings.TrimPrefix(namedArg.Name, "$")]; ok {
+			return nil, nil, fmt.Errorf("argument %q does not match any parameter of the prepared statement", namedArg.Name)
+		}
+	}
+	return names, values, nil
+}
+
+// newParameterArray builds a single-element array holding v as the Arrow
+// type corresponding to columnType.
+func newParameterArray(mem memory.Allocator, columnType ColumnType, v interface{}) (arrow.Array, error) {
+	if v == nil {
+		return array.NewNull(1), nil
+	}
+	switch columnType {
+	case ColumnType_I64:
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		b := array.NewInt64Builder(mem)
+		defer b.Release()
+		b.Append(i)
+		return b.NewArray(), nil
+	case ColumnType_U64:
+		u, err := toUint64(v)
+		if err != nil {
+			return nil, err
+		}
+		b := array.NewUint64Builder(mem)
+		defer b.Release()
+		b.Append(u)
+		return b.NewArray(), nil
+	case ColumnType_F64:
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		b := array.NewFloat64Builder(mem)
+		defer b.Release()
+		b.Append(f)
+		return b.NewArray(), nil
+	case ColumnType_BOOL:
+		value, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+		b := array.NewBooleanBuilder(mem)
+		defer b.Release()
+		b.Append(value)
+		return b.NewArray(), nil
+	case ColumnType_STRING, ColumnType_TAG:
+		switch s := v.(type) {
+		case string:
+			b := array.NewStringBuilder(mem)
+			defer b.Release()
+			b.Append(s)
+			return b.NewArray(), nil
+		case []byte:
+			if columnType == ColumnType_TAG {
+				return newParameterArray(mem, columnType, string(s))
+			}
+			b := array.NewBinaryBuilder(mem, arrow.BinaryTypes.Binary)
+			defer b.Release()
+			b.Append(s)
+			return b.NewArray(), nil
+		default:
+			return nil, fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+	case ColumnType_TIME:
+		var ns int64
+		switch t := v.(type) {
+		case time.Time:
+			ns = t.UnixNano()
+		case int64:
+			ns = t
+		default:
+			return nil, fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+		b := array.NewTimestampBuilder(mem, &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"})
+		defer b.Release()
+		b.Append(arrow.Timestamp(ns))
+		return b.NewArray(), nil
+	default:
+		return nil, fmt.Errorf("cannot encode %T as %s", v, columnType)
+	}
+}
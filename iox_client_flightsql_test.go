@@ -0,0 +1,319 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	"github.com/apache/arrow/go/v10/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+var flightSQLTestSchema = arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+// flightSQLTestServer serves a two-endpoint result for every statement,
+// and records what the client sent. The tickets of a prepared statement are
+// rejected once it is closed.
+type flightSQLTestServer struct {
+	flightsql.BaseServer
+
+	mu          sync.Mutex
+	query       string
+	namespace   []string
+	parameters  arrow.Record
+	prepared    map[string]bool // open prepared statement handles
+	closed      bool
+	location    string        // if set, the location of every endpoint
+	paramSchema *arrow.Schema // if set, the parameter schema of every prepared statement
+}
+
+// flightInfo returns the endpoints of a result; for a prepared statement,
+// preparedHandle is its handle, which is sent back in their tickets.
+func (s *flightSQLTestServer) flightInfo(desc *flight.FlightDescriptor, preparedHandle string) (*flight.FlightInfo, error) {
+	info := &flight.FlightInfo{
+		Schema:           flight.SerializeSchema(flightSQLTestSchema, memory.DefaultAllocator),
+		FlightDescriptor: desc,
+	}
+	for _, handle := range []string{"first", "second"} {
+		ticket, err := flightsql.CreateStatementQueryTicket([]byte(preparedHandle + "\x00" + handle))
+		if err != nil {
+			return nil, err
+		}
+		endpoint := &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: ticket}}
+		if s.location != "" {
+			endpoint.Location = []*flight.Location{{Uri: s.location}}
+		}
+		info.Endpoint = append(info.Endpoint, endpoint)
+	}
+	return info, nil
+}
+
+func (s *flightSQLTestServer) GetFlightInfoStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.query = cmd.GetQuery()
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.namespace = md.Get("iox-namespace-name")
+	return s.flightInfo(desc, "")
+}
+
+func (s *flightSQLTestServer) CreatePreparedStatement(ctx context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.query = req.GetQuery()
+	if s.prepared == nil {
+		s.prepared = make(map[string]bool)
+	}
+	s.prepared[req.GetQuery()] = true
+	return flightsql.ActionCreatePreparedStatementResult{
+		Handle:          []byte(req.GetQuery()),
+		ParameterSchema: s.paramSchema,
+	}, nil
+}
+
+func (s *flightSQLTestServer) DoPutPreparedStatementQuery(ctx context.Context, cmd flightsql.PreparedStatementQuery, reader flight.MessageReader, _ flight.MetadataWriter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for reader.Next() {
+		s.parameters = reader.Record()
+		s.parameters.Retain()
+	}
+	return reader.Err()
+}
+
+func (s *flightSQLTestServer) GetFlightInfoPreparedStatement(ctx context.Context, cmd flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flightInfo(desc, string(cmd.GetPreparedStatementHandle()))
+}
+
+func (s *flightSQLTestServer) ClosePreparedStatement(ctx context.Context, req flightsql.ActionClosePreparedStatementRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.prepared, string(req.GetPreparedStatementHandle()))
+	s.closed = true
+	return nil
+}
+
+func (s *flightSQLTestServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *flightSQLTestServer) DoGetStatement(ctx context.Context, ticket flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	preparedHandle, handle, _ := strings.Cut(string(ticket.GetStatementHandle()), "\x00")
+	s.mu.Lock()
+	open := preparedHandle == "" || s.prepared[preparedHandle]
+	s.mu.Unlock()
+	if !open {
+		return nil, nil, status.Errorf(codes.NotFound, "prepared statement %q is closed", preparedHandle)
+	}
+	values := map[string][]int64{
+		"first":  {1, 2},
+		"second": {3},
+	}[handle]
+
+	b := array.NewInt64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.AppendValues(values, nil)
+	column := b.NewArray()
+	defer column.Release()
+
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: array.NewRecord(flightSQLTestSchema, []arrow.Array{column}, int64(len(values)))}
+	close(ch)
+	return flightSQLTestSchema, ch, nil
+}
+
+func newFlightSQLTestClient(ctx context.Context, t *testing.T, srv *flightSQLTestServer) *influxdbiox.Client {
+	listener := bufconn.Listen(1 << 20)
+	flightServer := flight.NewFlightServer()
+	flightServer.RegisterFlightService(flightsql.NewFlightServer(srv))
+	flightServer.InitListener(listener)
+	go func() { _ = flightServer.Serve() }()
+	t.Cleanup(flightServer.Shutdown)
+
+	config := &influxdbiox.ClientConfig{
+		Address:   "bufnet",
+		Namespace: "mydb",
+		FlightSQL: true,
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+		},
+	}
+	return newTestClient(ctx, t, config)
+}
+
+func readInt64Column(t *testing.T, reader *influxdbiox.QueryReader) []int64 {
+	var values []int64
+	for reader.Next() {
+		values = append(values, reader.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, reader.Err())
+	return values
+}
+
+func TestClient_FlightSQL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	srv := new(flightSQLTestServer)
+	client := newFlightSQLTestClient(ctx, t, srv)
+
+	req, err := client.PrepareQuery(ctx, "", "select v from t")
+	require.NoError(t, err)
+	reader, err := req.Query(ctx)
+	require.NoError(t, err)
+	t.Cleanup(reader.Release)
+
+	assert.True(t, reader.Schema().Equal(flightSQLTestSchema))
+	assert.Equal(t, []int64{1, 2, 3}, readInt64Column(t, reader))
+	assert.Equal(t, "select v from t", srv.query)
+	assert.Equal(t, []string{"mydb"}, srv.namespace)
+}
+
+func TestClient_FlightSQL_prepared(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	srv := new(flightSQLTestServer)
+	client := newFlightSQLTestClient(ctx, t, srv)
+
+	req, err := client.PrepareQuery(ctx, "", "select v from t where foo = $1 and v > $2")
+	require.NoError(t, err)
+	reader, err := req.Query(ctx, "bar", influxdbiox.Typed(influxdbiox.ColumnType_U64, 1))
+	require.NoError(t, err)
+
+	// The prepared statement stays open until the reader is released.
+	assert.Equal(t, []int64{1, 2, 3}, readInt64Column(t, reader))
+	assert.Equal(t, "select v from t where foo = $1 and v > $2", srv.query)
+	assert.False(t, srv.isClosed())
+	reader.Release()
+	assert.True(t, srv.isClosed())
+
+	require.NotNil(t, srv.parameters)
+	t.Cleanup(srv.parameters.Release)
+	require.EqualValues(t, 1, srv.parameters.NumRows())
+	assert.Equal(t, "$1", srv.parameters.ColumnName(0))
+	assert.Equal(t, "bar", srv.parameters.Column(0).(*array.String).Value(0))
+	assert.Equal(t, "$2", srv.parameters.ColumnName(1))
+	assert.Equal(t, uint64(1), srv.parameters.Column(1).(*array.Uint64).Value(0))
+}
+
+func TestClient_FlightSQL_preparedNamed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	srv := &flightSQLTestServer{paramSchema: arrow.NewSchema([]arrow.Field{
+		{Name: "$foo", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "$v", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)}
+	client := newFlightSQLTestClient(ctx, t, srv)
+
+	// Named arguments are bound in the order of the parameter schema.
+	req, err := client.PrepareQuery(ctx, "", "select v from t where v > $v and foo = $foo")
+	require.NoError(t, err)
+	reader, err := req.Query(ctx, influxdbiox.Named("v", 1), influxdbiox.Named("$foo", "bar"))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, readInt64Column(t, reader))
+	reader.Release()
+
+	require.NotNil(t, srv.parameters)
+	t.Cleanup(srv.parameters.Release)
+	require.EqualValues(t, 2, srv.parameters.NumCols())
+	assert.Equal(t, "$foo", srv.parameters.ColumnName(0))
+	assert.Equal(t, "bar", srv.parameters.Column(0).(*array.String).Value(0))
+	assert.Equal(t, "$v", srv.parameters.ColumnName(1))
+	assert.Equal(t, int64(1), srv.parameters.Column(1).(*array.Int64).Value(0))
+
+	for _, tc := range []struct {
+		args    []interface{}
+		wantErr string
+	}{
+		{
+			args:    []interface{}{influxdbiox.Named("foo", "bar")},
+			wantErr: "no argument provided for parameter $v",
+		},
+		{
+			args:    []interface{}{influxdbiox.Named("foo", "bar"), influxdbiox.Named("v", 1), influxdbiox.Named("w", 2)},
+			wantErr: `argument "w" does not match any parameter of the prepared statement`,
+		},
+		{
+			args:    []interface{}{influxdbiox.Named("foo", "bar"), influxdbiox.Named("$foo", "baz"), influxdbiox.Named("v", 1)},
+			wantErr: `argument name "$foo" is used more than once`,
+		},
+		{
+			args:    []interface{}{influxdbiox.Named("foo", "bar"), 1},
+			wantErr: "named and positional arguments cannot be mixed",
+		},
+	} {
+		_, err := req.Query(ctx, tc.args...)
+		assert.EqualError(t, err, "failed to bind query arguments: "+tc.wantErr)
+	}
+}
+
+func TestClient_FlightSQL_preparedRelease(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	srv := new(flightSQLTestServer)
+	client := newFlightSQLTestClient(ctx, t, srv)
+
+	// Releasing the reader before the last endpoint is fetched closes the
+	// prepared statement.
+	req, err := client.PrepareQuery(ctx, "", "select v from t where v > $1")
+	require.NoError(t, err)
+	reader, err := req.Query(ctx, 0)
+	require.NoError(t, err)
+	require.True(t, reader.Next())
+	assert.False(t, srv.isClosed())
+	reader.Release()
+	assert.True(t, srv.isClosed())
+}
+
+func TestClient_FlightSQL_location(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	srv := &flightSQLTestServer{location: "grpc+tcp://querier-2:8082"}
+	client := newFlightSQLTestClient(ctx, t, srv)
+
+	req, err := client.PrepareQuery(ctx, "", "select v from t")
+	require.NoError(t, err)
+	_, err = req.Query(ctx)
+	assert.EqualError(t, err, "flight SQL endpoint 0 is at location grpc+tcp://querier-2:8082; endpoints at other locations are not supported")
+
+	_, err = req.Query(ctx, 1)
+	assert.Error(t, err)
+	assert.True(t, srv.isClosed())
+}
+
+func TestClient_FlightSQL_influxQL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	client := newFlightSQLTestClient(ctx, t, new(flightSQLTestServer))
+
+	req, err := client.PrepareInfluxQLQuery(ctx, "", "SELECT v FROM t")
+	require.NoError(t, err)
+	_, err = req.Query(ctx)
+	assert.EqualError(t, err, "query type influxql is not supported with Flight SQL")
+}
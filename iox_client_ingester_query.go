@@ -0,0 +1,240 @@
+package influxdbiox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	"google.golang.org/protobuf/proto"
+
+	ingester "github.com/influxdata/influxdb-iox-client-go/v2/internal/ingester"
+)
+
+// IngesterQuery is a request for the data of a table that an ingester has
+// buffered but not yet persisted. The IDs are those of NamespaceSchema and
+// TableSchema, as returned by Client.GetNamespaceSchema.
+type IngesterQuery struct {
+	// NamespaceID is the ID of the namespace to search.
+	NamespaceID int64
+	// TableID is the ID of the table to search.
+	TableID int64
+	// Columns are the names of the columns to return; all if empty.
+	Columns []string
+	// Predicate filters the rows returned; nil returns all rows.
+	Predicate *IngesterPredicate
+}
+
+// IngesterPredicate filters the rows returned by an IngesterQuery. Rows must
+// satisfy every condition given.
+type IngesterPredicate struct {
+	// FieldColumns restricts the results to tables with at least one of
+	// these field columns.
+	FieldColumns []string
+	// TimeRange restricts the results to rows with a time in the range.
+	TimeRange *TimeRange
+	// Exprs are DataFusion expressions, in DataFusion's protobuf
+	// serialization, that every row must satisfy.
+	Exprs [][]byte
+	// ValueExprs are DataFusion expressions, in DataFusion's protobuf
+	// serialization, on the special _value column of FieldColumns.
+	ValueExprs [][]byte
+}
+
+// TimeRange is a range of timestamps, from Start inclusive to End exclusive.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// IngesterPartitionStatus is the status of a partition with unpersisted data,
+// as reported by an ingester in response to an IngesterQuery.
+type IngesterPartitionStatus struct {
+	// PartitionID is the ID of the partition.
+	PartitionID int64
+	// ParquetMaxSequenceNumber is the greatest sequence number of the writes
+	// persisted to Parquet files, or nil if none have been persisted.
+	ParquetMaxSequenceNumber *int64
+}
+
+// QueryIngester sends query to the ingester that this client is connected
+// to, for debugging the visibility of writes that have not been persisted.
+// The ingester returns the buffered rows of the table in record batches,
+// each belonging to a partition, followed by the status of each partition.
+//
+// If ClientConfig.RetryPolicy is set, the query is sent again after a
+// transient failure, until the first record batch is received.
+//
+// The returned *IngesterQueryReader must be released when the caller is done
+// with it.
+func (c *Client) QueryIngester(ctx context.Context, query IngesterQuery) (*IngesterQueryReader, error) {
+	ticket, err := proto.Marshal(query.request())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ingester query: %w", err)
+	}
+	doGetClient, err := newRetryDoGetStream(ctx, c.flightClient, &flight.Ticket{Ticket: ticket}, c.config.RetryPolicy, c.telemetry.logger, nil)
+	if err != nil {
+		return nil, fmt.Errorf("arrow Flight DoGet request failed: %w", err)
+	}
+
+	stream := &ingesterQueryStream{stream: doGetClient}
+	reader := &IngesterQueryReader{stream: stream, refCount: 1}
+	reader.flightReader, err = flight.NewRecordReader(stream)
+	if errors.Is(err, io.EOF) {
+		// The ingester has no data for the table, only partition statuses.
+		reader.schema = arrow.NewSchema(nil, nil)
+		return reader, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to create Flight record reader: %w", err)
+	}
+	reader.schema = reader.flightReader.Schema()
+	return reader, nil
+}
+
+func (q IngesterQuery) request() *ingester.IngesterQueryRequest {
+	request := &ingester.IngesterQueryRequest{
+		NamespaceId: q.NamespaceID,
+		TableId:     q.TableID,
+		Columns:     q.Columns,
+	}
+	if p := q.Predicate; p != nil {
+		request.Predicate = &ingester.Predicate{
+			FieldColumns: p.FieldColumns,
+			Exprs:        p.Exprs,
+		}
+		if p.TimeRange != nil {
+			request.Predicate.Range = &ingester.TimestampRange{
+				Start: p.TimeRange.Start.UnixNano(),
+				End:   p.TimeRange.End.UnixNano(),
+			}
+		}
+		for _, expr := range p.ValueExprs {
+			request.Predicate.ValueExpr = append(request.Predicate.ValueExpr, &ingester.ValueExpr{Expr: expr})
+		}
+	}
+	return request
+}
+
+// IngesterQueryReader reads the response to Client.QueryIngester. It
+// implements array.RecordReader.
+type IngesterQueryReader struct {
+	stream       *ingesterQueryStream
+	flightReader *flight.Reader // nil if the response has no records
+	schema       *arrow.Schema
+	refCount     int64
+	partitionID  int64
+	err          error
+}
+
+// Schema returns the schema of the records. It has no fields if the
+// ingester returned no records.
+func (r *IngesterQueryReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Next advances to the next record, reporting whether there is one.
+func (r *IngesterQueryReader) Next() bool {
+	if r.flightReader == nil || r.err != nil {
+		return false
+	}
+	if !r.flightReader.Next() {
+		return false
+	}
+	metadata, err := decodeIngesterQueryMetadata(r.flightReader.LatestAppMetadata())
+	if err != nil {
+		r.err = err
+		return false
+	}
+	r.partitionID = metadata.GetPartitionId()
+	return true
+}
+
+// Record returns the current record. It is valid until the next call to
+// Next.
+func (r *IngesterQueryReader) Record() arrow.Record {
+	if r.flightReader == nil {
+		return nil
+	}
+	return r.flightReader.Record()
+}
+
+// PartitionID returns the ID of the partition that the current record
+// belongs to.
+func (r *IngesterQueryReader) PartitionID() int64 {
+	return r.partitionID
+}
+
+// PartitionStatuses returns the status of each partition received so far.
+// The statuses follow the records of their partitions, so all have been
+// received once Next returns false.
+func (r *IngesterQueryReader) PartitionStatuses() []IngesterPartitionStatus {
+	return append([]IngesterPartitionStatus(nil), r.stream.statuses...)
+}
+
+// Err returns the error that ended the response, if any.
+func (r *IngesterQueryReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.flightReader != nil {
+		return r.flightReader.Err()
+	}
+	return nil
+}
+
+// Retain increases the reference count of the reader.
+func (r *IngesterQueryReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+// Release decreases the reference count of the reader, releasing its
+// resources when the count reaches zero.
+func (r *IngesterQueryReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		if r.flightReader != nil {
+			r.flightReader.Release()
+		}
+		closeStream(r.stream.stream)
+	}
+}
+
+// ingesterQueryStream removes the Flight data messages that only carry a
+// partition status, which are not Arrow IPC messages, keeping the statuses.
+type ingesterQueryStream struct {
+	stream   flight.DataStreamReader
+	statuses []IngesterPartitionStatus
+}
+
+func (s *ingesterQueryStream) Recv() (*flight.FlightData, error) {
+	for {
+		data, err := s.stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if len(data.DataHeader) > 0 {
+			return data, nil
+		}
+		metadata, err := decodeIngesterQueryMetadata(data.AppMetadata)
+		if err != nil {
+			return nil, err
+		}
+		if status := metadata.GetStatus(); status != nil {
+			s.statuses = append(s.statuses, IngesterPartitionStatus{
+				PartitionID:              metadata.GetPartitionId(),
+				ParquetMaxSequenceNumber: status.ParquetMaxSequenceNumber,
+			})
+		}
+	}
+}
+
+func decodeIngesterQueryMetadata(appMetadata []byte) (*ingester.IngesterQueryResponseMetadata, error) {
+	metadata := &ingester.IngesterQueryResponseMetadata{}
+	if err := proto.Unmarshal(appMetadata, metadata); err != nil {
+		return nil, fmt.Errorf("invalid ingester query response metadata: %w", err)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,130 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func ExampleClient_QueryIngester() {
+	ctx := context.Background()
+	client, _ := influxdbiox.NewClient(ctx, &influxdbiox.ClientConfig{Address: "localhost:8083"})
+
+	namespaceSchema, _ := client.GetNamespaceSchema(ctx, "mydb")
+	table, _ := namespaceSchema.Table("cpu")
+	reader, _ := client.QueryIngester(ctx, influxdbiox.IngesterQuery{
+		NamespaceID: namespaceSchema.ID,
+		TableID:     table.ID,
+		Columns:     []string{"time", "usage"},
+		Predicate: &influxdbiox.IngesterPredicate{
+			TimeRange: &influxdbiox.TimeRange{Start: time.Now().Add(-time.Hour), End: time.Now()},
+		},
+	})
+	defer reader.Release()
+	for reader.Next() {
+		fmt.Println(reader.PartitionID(), reader.Record().NumRows())
+	}
+	for _, partitionStatus := range reader.PartitionStatuses() {
+		fmt.Println(partitionStatus.PartitionID, partitionStatus.ParquetMaxSequenceNumber)
+	}
+}
+
+func newIngesterRecord(t *testing.T, mem memory.Allocator, values ...float64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "usage", Type: arrow.PrimitiveTypes.Float64}}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.Float64Builder).AppendValues(values, nil)
+	record := b.NewRecord()
+	t.Cleanup(record.Release)
+	return record
+}
+
+func TestClient_QueryIngester(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddTable("mydb", "cpu", map[string]influxdbiox.ColumnType{
+		"time":  influxdbiox.ColumnType_TIME,
+		"usage": influxdbiox.ColumnType_F64,
+	})
+	client := newTestClient(ctx, t, server.ClientConfig("mydb"))
+	namespaceSchema, err := client.GetNamespaceSchema(ctx, "mydb")
+	require.NoError(t, err)
+	table, err := namespaceSchema.Table("cpu")
+	require.NoError(t, err)
+
+	persisted := int64(42)
+	server.HandleIngesterQuery(table.ID,
+		ioxtest.IngesterPartition{ID: 7, ParquetMaxSequenceNumber: &persisted, Records: []arrow.Record{
+			newIngesterRecord(t, memory.DefaultAllocator, 1, 2),
+			newIngesterRecord(t, memory.DefaultAllocator, 3),
+		}},
+		ioxtest.IngesterPartition{ID: 8, Records: []arrow.Record{newIngesterRecord(t, memory.DefaultAllocator, 4)}},
+	)
+
+	query := influxdbiox.IngesterQuery{
+		NamespaceID: namespaceSchema.ID,
+		TableID:     table.ID,
+		Columns:     []string{"usage"},
+		Predicate: &influxdbiox.IngesterPredicate{
+			FieldColumns: []string{"usage"},
+			TimeRange:    &influxdbiox.TimeRange{Start: time.Unix(10, 0).UTC(), End: time.Unix(20, 0).UTC()},
+			Exprs:        [][]byte{{1, 2, 3}},
+			ValueExprs:   [][]byte{{4, 5}},
+		},
+	}
+	reader, err := client.QueryIngester(ctx, query)
+	require.NoError(t, err)
+	defer reader.Release()
+
+	assert.Equal(t, "usage", reader.Schema().Field(0).Name)
+	var partitionIDs []int64
+	var values []float64
+	for reader.Next() {
+		partitionIDs = append(partitionIDs, reader.PartitionID())
+		values = append(values, reader.Record().Column(0).(*array.Float64).Float64Values()...)
+	}
+	require.NoError(t, reader.Err())
+	assert.Equal(t, []int64{7, 7, 8}, partitionIDs)
+	assert.Equal(t, []float64{1, 2, 3, 4}, values)
+	assert.Equal(t, []influxdbiox.IngesterPartitionStatus{
+		{PartitionID: 7, ParquetMaxSequenceNumber: &persisted},
+		{PartitionID: 8},
+	}, reader.PartitionStatuses())
+	assert.Equal(t, []influxdbiox.IngesterQuery{query}, server.IngesterQueries())
+}
+
+func TestClient_QueryIngester_noRecords(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.HandleIngesterQuery(1, ioxtest.IngesterPartition{ID: 3})
+	client := newTestClient(ctx, t, server.ClientConfig("mydb"))
+
+	reader, err := client.QueryIngester(ctx, influxdbiox.IngesterQuery{NamespaceID: 1, TableID: 1})
+	require.NoError(t, err)
+	defer reader.Release()
+	assert.False(t, reader.Next())
+	assert.NoError(t, reader.Err())
+	assert.Empty(t, reader.Schema().Fields())
+	assert.Equal(t, []influxdbiox.IngesterPartitionStatus{{PartitionID: 3}}, reader.PartitionStatuses())
+
+	_, err = client.QueryIngester(ctx, influxdbiox.IngesterQuery{NamespaceID: 1, TableID: 2})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -11,17 +11,33 @@ import (
 
 const tokenWaitInterval = 500 * time.Millisecond
 
-// Blocks until the specified predicate is true.
-func (c *Client) waitForToken(ctx context.Context, writeToken string, predicate func(*ingester.GetWriteInfoResponse) bool) error {
+// Blocks until the specified predicate, named by condition, is true.
+func (c *Client) waitForToken(ctx context.Context, writeToken, condition string, predicate func(*ingester.GetWriteInfoResponse) bool) error {
+	return c.telemetry.waitForToken(ctx, condition, func(ctx context.Context) error {
+		return c.pollWriteInfo(ctx, writeToken, condition, predicate)
+	})
+}
+
+func (c *Client) pollWriteInfo(ctx context.Context, writeToken, condition string, predicate func(*ingester.GetWriteInfoResponse) bool) error {
 	request := &ingester.GetWriteInfoRequest{
 		WriteToken: writeToken,
 	}
+	logger := c.telemetry.logger
 	for {
 		response, err := c.ingesterWriteInfoClient.GetWriteInfo(ctx, request)
 		if err != nil {
+			logger.DebugContext(ctx, "failed to poll write token",
+				"write_token", writeToken, "condition", condition, "error", err)
 			return err
 		}
-		if predicate(response) {
+		done := predicate(response)
+		statuses := make([]string, len(response.ShardInfos))
+		for i, shardInfo := range response.ShardInfos {
+			statuses[i] = shardInfo.Status.String()
+		}
+		logger.DebugContext(ctx, "polled write token",
+			"write_token", writeToken, "condition", condition, "shard_statuses", statuses, "done", done)
+		if done {
 			return nil
 		}
 
@@ -47,7 +63,7 @@ func WriteTokenFromHTTPResponse(response *http.Response) (string, error) {
 // WaitForDurable blocks until the write associated with writeToken is durable,
 // meaning that the data has been safely stored in a write-ahead log.
 func (c *Client) WaitForDurable(ctx context.Context, writeToken string) error {
-	return c.waitForToken(ctx, writeToken, func(response *ingester.GetWriteInfoResponse) bool {
+	return c.waitForToken(ctx, writeToken, "durable", func(response *ingester.GetWriteInfoResponse) bool {
 		for _, pi := range response.ShardInfos {
 			if !((pi.Status == ingester.ShardStatus_SHARD_STATUS_DURABLE) || (pi.Status == ingester.ShardStatus_SHARD_STATUS_READABLE) || (pi.Status == ingester.ShardStatus_SHARD_STATUS_PERSISTED)) {
 				return false
@@ -60,7 +76,7 @@ func (c *Client) WaitForDurable(ctx context.Context, writeToken string) error {
 // WaitForReadable blocks until the write associated with writeToken is readable,
 // meaning that the data can be queried.
 func (c *Client) WaitForReadable(ctx context.Context, writeToken string) error {
-	return c.waitForToken(ctx, writeToken, func(response *ingester.GetWriteInfoResponse) bool {
+	return c.waitForToken(ctx, writeToken, "readable", func(response *ingester.GetWriteInfoResponse) bool {
 		for _, pi := range response.ShardInfos {
 			if !((pi.Status == ingester.ShardStatus_SHARD_STATUS_READABLE) || (pi.Status == ingester.ShardStatus_SHARD_STATUS_PERSISTED)) {
 				return false
@@ -74,7 +90,7 @@ func (c *Client) WaitForReadable(ctx context.Context, writeToken string) error {
 // meaning that the data has been batched, sorted, compacted, and persisted to disk
 // or object storage.
 func (c *Client) WaitForPersisted(ctx context.Context, writeToken string) error {
-	return c.waitForToken(ctx, writeToken, func(response *ingester.GetWriteInfoResponse) bool {
+	return c.waitForToken(ctx, writeToken, "persisted", func(response *ingester.GetWriteInfoResponse) bool {
 		for _, pi := range response.ShardInfos {
 			if pi.Status != ingester.ShardStatus_SHARD_STATUS_PERSISTED {
 				return false
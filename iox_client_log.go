@@ -0,0 +1,36 @@
+package influxdbiox
+
+import (
+	"context"
+)
+
+// Logger receives the debug-level events of a Client. args are alternating
+// keys and values, as accepted by the methods of log/slog.Logger, which
+// implements Logger.
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// discardLogger is a Logger that drops every event, for a ClientConfig
+// without a Logger.
+type discardLogger struct{}
+
+func (discardLogger) DebugContext(context.Context, string, ...interface{}) {}
+
+// logger returns ClientConfig.Logger, or a logger that discards everything.
+func (dc *ClientConfig) logger() Logger {
+	if dc.Logger != nil {
+		return dc.Logger
+	}
+	return discardLogger{}
+}
+
+// queryLogArgs returns the key and value that identify query in log events:
+// its text if ClientConfig.LogQueryText is set, or else its hash, as
+// recorded in traces.
+func (dc *ClientConfig) queryLogArgs(query string) []interface{} {
+	if dc.LogQueryText {
+		return []interface{}{"query", query}
+	}
+	return []interface{}{"query_hash", queryHash(query)}
+}
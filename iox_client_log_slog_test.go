@@ -0,0 +1,11 @@
+//go:build go1.21
+
+package influxdbiox_test
+
+import (
+	"log/slog"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+var _ influxdbiox.Logger = (*slog.Logger)(nil)
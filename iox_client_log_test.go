@@ -0,0 +1,184 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+// printLogger is an influxdbiox.Logger that prints events with the log
+// package. A *slog.Logger may be used instead.
+type printLogger struct{}
+
+func (printLogger) DebugContext(_ context.Context, msg string, args ...interface{}) {
+	log.Println(append([]interface{}{msg}, args...)...)
+}
+
+func ExampleClientConfig_logger() {
+	config := &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "mydb",
+		Logger:    printLogger{},
+		// Log query text, rather than a hash of it.
+		LogQueryText: true,
+	}
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+	_ = client.Handshake(context.Background())
+}
+
+// recordingLogger records log events as maps of their message, under
+// "msg", and their arguments; errors are recorded as their text.
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []map[string]interface{}
+}
+
+func (l *recordingLogger) DebugContext(_ context.Context, msg string, args ...interface{}) {
+	event := map[string]interface{}{"msg": msg}
+	for i := 0; i+1 < len(args); i += 2 {
+		value := args[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		event[args[i].(string)] = value
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+// logEvents returns the events recorded by logger, in order, and resets it.
+func logEvents(logger *recordingLogger) []map[string]interface{} {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	events := logger.events
+	logger.events = nil
+	return events
+}
+
+// logEventsWithMessage returns the events whose message is msg.
+func logEventsWithMessage(events []map[string]interface{}, msg string) []map[string]interface{} {
+	var found []map[string]interface{}
+	for _, event := range events {
+		if event["msg"] == msg {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+func TestClient_logger(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	record1, record2 := newRetryTestRecord(1, 2), newRetryTestRecord(3)
+	defer record1.Release()
+	defer record2.Release()
+	server.HandleQuery("select v from t", record1, record2)
+	server.SetWriteInfo("token", []ioxtest.ShardStatus{ioxtest.ShardStatusDurable}, []ioxtest.ShardStatus{ioxtest.ShardStatusReadable})
+
+	logger := &recordingLogger{}
+	config := server.ClientConfig("mydb")
+	config.Logger = logger
+	config.RetryPolicy = &influxdbiox.RetryPolicy{InitialBackoff: time.Millisecond}
+	client := newTestClient(ctx, t, config)
+
+	events := logEvents(logger)
+	require.Len(t, events, 1)
+	assert.Equal(t, "dialing IOx", events[0]["msg"])
+	assert.Equal(t, "bufnet", events[0]["address"])
+	assert.Equal(t, false, events[0]["tls"])
+
+	// Queries are logged with a hash of their text, and retries are logged.
+	server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: status.Error(codes.Unavailable, "restarting"), Count: 1})
+	_, err := readRetryTestQuery(ctx, t, client)
+	require.NoError(t, err)
+	events = logEvents(logger)
+	require.Len(t, events, 3)
+	assert.Equal(t, "query started", events[0]["msg"])
+	assert.Equal(t, "mydb", events[0]["namespace"])
+	assert.Equal(t, "sql", events[0]["query_type"])
+	assert.NotEmpty(t, events[0]["query_hash"])
+	assert.NotContains(t, events[0], "query")
+	assert.Equal(t, "retrying request", events[1]["msg"])
+	assert.Equal(t, "DoGet", events[1]["method"])
+	assert.EqualValues(t, 2, events[1]["attempt"])
+	assert.Contains(t, events[1]["error"], "restarting")
+	assert.Equal(t, "query finished", events[2]["msg"])
+	assert.EqualValues(t, 3, events[2]["rows"])
+	assert.EqualValues(t, 2, events[2]["batches"])
+	assert.NotContains(t, events[2], "error")
+
+	require.NoError(t, client.WaitForReadable(ctx, "token"))
+	events = logEventsWithMessage(logEvents(logger), "polled write token")
+	require.Len(t, events, 2)
+	for i, expect := range []string{"SHARD_STATUS_DURABLE", "SHARD_STATUS_READABLE"} {
+		assert.Equal(t, "token", events[i]["write_token"])
+		assert.Equal(t, "readable", events[i]["condition"])
+		assert.Equal(t, []string{expect}, events[i]["shard_statuses"])
+		assert.Equal(t, i == 1, events[i]["done"])
+	}
+
+	server.InjectFailure(ioxtest.Failure{Method: "Handshake", Err: status.Error(codes.Internal, "boom"), Count: 1})
+	assert.Error(t, client.Handshake(ctx))
+	events = logEvents(logger)
+	require.Len(t, events, 1)
+	assert.Equal(t, "handshake failed", events[0]["msg"])
+
+	require.NoError(t, client.Reconnect(ctx))
+	events = logEvents(logger)
+	require.Len(t, events, 2)
+	assert.Equal(t, "reconnecting to IOx", events[0]["msg"])
+	assert.Equal(t, "dialing IOx", events[1]["msg"])
+}
+
+func TestClient_logger_queryText(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+
+	logger := &recordingLogger{}
+	config := server.ClientConfig("mydb")
+	config.Logger = logger
+	config.LogQueryText = true
+	client := newTestClient(ctx, t, config)
+
+	_, err := readRetryTestQuery(ctx, t, client)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	events := logEvents(logger)
+	started := logEventsWithMessage(events, "query started")
+	require.Len(t, started, 1)
+	assert.Equal(t, "select v from t", started[0]["query"])
+	assert.NotContains(t, started[0], "query_hash")
+	finished := logEventsWithMessage(events, "query finished")
+	require.Len(t, finished, 1)
+	assert.Contains(t, finished[0]["error"], "InvalidArgument")
+
+	// Without a Logger, nothing is logged.
+	config.Logger = nil
+	client, err = influxdbiox.NewClient(ctx, config)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	_, err = readRetryTestQuery(ctx, t, client)
+	assert.Error(t, err)
+	assert.Empty(t, logEvents(logger))
+
+	config, err = influxdbiox.ClientConfigFromURLString("iox://localhost/mydb?log_query_text=true")
+	require.NoError(t, err)
+	assert.True(t, config.LogQueryText)
+	assert.Equal(t, "iox://localhost:8082/mydb?log_query_text=true", config.ToURL())
+}
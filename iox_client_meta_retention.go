@@ -0,0 +1,116 @@
+package influxdbiox
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	schema "github.com/influxdata/influxdb-iox-client-go/v2/internal/schema"
+)
+
+// InfiniteRetention is the retention period of a namespace whose data is
+// never deleted.
+const InfiniteRetention time.Duration = math.MaxInt64
+
+// Namespace describes an IOx namespace.
+type Namespace struct {
+	// Namespace ID
+	ID int64
+	// Namespace name
+	Name string
+	// Retention period, or InfiniteRetention
+	Retention time.Duration
+}
+
+// SetNamespaceRetention sets the retention period of namespace, returning the
+// updated namespace.
+//
+// IOx stores retention periods in whole hours, so retention must be a
+// positive multiple of time.Hour, or InfiniteRetention.
+func (c *Client) SetNamespaceRetention(ctx context.Context, namespace string, retention time.Duration) (*Namespace, error) {
+	var retentionHours int64
+	if retention != InfiniteRetention {
+		if retention <= 0 || retention%time.Hour != 0 {
+			return nil, fmt.Errorf("retention period %s must be a positive whole number of hours, or InfiniteRetention", retentionString(retention))
+		}
+		retentionHours = int64(retention / time.Hour)
+	}
+
+	client := schema.NewSchemaServiceClient(c.grpcClient)
+	resp, err := client.UpdateNamespaceRetention(ctx, &schema.UpdateNamespaceRetentionRequest{
+		Name:           namespace,
+		RetentionHours: retentionHours,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Namespace{
+		ID:        resp.GetNamespace().GetId(),
+		Name:      resp.GetNamespace().GetName(),
+		Retention: InfiniteRetention,
+	}
+	// An absent (or zero) retention period means infinite retention.
+	if resp.GetNamespace().RetentionPeriodNs != nil && resp.GetNamespace().GetRetentionPeriodNs() > 0 {
+		ret.Retention = time.Duration(resp.GetNamespace().GetRetentionPeriodNs())
+	}
+	return ret, nil
+}
+
+// RetentionUpdate is the outcome of setting the retention period of one
+// namespace in ReconcileNamespaceRetention.
+type RetentionUpdate struct {
+	// Namespace name
+	Name string
+	// Requested retention period
+	Desired time.Duration
+	// The updated namespace, or nil if Err is not nil
+	Namespace *Namespace
+	// Err is not nil if the update failed
+	Err error
+}
+
+// Applied reports whether the namespace now has the desired retention period.
+func (u RetentionUpdate) Applied() bool {
+	return u.Err == nil && u.Namespace != nil && u.Namespace.Retention == u.Desired
+}
+
+// ReconcileNamespaceRetention sets the retention period of every namespace in
+// desired, a map of namespace name to retention period (or InfiniteRetention).
+//
+// Namespaces are updated in lexical order. A failed update does not stop the
+// remaining updates; the returned slice reports the outcome for every
+// namespace, and the returned error is not nil if any update failed.
+//
+// IOx does not report the previous retention period, so every namespace is
+// updated, even if it already had the desired retention period.
+func (c *Client) ReconcileNamespaceRetention(ctx context.Context, desired map[string]time.Duration) ([]RetentionUpdate, error) {
+	updates := make([]RetentionUpdate, 0, len(desired))
+	var failed int
+	for _, name := range sortedKeys(desired) {
+		update := RetentionUpdate{
+			Name:    name,
+			Desired: desired[name],
+		}
+		update.Namespace, update.Err = c.SetNamespaceRetention(ctx, name, update.Desired)
+		if update.Err != nil {
+			failed++
+		} else if !update.Applied() {
+			update.Err = fmt.Errorf("namespace %q has retention period %s after update, want %s", name, retentionString(update.Namespace.Retention), retentionString(update.Desired))
+			failed++
+		}
+		updates = append(updates, update)
+	}
+	if failed > 0 {
+		return updates, fmt.Errorf("failed to set retention period of %d of %d namespaces", failed, len(desired))
+	}
+	return updates, nil
+}
+
+func retentionString(retention time.Duration) string {
+	if retention == InfiniteRetention {
+		return "infinite"
+	}
+	return retention.String()
+}
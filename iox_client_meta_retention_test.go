@@ -0,0 +1,96 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func ExampleClient_ReconcileNamespaceRetention() {
+	config, _ := influxdbiox.ClientConfigFromAddressString("localhost:8082")
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+
+	updates, err := client.ReconcileNamespaceRetention(context.Background(), map[string]time.Duration{
+		"myorg_metrics": 30 * 24 * time.Hour,
+		"myorg_audit":   influxdbiox.InfiniteRetention,
+	})
+	for _, update := range updates {
+		if update.Err != nil {
+			fmt.Printf("%s: %s\n", update.Name, update.Err)
+		}
+	}
+	_ = err
+}
+
+func TestClient_SetNamespaceRetention(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddNamespace("mydb")
+	client := newTestClient(ctx, t, server.ClientConfig("mydb"))
+
+	namespace, err := client.SetNamespaceRetention(ctx, "mydb", 48*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "mydb", namespace.Name)
+	assert.NotZero(t, namespace.ID)
+	assert.Equal(t, 48*time.Hour, namespace.Retention)
+	retention, _ := server.NamespaceRetention("mydb")
+	assert.Equal(t, 48*time.Hour, retention)
+
+	namespace, err = client.SetNamespaceRetention(ctx, "mydb", influxdbiox.InfiniteRetention)
+	require.NoError(t, err)
+	assert.Equal(t, influxdbiox.InfiniteRetention, namespace.Retention)
+	retention, _ = server.NamespaceRetention("mydb")
+	assert.Equal(t, influxdbiox.InfiniteRetention, retention)
+
+	_, err = client.SetNamespaceRetention(ctx, "mydb", 90*time.Minute)
+	assert.EqualError(t, err, "retention period 1h30m0s must be a positive whole number of hours, or InfiniteRetention")
+	_, err = client.SetNamespaceRetention(ctx, "mydb", 0)
+	assert.Error(t, err)
+	_, err = client.SetNamespaceRetention(ctx, "missing", time.Hour)
+	assert.ErrorContains(t, err, "namespace missing not found")
+}
+
+func TestClient_ReconcileNamespaceRetention(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddNamespace("a")
+	server.AddNamespace("c")
+	client := newTestClient(ctx, t, server.ClientConfig(""))
+
+	updates, err := client.ReconcileNamespaceRetention(ctx, map[string]time.Duration{
+		"c": influxdbiox.InfiniteRetention,
+		"b": time.Hour,
+		"a": 24 * time.Hour,
+	})
+	assert.EqualError(t, err, "failed to set retention period of 1 of 3 namespaces")
+	require.Len(t, updates, 3)
+
+	assert.Equal(t, "a", updates[0].Name)
+	assert.True(t, updates[0].Applied())
+	assert.Equal(t, 24*time.Hour, updates[0].Namespace.Retention)
+
+	assert.Equal(t, "b", updates[1].Name)
+	assert.False(t, updates[1].Applied())
+	assert.Nil(t, updates[1].Namespace)
+	assert.ErrorContains(t, updates[1].Err, "namespace b not found")
+
+	assert.Equal(t, "c", updates[2].Name)
+	assert.True(t, updates[2].Applied())
+	assert.Equal(t, influxdbiox.InfiniteRetention, updates[2].Namespace.Retention)
+
+	retention, _ := server.NamespaceRetention("a")
+	assert.Equal(t, 24*time.Hour, retention)
+}
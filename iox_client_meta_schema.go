@@ -3,6 +3,7 @@ package influxdbiox
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	schema "github.com/influxdata/influxdb-iox-client-go/v2/internal/schema"
 )
@@ -50,9 +51,71 @@ func (c ColumnType) String() string {
 	}
 }
 
-// Return a map of column name to data types for the specified table in
+// ErrTableNotFound is returned when a requested table does not exist in a
 // namespace.
-func (c *Client) GetSchema(ctx context.Context, namespace string, table string) (map[string]ColumnType, error) {
+var ErrTableNotFound = errors.New("table not found")
+
+// NamespaceSchema is the schema of an IOx namespace.
+type NamespaceSchema struct {
+	// Namespace ID
+	ID int64
+	// Topic ID
+	TopicID int64
+	// Query pool ID
+	QueryPoolID int64
+	// Map of table name -> table schema
+	Tables map[string]*TableSchema
+}
+
+// TableSchema is the schema of a table in an IOx namespace.
+type TableSchema struct {
+	// Table ID
+	ID int64
+	// Map of column name -> column schema
+	Columns map[string]*ColumnSchema
+}
+
+// ColumnSchema is the schema of a column in an IOx table.
+type ColumnSchema struct {
+	// Column ID
+	ID int64
+	// Column data type
+	Type ColumnType
+}
+
+// TableNames returns the names of all tables in the namespace, in lexical
+// order.
+func (s *NamespaceSchema) TableNames() []string {
+	return sortedKeys(s.Tables)
+}
+
+// Table returns the schema of the named table, or an error wrapping
+// ErrTableNotFound.
+func (s *NamespaceSchema) Table(name string) (*TableSchema, error) {
+	table, ok := s.Tables[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTableNotFound, name)
+	}
+	return table, nil
+}
+
+// ColumnNames returns the names of all columns in the table, in lexical
+// order.
+func (t *TableSchema) ColumnNames() []string {
+	return sortedKeys(t.Columns)
+}
+
+// ColumnTypes returns a map of column name to data type.
+func (t *TableSchema) ColumnTypes() map[string]ColumnType {
+	columnTypes := make(map[string]ColumnType, len(t.Columns))
+	for name, column := range t.Columns {
+		columnTypes[name] = column.Type
+	}
+	return columnTypes
+}
+
+// GetNamespaceSchema fetches the schema of every table in namespace.
+func (c *Client) GetNamespaceSchema(ctx context.Context, namespace string) (*NamespaceSchema, error) {
 	client := schema.NewSchemaServiceClient(c.grpcClient)
 	resp, err := client.GetSchema(ctx, &schema.GetSchemaRequest{
 		Namespace: namespace,
@@ -61,32 +124,65 @@ func (c *Client) GetSchema(ctx context.Context, namespace string, table string)
 		return nil, err
 	}
 
-	// Extract the (possibly nil) map of column name -> data type for the
-	// requested table.
-	cols := resp.GetSchema().GetTables()[table].GetColumns()
-	if cols == nil {
-		return nil, errors.New("table not found")
+	protoNamespace := resp.GetSchema()
+	ret := &NamespaceSchema{
+		ID:          protoNamespace.GetId(),
+		TopicID:     protoNamespace.GetTopicId(),
+		QueryPoolID: protoNamespace.GetQueryPoolId(),
+		Tables:      make(map[string]*TableSchema, len(protoNamespace.GetTables())),
 	}
-
-	// Iterate over all the columns for table, mapping the proto data type to a
-	// package const.
-	ret := make(map[string]ColumnType, len(cols))
-	for colName, col := range cols {
-		// Attempt to map the proto type to the package const.
-		//
-		// This can fail if the server sends a data type identifier this client
-		// does not know - this would indicate a client/server version mismatch.
-		colType, err := mapProtoColumnType(col.GetColumnType())
-		if err != nil {
-			return nil, err
+	for tableName, protoTable := range protoNamespace.GetTables() {
+		table := &TableSchema{
+			ID:      protoTable.GetId(),
+			Columns: make(map[string]*ColumnSchema, len(protoTable.GetColumns())),
 		}
-
-		ret[colName] = colType
+		for colName, col := range protoTable.GetColumns() {
+			// Attempt to map the proto type to the package const.
+			//
+			// This can fail if the server sends a data type identifier this
+			// client does not know - this would indicate a client/server
+			// version mismatch.
+			colType, err := mapProtoColumnType(col.GetColumnType())
+			if err != nil {
+				return nil, err
+			}
+			table.Columns[colName] = &ColumnSchema{
+				ID:   col.GetId(),
+				Type: colType,
+			}
+		}
+		ret.Tables[tableName] = table
 	}
 
 	return ret, nil
 }
 
+// ListTables returns the names of all tables in namespace, in lexical order.
+func (c *Client) ListTables(ctx context.Context, namespace string) ([]string, error) {
+	namespaceSchema, err := c.GetNamespaceSchema(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return namespaceSchema.TableNames(), nil
+}
+
+// Return a map of column name to data types for the specified table in
+// namespace.
+//
+// If the table does not exist, the returned error wraps ErrTableNotFound.
+// To fetch the schema of several tables, use GetNamespaceSchema.
+func (c *Client) GetSchema(ctx context.Context, namespace string, table string) (map[string]ColumnType, error) {
+	namespaceSchema, err := c.GetNamespaceSchema(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	tableSchema, err := namespaceSchema.Table(table)
+	if err != nil {
+		return nil, err
+	}
+	return tableSchema.ColumnTypes(), nil
+}
+
 // Convert the given proto data type const into an exported typed const.
 func mapProtoColumnType(v schema.ColumnSchema_ColumnType) (ColumnType, error) {
 	switch v {
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -23,6 +25,58 @@ func ExampleClient_GetSchema() {
 	}
 }
 
+func ExampleClient_GetNamespaceSchema() {
+	config, _ := influxdbiox.ClientConfigFromAddressString("localhost:8082")
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+
+	namespaceSchema, _ := client.GetNamespaceSchema(context.Background(), "mydb")
+	for _, tableName := range namespaceSchema.TableNames() {
+		table := namespaceSchema.Tables[tableName]
+		fmt.Printf("%s (id %d):\n", tableName, table.ID)
+		for _, columnName := range table.ColumnNames() {
+			fmt.Printf("  %-15s: %s\n", columnName, table.Columns[columnName].Type)
+		}
+	}
+}
+
+func TestClient_GetNamespaceSchema(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddTable("mydb", "cpu", map[string]influxdbiox.ColumnType{
+		"time": influxdbiox.ColumnType_TIME,
+		"host": influxdbiox.ColumnType_TAG,
+	})
+	server.AddTable("mydb", "mem", map[string]influxdbiox.ColumnType{
+		"free": influxdbiox.ColumnType_U64,
+	})
+	client := newTestClient(ctx, t, server.ClientConfig("mydb"))
+
+	namespaceSchema, err := client.GetNamespaceSchema(ctx, "mydb")
+	require.NoError(t, err)
+	assert.NotZero(t, namespaceSchema.ID)
+	assert.Equal(t, []string{"cpu", "mem"}, namespaceSchema.TableNames())
+
+	cpu, err := namespaceSchema.Table("cpu")
+	require.NoError(t, err)
+	assert.NotZero(t, cpu.ID)
+	assert.Equal(t, []string{"host", "time"}, cpu.ColumnNames())
+	assert.Equal(t, influxdbiox.ColumnType_TAG, cpu.Columns["host"].Type)
+	assert.NotEqual(t, cpu.Columns["host"].ID, cpu.Columns["time"].ID)
+
+	_, err = namespaceSchema.Table("disk")
+	assert.ErrorIs(t, err, influxdbiox.ErrTableNotFound)
+	_, err = client.GetSchema(ctx, "mydb", "disk")
+	assert.ErrorIs(t, err, influxdbiox.ErrTableNotFound)
+	assert.EqualError(t, err, `table not found: "disk"`)
+
+	tables, err := client.ListTables(ctx, "mydb")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cpu", "mem"}, tables)
+}
+
 func TestClient_GetSchema(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	t.Cleanup(cancel)
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"strings"
+	"sync/atomic"
 
 	"github.com/apache/arrow/go/v10/arrow/flight"
 	"github.com/apache/arrow/go/v10/arrow/ipc"
@@ -17,7 +20,15 @@ import (
 // Handshake the InfluxDB/IOx service, possibly (re-)connecting to the gRPC
 // service in the process.
 func (c *Client) Handshake(ctx context.Context) error {
-	response, err := c.flightClient.Handshake(ctx)
+	if err := handshake(ctx, c.flightClient); err != nil {
+		c.telemetry.logger.DebugContext(ctx, "handshake failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+func handshake(ctx context.Context, flightClient flight.FlightServiceClient) error {
+	response, err := flightClient.Handshake(ctx)
 	if err != nil {
 		return err
 	}
@@ -41,16 +52,83 @@ func (c *Client) Handshake(ctx context.Context) error {
 type ticketReadInfo struct {
 	NamespaceName string `json:"namespace_name"`
 	SQLQuery      string `json:"sql_query"`
+	// QueryType is omitted for SQL queries, for compatibility with IOx
+	// versions that only support SQL.
+	QueryType string `json:"query_type,omitempty"`
+}
+
+// QueryType defines the query languages IOx can execute.
+type QueryType int
+
+const (
+	// QueryTypeSQL is a SQL query. This is the default.
+	QueryTypeSQL QueryType = iota
+	// QueryTypeInfluxQL is an InfluxQL query.
+	QueryTypeInfluxQL
+)
+
+func (t QueryType) String() string {
+	switch t {
+	case QueryTypeSQL:
+		return "sql"
+	case QueryTypeInfluxQL:
+		return "influxql"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseQueryType parses the names produced by QueryType.String.
+// The empty string is parsed as QueryTypeSQL.
+func ParseQueryType(s string) (QueryType, error) {
+	switch strings.ToLower(s) {
+	case "", "sql":
+		return QueryTypeSQL, nil
+	case "influxql":
+		return QueryTypeInfluxQL, nil
+	default:
+		return 0, fmt.Errorf("unknown query type %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t QueryType) MarshalText() ([]byte, error) {
+	if t != QueryTypeSQL && t != QueryTypeInfluxQL {
+		return nil, fmt.Errorf("unknown query type %d", int(t))
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *QueryType) UnmarshalText(text []byte) error {
+	queryType, err := ParseQueryType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = queryType
+	return nil
 }
 
 // PrepareQuery prepares a query request.
 //
 // If database is "" then the configured default is used.
+// The query language is ClientConfig.QueryType, which defaults to SQL;
+// use QueryRequest.WithQueryType to override it.
 func (c *Client) PrepareQuery(ctx context.Context, database, query string) (*QueryRequest, error) {
 	if database == "" {
 		database = c.config.Namespace
 	}
-	return newRequest(c, database, query), nil
+	return newRequest(c, database, query, c.config.QueryType), nil
+}
+
+// PrepareInfluxQLQuery prepares an InfluxQL query request.
+//
+// If database is "" then the configured default is used.
+func (c *Client) PrepareInfluxQLQuery(ctx context.Context, database, query string) (*QueryRequest, error) {
+	if database == "" {
+		database = c.config.Namespace
+	}
+	return newRequest(c, database, query, QueryTypeInfluxQL), nil
 }
 
 // QueryRequest represents a prepared query.
@@ -58,19 +136,38 @@ type QueryRequest struct {
 	client          *Client
 	database        string
 	query           string
+	queryType       QueryType
 	grpcCallOptions []grpc.CallOption
 	allocator       memory.Allocator
 }
 
-func newRequest(client *Client, database, query string) *QueryRequest {
+func newRequest(client *Client, database, query string, queryType QueryType) *QueryRequest {
 	return &QueryRequest{
 		client:    client,
 		database:  database,
 		query:     query,
+		queryType: queryType,
 		allocator: memory.DefaultAllocator,
 	}
 }
 
+// WithQueryType sets the language that the query is written in.
+func (r *QueryRequest) WithQueryType(queryType QueryType) *QueryRequest {
+	return &QueryRequest{
+		client:          r.client,
+		database:        r.database,
+		query:           r.query,
+		queryType:       queryType,
+		grpcCallOptions: r.grpcCallOptions,
+		allocator:       r.allocator,
+	}
+}
+
+// QueryType returns the language that the query is written in.
+func (r *QueryRequest) QueryType() QueryType {
+	return r.queryType
+}
+
 // WithCallOption adds a grpc.CallOption to be included when the gRPC service
 // is called.
 func (r *QueryRequest) WithCallOption(grpcCallOption grpc.CallOption) *QueryRequest {
@@ -78,6 +175,7 @@ func (r *QueryRequest) WithCallOption(grpcCallOption grpc.CallOption) *QueryRequ
 		client:          r.client,
 		database:        r.database,
 		query:           r.query,
+		queryType:       r.queryType,
 		grpcCallOptions: append(r.grpcCallOptions, grpcCallOption),
 		allocator:       r.allocator,
 	}
@@ -90,36 +188,123 @@ func (r *QueryRequest) WithAllocator(alloc memory.Allocator) *QueryRequest {
 		client:          r.client,
 		database:        r.database,
 		query:           r.query,
+		queryType:       r.queryType,
 		grpcCallOptions: r.grpcCallOptions,
 		allocator:       alloc,
 	}
 }
 
+// NumInput returns the number of arguments expected by the placeholders in
+// the query, or -1 if that number cannot be determined, for example because
+// positional and named placeholders are mixed.
+func (r *QueryRequest) NumInput() int {
+	return numPlaceholders(r.queryType, r.query)
+}
+
 // Query sends a query via the Flight RPC DoGet.
 //
-// The returned *flight.Reader must be released when the caller is done with it.
+// If ClientConfig.FlightSQL is set, the query is sent with the Arrow Flight SQL
+// protocol instead; arguments are then bound by the server to a prepared
+// statement, rather than encoded as literals by this client.
+//
+// Arguments are bound to placeholders in the query before it is sent.
+// Positional placeholders ($1, $2, ...) refer to args by position, and named
+// placeholders ($name) refer to args constructed with Named.
+// Each argument is encoded as a literal of the query language, inferred from
+// its Go type, or from the ColumnType given to Typed.
+//
+// If ClientConfig.RetryPolicy is set, the query is sent again after a
+// transient failure, until the first record batch is received.
+//
+// The returned *QueryReader must be released when the caller is done with it.
+// Releasing it before all records are read ends the query.
 //
 //	reader, err := request.Query(ctx)
 //	defer reader.Release()
 //	...
-func (r *QueryRequest) Query(ctx context.Context, args ...interface{}) (*flight.Reader, error) {
+func (r *QueryRequest) Query(ctx context.Context, args ...interface{}) (*QueryReader, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	ctx, telemetry := r.client.telemetry.startQuery(ctx, r.database, r.query, r.queryType)
+	var stream flight.DataStreamReader
+	var err error
+	if r.client.config.FlightSQL {
+		stream, err = r.queryFlightSQL(ctx, args)
+	} else {
+		stream, err = r.queryDoGet(ctx, args)
+	}
+	if err != nil {
+		telemetry.end(err)
+		cancel()
+		return nil, err
+	}
+	stream = telemetry.wrap(stream)
+	flightReader, err := flight.NewRecordReader(stream, ipc.WithAllocator(r.allocator))
+	if err != nil {
+		telemetry.end(err)
+		closeStream(stream)
+		cancel()
+		return nil, fmt.Errorf("failed to create Flight record reader: %w", err)
+	}
+	return &QueryReader{Reader: flightReader, stream: stream, cancel: cancel, refCount: 1}, nil
+}
+
+// QueryReader is the *flight.Reader of the results of a query, returned by
+// QueryRequest.Query. Releasing it ends the query, even if not all records
+// have been read.
+type QueryReader struct {
+	*flight.Reader
+	stream   flight.DataStreamReader
+	cancel   context.CancelFunc
+	refCount int64
+}
+
+// Retain increases the reference count of the reader.
+func (r *QueryReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+	r.Reader.Retain()
+}
+
+// Release decreases the reference count of the reader. When the count
+// reaches zero, the resources of the reader are released, and the query is
+// ended by canceling its context.
+func (r *QueryReader) Release() {
+	r.Reader.Release()
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		closeStream(r.stream)
+		r.cancel()
+	}
+}
+
+// closeStream closes stream if it is an io.Closer.
+func closeStream(stream flight.DataStreamReader) {
+	if closer, ok := stream.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// queryDoGet sends the query in an IOx ticket via the Flight RPC DoGet.
+func (r *QueryRequest) queryDoGet(ctx context.Context, args []interface{}) (flight.DataStreamReader, error) {
+	query := r.query
 	if len(args) > 0 {
-		return nil, errors.New("query arguments are not supported")
+		var err error
+		if query, err = bindQuery(r.queryType, query, args); err != nil {
+			return nil, fmt.Errorf("failed to bind query arguments: %w", err)
+		}
 	}
-	ticket, err := json.Marshal(ticketReadInfo{
+	readInfo := ticketReadInfo{
 		NamespaceName: r.database,
-		SQLQuery:      r.query,
-	})
+		SQLQuery:      query,
+	}
+	if r.queryType != QueryTypeSQL {
+		readInfo.QueryType = r.queryType.String()
+	}
+	ticket, err := json.Marshal(readInfo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal Arrow DoGet ticket: %w", err)
 	}
-	doGetClient, err := r.client.flightClient.DoGet(ctx, &flight.Ticket{Ticket: ticket}, r.grpcCallOptions...)
+	doGetClient, err := newRetryDoGetStream(ctx, r.client.flightClient, &flight.Ticket{Ticket: ticket}, r.client.config.RetryPolicy, r.client.telemetry.logger, r.grpcCallOptions)
 	if err != nil {
 		return nil, fmt.Errorf("arrow Flight DoGet request failed: %w", err)
 	}
-	flightReader, err := flight.NewRecordReader(doGetClient, ipc.WithAllocator(r.allocator))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Flight record reader: %w", err)
-	}
-	return flightReader, nil
+	return doGetClient, nil
 }
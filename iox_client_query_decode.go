@@ -0,0 +1,479 @@
+package influxdbiox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+)
+
+// QueryInto sends a query and decodes every result row into a value of type T,
+// which must be a struct.
+//
+// Columns are matched to struct fields by the field tag `iox:"name"`, or by
+// the case-insensitive field name if the field has no iox tag.
+// Fields tagged `iox:"-"` are ignored, as are columns without a matching field.
+// A NULL value can only be decoded into a pointer, interface or sql.Scanner
+// field.
+// Timestamps are decoded into time.Time, respecting the time unit and time
+// zone of the Arrow column type. Decimals are decoded into floating point
+// fields, or exactly into string fields.
+//
+//	type cpu struct {
+//		Time  time.Time `iox:"time"`
+//		Host  *string   `iox:"host"`
+//		Usage float64   `iox:"usage_user"`
+//	}
+//	rows, err := influxdbiox.QueryInto[cpu](ctx, request)
+func QueryInto[T any](ctx context.Context, request *QueryRequest, args ...interface{}) ([]T, error) {
+	iterator, err := QueryIter[T](ctx, request, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Release()
+
+	var rows []T
+	for iterator.Next() {
+		rows = append(rows, iterator.Row())
+	}
+	if err = iterator.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// QueryIter sends a query and returns an iterator that decodes one result row
+// at a time into a value of type T, which must be a struct.
+// Unlike QueryInto, the result is not held in memory all at once.
+//
+// See QueryInto for how rows are decoded.
+// The returned *RowIterator must be released when the caller is done with it.
+//
+//	iterator, err := influxdbiox.QueryIter[cpu](ctx, request)
+//	defer iterator.Release()
+//	for iterator.Next() {
+//		row := iterator.Row()
+//		...
+//	}
+//	err = iterator.Err()
+func QueryIter[T any](ctx context.Context, request *QueryRequest, args ...interface{}) (*RowIterator[T], error) {
+	reader, err := request.Query(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := NewRowIterator[T](reader)
+	reader.Release()
+	if err != nil {
+		return nil, err
+	}
+	return iterator, nil
+}
+
+// recordReader is satisfied by *QueryReader and array.RecordReader
+// implementations that report stream errors.
+type recordReader interface {
+	array.RecordReader
+	Err() error
+}
+
+// RowIterator decodes the rows of a stream of Arrow records into values of
+// type T. Construct one with QueryIter or NewRowIterator.
+type RowIterator[T any] struct {
+	reader  array.RecordReader
+	decoder *structDecoder
+	record  arrow.Record // current record
+	rowI    int          // next row index for current record
+	row     T
+	err     error
+}
+
+// NewRowIterator constructs a *RowIterator that decodes rows from reader,
+// which is typically the *QueryReader returned by QueryRequest.Query.
+// The iterator retains reader, and releases it when the iterator is released.
+func NewRowIterator[T any](reader array.RecordReader) (*RowIterator[T], error) {
+	decoder, err := newStructDecoder(reflect.TypeOf((*T)(nil)).Elem(), reader.Schema())
+	if err != nil {
+		return nil, err
+	}
+	reader.Retain()
+	return &RowIterator[T]{
+		reader:  reader,
+		decoder: decoder,
+	}, nil
+}
+
+// Next decodes the next row, returning false when there are no more rows or
+// an error occurred.
+func (it *RowIterator[T]) Next() bool {
+	if it.err != nil || it.reader == nil {
+		return false
+	}
+	for it.record == nil || it.rowI >= int(it.record.NumRows()) {
+		if !it.reader.Next() {
+			if r, ok := it.reader.(recordReader); ok {
+				it.err = r.Err()
+			}
+			it.record = nil
+			return false
+		}
+		it.record = it.reader.Record()
+		it.rowI = 0
+	}
+
+	var row T
+	if it.err = it.decoder.decode(reflect.ValueOf(&row).Elem(), it.record, it.rowI); it.err != nil {
+		return false
+	}
+	it.row = row
+	it.rowI++
+	return true
+}
+
+// Row returns the row decoded by the most recent call to Next.
+func (it *RowIterator[T]) Row() T {
+	return it.row
+}
+
+// Err returns the error, if any, that stopped iteration.
+func (it *RowIterator[T]) Err() error {
+	return it.err
+}
+
+// Release releases the underlying record reader.
+func (it *RowIterator[T]) Release() {
+	it.record = nil
+	if it.reader != nil {
+		it.reader.Release()
+		it.reader = nil
+	}
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// structDecoder decodes rows of records with a specific schema into structs
+// of a specific type.
+type structDecoder struct {
+	columns []decodedColumn
+}
+
+type decodedColumn struct {
+	column     int   // column index in the record
+	fieldIndex []int // field index path for reflect.Value.FieldByIndex
+	name       string
+}
+
+func newStructDecoder(structType reflect.Type, schema *arrow.Schema) (*structDecoder, error) {
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot decode rows into %s; must be a struct", structType)
+	}
+
+	fieldsByName := make(map[string][]int)
+	fieldsByFoldedName := make(map[string][]int)
+	for _, field := range reflect.VisibleFields(structType) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("iox"); ok {
+			if name := strings.Split(tag, ",")[0]; name == "-" {
+				continue
+			} else if name != "" {
+				fieldsByName[name] = field.Index
+				continue
+			}
+		}
+		fieldsByFoldedName[strings.ToLower(field.Name)] = field.Index
+	}
+
+	decoder := &structDecoder{}
+	for i, field := range schema.Fields() {
+		fieldIndex, found := fieldsByName[field.Name]
+		if !found {
+			fieldIndex, found = fieldsByFoldedName[strings.ToLower(field.Name)]
+		}
+		if found {
+			decoder.columns = append(decoder.columns, decodedColumn{column: i, fieldIndex: fieldIndex, name: field.Name})
+		}
+	}
+	return decoder, nil
+}
+
+func (d *structDecoder) decode(dest reflect.Value, record arrow.Record, row int) error {
+	for _, c := range d.columns {
+		value, err := ValueFromArrowColumn(record.Column(c.column), row)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", c.name, err)
+		}
+		if err = assignValue(dest.FieldByIndex(c.fieldIndex), value); err != nil {
+			return fmt.Errorf("column %q: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// ValueFromArrowColumn returns the Go value at the given row of an Arrow
+// column, or nil if the value is NULL.
+//
+// Integers are returned as int64 or uint64, floating point numbers as
+// float64, decimals as exact decimal strings such as "-1.50", strings as
+// string, binary as []byte, timestamps and dates as time.Time, durations and
+// times of day as time.Duration, and lists as []interface{} of these types.
+// Timestamps are presented in the time zone of their Arrow type, or in UTC if
+// it has none. Dictionary-encoded columns are unwrapped.
+func ValueFromArrowColumn(column arrow.Array, row int) (interface{}, error) {
+	return ValueFromArrowColumnInLocation(column, row, nil)
+}
+
+// ValueFromArrowColumnInLocation is like ValueFromArrowColumn, but presents
+// timestamps in location, unless it is nil.
+func ValueFromArrowColumnInLocation(column arrow.Array, row int, location *time.Location) (interface{}, error) {
+	if column.IsNull(row) {
+		return nil, nil
+	}
+	switch typedColumn := column.(type) {
+	case *array.Timestamp:
+		return timestampValue(typedColumn.Value(row), typedColumn.DataType().(*arrow.TimestampType), location)
+	case *array.Date32:
+		return typedColumn.Value(row).ToTime(), nil
+	case *array.Date64:
+		return typedColumn.Value(row).ToTime(), nil
+	case *array.Time32:
+		unit := typedColumn.DataType().(*arrow.Time32Type).Unit
+		return time.Duration(typedColumn.Value(row)) * unit.Multiplier(), nil
+	case *array.Time64:
+		unit := typedColumn.DataType().(*arrow.Time64Type).Unit
+		return time.Duration(typedColumn.Value(row)) * unit.Multiplier(), nil
+	case *array.Duration:
+		unit := typedColumn.DataType().(*arrow.DurationType).Unit
+		return time.Duration(typedColumn.Value(row)) * unit.Multiplier(), nil
+	case *array.Float64:
+		return typedColumn.Value(row), nil
+	case *array.Float32:
+		return float64(typedColumn.Value(row)), nil
+	case *array.Float16:
+		return float64(typedColumn.Value(row).Float32()), nil
+	case *array.Decimal128:
+		return decimalString(typedColumn.Value(row).BigInt(), typedColumn.DataType().(*arrow.Decimal128Type).Scale), nil
+	case *array.Decimal256:
+		return decimalString(typedColumn.Value(row).BigInt(), typedColumn.DataType().(*arrow.Decimal256Type).Scale), nil
+	case *array.Int64:
+		return typedColumn.Value(row), nil
+	case *array.Int32:
+		return int64(typedColumn.Value(row)), nil
+	case *array.Int16:
+		return int64(typedColumn.Value(row)), nil
+	case *array.Int8:
+		return int64(typedColumn.Value(row)), nil
+	case *array.Uint64:
+		return typedColumn.Value(row), nil
+	case *array.Uint32:
+		return uint64(typedColumn.Value(row)), nil
+	case *array.Uint16:
+		return uint64(typedColumn.Value(row)), nil
+	case *array.Uint8:
+		return uint64(typedColumn.Value(row)), nil
+	case *array.String:
+		return typedColumn.Value(row), nil
+	case *array.LargeString:
+		return typedColumn.Value(row), nil
+	case *array.Binary:
+		return typedColumn.Value(row), nil
+	case *array.LargeBinary:
+		return typedColumn.Value(row), nil
+	case *array.FixedSizeBinary:
+		return typedColumn.Value(row), nil
+	case *array.Boolean:
+		return typedColumn.Value(row), nil
+	case *array.Dictionary:
+		return ValueFromArrowColumnInLocation(typedColumn.Dictionary(), typedColumn.GetValueIndex(row), location)
+	case *array.FixedSizeList:
+		n := int(typedColumn.DataType().(*arrow.FixedSizeListType).Len())
+		start := (typedColumn.Offset() + row) * n
+		return listValues(typedColumn.ListValues(), start, start+n, location)
+	case array.ListLike:
+		start, end := typedColumn.ValueOffsets(row)
+		return listValues(typedColumn.ListValues(), int(start), int(end), location)
+	default:
+		return nil, fmt.Errorf("unsupported arrow type %q", column.DataType().Name())
+	}
+}
+
+// listValues returns the values of column from start to end, as the value
+// of a list.
+func listValues(column arrow.Array, start, end int, location *time.Location) ([]interface{}, error) {
+	values := make([]interface{}, 0, end-start)
+	for i := start; i < end; i++ {
+		value, err := ValueFromArrowColumnInLocation(column, i, location)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// decimalString formats the unscaled value of a decimal with the given scale,
+// with scale digits after the decimal point.
+func decimalString(unscaled *big.Int, scale int32) string {
+	if scale <= 0 {
+		return unscaled.Mul(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil)).String()
+	}
+	digits := new(big.Int).Abs(unscaled).String()
+	if n := int(scale) + 1 - len(digits); n > 0 {
+		digits = strings.Repeat("0", n) + digits
+	}
+	point := len(digits) - int(scale)
+	s := digits[:point] + "." + digits[point:]
+	if unscaled.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// timestampValue converts value, in the unit of dataType, to a time.Time in
+// location. If location is nil, the time zone of dataType is used, or UTC if
+// dataType has none.
+func timestampValue(value arrow.Timestamp, dataType *arrow.TimestampType, location *time.Location) (time.Time, error) {
+	if location == nil {
+		var err error
+		if location, err = dataType.GetZone(); err != nil {
+			return time.Time{}, err
+		}
+	}
+	var t time.Time
+	switch dataType.Unit {
+	case arrow.Second:
+		t = time.Unix(int64(value), 0)
+	case arrow.Millisecond:
+		t = time.UnixMilli(int64(value))
+	case arrow.Microsecond:
+		t = time.UnixMicro(int64(value))
+	default:
+		t = time.Unix(0, int64(value))
+	}
+	return t.In(location), nil
+}
+
+// assignValue assigns a value returned by ValueFromArrowColumn to dest,
+// converting between numeric types where no precision is lost.
+func assignValue(dest reflect.Value, value interface{}) error {
+	if dest.CanAddr() && dest.Addr().Type().Implements(scannerType) {
+		return dest.Addr().Interface().(sql.Scanner).Scan(value)
+	}
+
+	if value == nil {
+		switch dest.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		default:
+			return fmt.Errorf("cannot decode NULL into %s; use a pointer", dest.Type())
+		}
+	}
+
+	switch dest.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(dest.Type().Elem())
+		if err := assignValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		dest.Set(elem)
+		return nil
+	case reflect.Interface:
+		v := reflect.ValueOf(value)
+		if !v.Type().AssignableTo(dest.Type()) {
+			return fmt.Errorf("cannot decode %T into %s", value, dest.Type())
+		}
+		dest.Set(v)
+		return nil
+	}
+
+	switch v := value.(type) {
+	case int64:
+		switch dest.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if dest.OverflowInt(v) {
+				return fmt.Errorf("value %d overflows %s", v, dest.Type())
+			}
+			dest.SetInt(v)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if v < 0 || dest.OverflowUint(uint64(v)) {
+				return fmt.Errorf("value %d overflows %s", v, dest.Type())
+			}
+			dest.SetUint(uint64(v))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dest.SetFloat(float64(v))
+			return nil
+		}
+	case uint64:
+		switch dest.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if dest.OverflowUint(v) {
+				return fmt.Errorf("value %d overflows %s", v, dest.Type())
+			}
+			dest.SetUint(v)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v > math.MaxInt64 || dest.OverflowInt(int64(v)) {
+				return fmt.Errorf("value %d overflows %s", v, dest.Type())
+			}
+			dest.SetInt(int64(v))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dest.SetFloat(float64(v))
+			return nil
+		}
+	case float64:
+		switch dest.Kind() {
+		case reflect.Float32, reflect.Float64:
+			dest.SetFloat(v)
+			return nil
+		}
+	case bool:
+		if dest.Kind() == reflect.Bool {
+			dest.SetBool(v)
+			return nil
+		}
+	case string:
+		switch {
+		case dest.Kind() == reflect.String:
+			dest.SetString(v)
+			return nil
+		case dest.Type() == reflect.TypeOf([]byte(nil)):
+			dest.SetBytes([]byte(v))
+			return nil
+		case dest.Kind() == reflect.Float32 || dest.Kind() == reflect.Float64:
+			// Decimals are returned by ValueFromArrowColumn as strings.
+			f, err := strconv.ParseFloat(v, dest.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("cannot decode %q into %s", v, dest.Type())
+			}
+			dest.SetFloat(f)
+			return nil
+		}
+	case []byte:
+		switch {
+		case dest.Kind() == reflect.String:
+			dest.SetString(string(v))
+			return nil
+		case dest.Type() == reflect.TypeOf([]byte(nil)):
+			dest.SetBytes(append([]byte(nil), v...))
+			return nil
+		}
+	case time.Time:
+		if dest.Type() == reflect.TypeOf(time.Time{}) {
+			dest.Set(reflect.ValueOf(v))
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot decode %T into %s", value, dest.Type())
+}
@@ -0,0 +1,208 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/decimal128"
+	"github.com/apache/arrow/go/v10/arrow/decimal256"
+	"github.com/apache/arrow/go/v10/arrow/float16"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+func ExampleQueryInto() {
+	config, _ := influxdbiox.ClientConfigFromAddressString("localhost:8082")
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+
+	type row struct {
+		Time time.Time `iox:"time"`
+		Foo  *string   `iox:"foo"`
+		V    int64     `iox:"v"`
+	}
+
+	req, _ := client.PrepareQuery(context.Background(), "mydb", "select time, foo, v from t")
+	rows, _ := influxdbiox.QueryInto[row](context.Background(), req)
+	for _, r := range rows {
+		fmt.Println(r.Time, r.V)
+	}
+}
+
+type decodeTestRow struct {
+	Time    time.Time      `iox:"time"`
+	Host    *string        `iox:"host"`
+	Region  sql.NullString `iox:"region"`
+	Usage   float64
+	Count   int32       `iox:"n"`
+	Ignored string      `iox:"-"`
+	Any     interface{} `iox:"u"`
+	Price   float64     `iox:"price"`
+}
+
+func newDecodeTestReader(t *testing.T) array.RecordReader {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	t.Cleanup(func() { mem.AssertSize(t, 0) })
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "time", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "America/New_York"}},
+		{Name: "host", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}, Nullable: true},
+		{Name: "region", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "usage", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "n", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "u", Type: arrow.PrimitiveTypes.Uint64},
+		{Name: "Ignored", Type: arrow.BinaryTypes.String},
+		{Name: "unmatched", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "price", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}},
+	}, nil)
+
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1618444800000, 1618444801500}, nil)
+	require.NoError(t, b.Field(1).(*array.BinaryDictionaryBuilder).AppendString("a"))
+	b.Field(1).AppendNull()
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"", "west"}, []bool{false, true})
+	b.Field(3).(*array.Float64Builder).AppendValues([]float64{0.5, 1.5}, nil)
+	b.Field(4).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	b.Field(5).(*array.Uint64Builder).AppendValues([]uint64{3, 4}, nil)
+	b.Field(6).(*array.StringBuilder).AppendValues([]string{"x", "y"}, nil)
+	b.Field(7).(*array.Int64Builder).AppendValues([]int64{5, 6}, nil)
+	b.Field(8).(*array.Decimal128Builder).AppendValues([]decimal128.Num{decimal128.FromI64(-150), decimal128.FromI64(1999)}, nil)
+	record := b.NewRecord()
+	defer record.Release()
+
+	reader, err := array.NewRecordReader(schema, []arrow.Record{record})
+	require.NoError(t, err)
+	return reader
+}
+
+func TestRowIterator(t *testing.T) {
+	reader := newDecodeTestReader(t)
+	defer reader.Release()
+
+	iterator, err := influxdbiox.NewRowIterator[decodeTestRow](reader)
+	require.NoError(t, err)
+	defer iterator.Release()
+
+	var rows []decodeTestRow
+	for iterator.Next() {
+		rows = append(rows, iterator.Row())
+	}
+	require.NoError(t, iterator.Err())
+	require.Len(t, rows, 2)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	assert.True(t, time.Date(2021, time.April, 15, 0, 0, 0, 0, time.UTC).Equal(rows[0].Time))
+	assert.Equal(t, newYork, rows[0].Time.Location())
+	assert.True(t, time.Date(2021, time.April, 15, 0, 0, 1, 500000000, time.UTC).Equal(rows[1].Time))
+
+	if assert.NotNil(t, rows[0].Host) {
+		assert.Equal(t, "a", *rows[0].Host)
+	}
+	assert.Nil(t, rows[1].Host)
+	assert.Equal(t, sql.NullString{}, rows[0].Region)
+	assert.Equal(t, sql.NullString{String: "west", Valid: true}, rows[1].Region)
+	assert.Equal(t, 1.5, rows[1].Usage)
+	assert.Equal(t, int32(2), rows[1].Count)
+	assert.Equal(t, uint64(4), rows[1].Any)
+	assert.Equal(t, -1.5, rows[0].Price)
+	assert.Equal(t, 19.99, rows[1].Price)
+	assert.Empty(t, rows[1].Ignored)
+}
+
+func TestRowIterator_errors(t *testing.T) {
+	reader := newDecodeTestReader(t)
+	defer reader.Release()
+
+	_, err := influxdbiox.NewRowIterator[int](reader)
+	assert.EqualError(t, err, "cannot decode rows into int; must be a struct")
+
+	iterator, err := influxdbiox.NewRowIterator[struct {
+		Region string `iox:"region"`
+	}](reader)
+	require.NoError(t, err)
+	defer iterator.Release()
+	assert.False(t, iterator.Next())
+	assert.EqualError(t, iterator.Err(), `column "region": cannot decode NULL into string; use a pointer`)
+
+	reader = newDecodeTestReader(t)
+	defer reader.Release()
+	usageIterator, err := influxdbiox.NewRowIterator[struct {
+		Usage int64
+	}](reader)
+	require.NoError(t, err)
+	defer usageIterator.Release()
+	assert.False(t, usageIterator.Next())
+	assert.EqualError(t, usageIterator.Err(), `column "usage": cannot decode float64 into int64`)
+
+	reader = newDecodeTestReader(t)
+	defer reader.Release()
+	stringIterator, err := influxdbiox.NewRowIterator[struct {
+		Ignored float64
+	}](reader)
+	require.NoError(t, err)
+	defer stringIterator.Release()
+	assert.False(t, stringIterator.Next())
+	assert.EqualError(t, stringIterator.Err(), `column "Ignored": cannot decode "x" into float64`)
+}
+
+func TestValueFromArrowColumn(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Second}},
+		{Name: "dur", Type: &arrow.DurationType{Unit: arrow.Millisecond}},
+		{Name: "tod", Type: arrow.FixedWidthTypes.Time32s},
+		{Name: "f16", Type: arrow.FixedWidthTypes.Float16},
+		{Name: "fixed", Type: &arrow.FixedSizeBinaryType{ByteWidth: 2}},
+		{Name: "list", Type: arrow.ListOf(arrow.PrimitiveTypes.Int64)},
+		{Name: "dec", Type: &arrow.Decimal128Type{Precision: 38, Scale: 3}},
+		{Name: "dec256", Type: &arrow.Decimal256Type{Precision: 76, Scale: -2}},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.TimestampBuilder).Append(1618444800)
+	b.Field(1).(*array.DurationBuilder).Append(1500)
+	b.Field(2).(*array.Time32Builder).Append(3600)
+	b.Field(3).(*array.Float16Builder).Append(float16.New(0.5))
+	b.Field(4).(*array.FixedSizeBinaryBuilder).Append([]byte{0xbe, 0xef})
+	list := b.Field(5).(*array.ListBuilder)
+	list.Append(true)
+	list.ValueBuilder().(*array.Int64Builder).AppendValues([]int64{1, 2}, []bool{true, false})
+	b.Field(6).(*array.Decimal128Builder).Append(decimal128.FromI64(-5))
+	b.Field(7).(*array.Decimal256Builder).Append(decimal256.FromI64(12))
+	record := b.NewRecord()
+	defer record.Release()
+
+	values := make([]interface{}, record.NumCols())
+	for i, column := range record.Columns() {
+		var err error
+		values[i], err = influxdbiox.ValueFromArrowColumn(column, 0)
+		require.NoError(t, err, record.ColumnName(i))
+	}
+	assert.Equal(t, []interface{}{
+		time.Date(2021, time.April, 15, 0, 0, 0, 0, time.UTC),
+		1500 * time.Millisecond,
+		time.Hour,
+		0.5,
+		[]byte{0xbe, 0xef},
+		[]interface{}{int64(1), nil},
+		"-0.005",
+		"1200",
+	}, values)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	value, err := influxdbiox.ValueFromArrowColumnInLocation(record.Column(0), 0, tokyo)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2021, time.April, 15, 9, 0, 0, 0, tokyo), value)
+}
@@ -0,0 +1,455 @@
+package influxdbiox
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NamedArg is a query argument bound to a named placeholder such as $name.
+//
+// Construct one with Named.
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named constructs a NamedArg, which binds value to the placeholder $name.
+//
+//	reader, err := request.Query(ctx, influxdbiox.Named("host", "server01"))
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// TypedArg is a query argument that is encoded as a SQL literal of a specific
+// ColumnType, instead of the type inferred from its Go type.
+//
+// Construct one with Typed.
+type TypedArg struct {
+	Type  ColumnType
+	Value interface{}
+}
+
+// Typed constructs a TypedArg, which encodes value as a literal of columnType.
+//
+//	reader, err := request.Query(ctx, influxdbiox.Typed(influxdbiox.ColumnType_U64, 42))
+func Typed(columnType ColumnType, value interface{}) TypedArg {
+	return TypedArg{Type: columnType, Value: value}
+}
+
+// placeholder is a single $1 or $name occurrence in a query.
+type placeholder struct {
+	start, end int    // byte offsets of the placeholder within the query
+	ordinal    int    // 1-based position, or 0 if named
+	name       string // set if the placeholder is named
+}
+
+// parsePlaceholders finds every placeholder in query, skipping string
+// literals, quoted identifiers and comments.
+func parsePlaceholders(queryType QueryType, query string) ([]placeholder, error) {
+	var placeholders []placeholder
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			// Skip to the closing quote; a doubled quote is an escaped quote
+			// in SQL, and InfluxQL escapes with a backslash.
+			j := i + 1
+			for ; j < len(query); j++ {
+				if queryType == QueryTypeInfluxQL && query[j] == '\\' {
+					j++
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j >= len(query) {
+				return nil, fmt.Errorf("unterminated quoted string starting at offset %d", i)
+			}
+			i = j
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment starting at offset %d", i)
+			}
+			i += end + 3
+		case c == '$':
+			j := i + 1
+			for j < len(query) && isPlaceholderByte(query[j]) {
+				j++
+			}
+			if j == i+1 {
+				continue
+			}
+			p := placeholder{start: i, end: j}
+			if s := query[i+1 : j]; s[0] >= '0' && s[0] <= '9' {
+				ordinal, err := strconv.Atoi(s)
+				if err != nil || ordinal < 1 {
+					return nil, fmt.Errorf("invalid positional placeholder %q", query[i:j])
+				}
+				p.ordinal = ordinal
+			} else {
+				p.name = s
+			}
+			placeholders = append(placeholders, p)
+			i = j - 1
+		}
+	}
+	return placeholders, nil
+}
+
+func isPlaceholderByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// numPlaceholders returns the number of arguments the query expects, or -1
+// if the query cannot be parsed or mixes positional and named placeholders.
+func numPlaceholders(queryType QueryType, query string) int {
+	placeholders, err := parsePlaceholders(queryType, query)
+	if err != nil {
+		return -1
+	}
+	maxOrdinal := 0
+	names := make(map[string]struct{})
+	for _, p := range placeholders {
+		if p.name != "" {
+			names[p.name] = struct{}{}
+		} else if p.ordinal > maxOrdinal {
+			maxOrdinal = p.ordinal
+		}
+	}
+	if maxOrdinal > 0 && len(names) > 0 {
+		return -1
+	}
+	return maxOrdinal + len(names)
+}
+
+// bindQuery replaces every placeholder in query with the literal encoding of
+// the matching argument, in the language given by queryType.
+//
+// Positional placeholders ($1, $2, ...) refer to args by position.
+// Named placeholders ($name) refer to args of type NamedArg.
+// Every argument must be referenced by at least one placeholder.
+func bindQuery(queryType QueryType, query string, args []interface{}) (string, error) {
+	placeholders, err := parsePlaceholders(queryType, query)
+	if err != nil {
+		return "", err
+	}
+	if len(placeholders) == 0 {
+		if len(args) > 0 {
+			return "", fmt.Errorf("query has no placeholders but %d arguments were provided", len(args))
+		}
+		return query, nil
+	}
+
+	named := make(map[string]int)
+	for i, arg := range args {
+		if namedArg, ok := arg.(NamedArg); ok {
+			if namedArg.Name == "" {
+				return "", fmt.Errorf("argument %d has an empty name", i+1)
+			}
+			if _, found := named[namedArg.Name]; found {
+				return "", fmt.Errorf("argument name %q is used more than once", namedArg.Name)
+			}
+			named[namedArg.Name] = i
+		}
+	}
+
+	used := make([]bool, len(args))
+	var b strings.Builder
+	last := 0
+	for _, p := range placeholders {
+		var index int
+		if p.name != "" {
+			i, found := named[p.name]
+			if !found {
+				return "", fmt.Errorf("no argument provided for placeholder $%s", p.name)
+			}
+			index = i
+		} else {
+			if p.ordinal > len(args) {
+				return "", fmt.Errorf("no argument provided for placeholder $%d", p.ordinal)
+			}
+			index = p.ordinal - 1
+		}
+		literal, err := encodeArg(queryType, args[index])
+		if err != nil {
+			return "", fmt.Errorf("failed to encode argument for placeholder %s: %w", query[p.start:p.end], err)
+		}
+		used[index] = true
+		b.WriteString(query[last:p.start])
+		b.WriteString(literal)
+		last = p.end
+	}
+	b.WriteString(query[last:])
+
+	for i, u := range used {
+		if !u {
+			return "", fmt.Errorf("argument %d is not referenced by any placeholder", i+1)
+		}
+	}
+	return b.String(), nil
+}
+
+// encodeArg encodes a single query argument as a literal in the language
+// given by queryType.
+func encodeArg(queryType QueryType, arg interface{}) (string, error) {
+	var columnType ColumnType
+	switch a := arg.(type) {
+	case NamedArg:
+		return encodeArg(queryType, a.Value)
+	case TypedArg:
+		columnType, arg = a.Type, a.Value
+	default:
+		var err error
+		if columnType, err = inferColumnType(arg); err != nil {
+			return "", err
+		}
+	}
+	if queryType == QueryTypeInfluxQL {
+		return encodeInfluxQLLiteral(columnType, arg)
+	}
+	return encodeTypedLiteral(columnType, arg)
+}
+
+// inferColumnType maps the Go type of a query argument to a ColumnType.
+func inferColumnType(v interface{}) (ColumnType, error) {
+	switch v.(type) {
+	case nil:
+		return ColumnTypeUnknown, nil
+	case int, int8, int16, int32, int64:
+		return ColumnType_I64, nil
+	case uint, uint8, uint16, uint32, uint64:
+		return ColumnType_U64, nil
+	case float32, float64:
+		return ColumnType_F64, nil
+	case bool:
+		return ColumnType_BOOL, nil
+	case string, []byte:
+		return ColumnType_STRING, nil
+	case time.Time:
+		return ColumnType_TIME, nil
+	default:
+		return ColumnTypeUnknown, fmt.Errorf("unsupported argument type %T", v)
+	}
+}
+
+// encodeTypedLiteral encodes v as a SQL literal of the given ColumnType.
+// A nil value is always encoded as NULL.
+func encodeTypedLiteral(columnType ColumnType, v interface{}) (string, error) {
+	if v == nil {
+		return "NULL", nil
+	}
+	switch columnType {
+	case ColumnType_I64:
+		i, err := toInt64(v)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(i, 10), nil
+	case ColumnType_U64:
+		u, err := toUint64(v)
+		if err != nil {
+			return "", err
+		}
+		return "CAST(" + strconv.FormatUint(u, 10) + " AS BIGINT UNSIGNED)", nil
+	case ColumnType_F64:
+		f, err := toFloat64(v)
+		if err != nil {
+			return "", err
+		}
+		return encodeFloat64(f), nil
+	case ColumnType_BOOL:
+		b, ok := v.(bool)
+		if !ok {
+			return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+		return strconv.FormatBool(b), nil
+	case ColumnType_STRING, ColumnType_TAG:
+		switch s := v.(type) {
+		case string:
+			return quoteString(s), nil
+		case []byte:
+			if columnType == ColumnType_TAG {
+				return quoteString(string(s)), nil
+			}
+			return "X'" + hex.EncodeToString(s) + "'", nil
+		default:
+			return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+	case ColumnType_TIME:
+		switch t := v.(type) {
+		case time.Time:
+			return "CAST(" + quoteString(t.UTC().Format(time.RFC3339Nano)) + " AS TIMESTAMP)", nil
+		case int64:
+			return "CAST(" + strconv.FormatInt(t, 10) + " AS TIMESTAMP)", nil
+		default:
+			return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+	default:
+		return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+	}
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), nil
+	case int8:
+		return int64(i), nil
+	case int16:
+		return int64(i), nil
+	case int32:
+		return int64(i), nil
+	case int64:
+		return i, nil
+	case uint, uint8, uint16, uint32, uint64:
+		u, _ := toUint64(v)
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows %s", u, ColumnType_I64)
+		}
+		return int64(u), nil
+	default:
+		return 0, fmt.Errorf("cannot encode %T as %s", v, ColumnType_I64)
+	}
+}
+
+func toUint64(v interface{}) (uint64, error) {
+	switch u := v.(type) {
+	case uint:
+		return uint64(u), nil
+	case uint8:
+		return uint64(u), nil
+	case uint16:
+		return uint64(u), nil
+	case uint32:
+		return uint64(u), nil
+	case uint64:
+		return u, nil
+	case int, int8, int16, int32, int64:
+		i, _ := toInt64(v)
+		if i < 0 {
+			return 0, fmt.Errorf("value %d overflows %s", i, ColumnType_U64)
+		}
+		return uint64(i), nil
+	default:
+		return 0, fmt.Errorf("cannot encode %T as %s", v, ColumnType_U64)
+	}
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch f := v.(type) {
+	case float32:
+		return float64(f), nil
+	case float64:
+		return f, nil
+	case int, int8, int16, int32, int64:
+		i, _ := toInt64(v)
+		return float64(i), nil
+	case uint, uint8, uint16, uint32, uint64:
+		u, _ := toUint64(v)
+		return float64(u), nil
+	default:
+		return 0, fmt.Errorf("cannot encode %T as %s", v, ColumnType_F64)
+	}
+}
+
+func encodeFloat64(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "CAST('NaN' AS DOUBLE)"
+	case math.IsInf(f, 1):
+		return "CAST('Infinity' AS DOUBLE)"
+	case math.IsInf(f, -1):
+		return "CAST('-Infinity' AS DOUBLE)"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		// Without a decimal point, the literal would be parsed as an integer.
+		s += ".0"
+	}
+	return s
+}
+
+// quoteString quotes s as a SQL string literal, escaping single quotes.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// encodeInfluxQLLiteral encodes v as an InfluxQL literal of the given
+// ColumnType. InfluxQL has no NULL literal, so nil values are rejected.
+func encodeInfluxQLLiteral(columnType ColumnType, v interface{}) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("cannot encode NULL in InfluxQL")
+	}
+	switch columnType {
+	case ColumnType_I64:
+		i, err := toInt64(v)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(i, 10), nil
+	case ColumnType_U64:
+		u, err := toUint64(v)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(u, 10), nil
+	case ColumnType_F64:
+		f, err := toFloat64(v)
+		if err != nil {
+			return "", err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("cannot encode %v in InfluxQL", f)
+		}
+		return encodeFloat64(f), nil
+	case ColumnType_BOOL:
+		b, ok := v.(bool)
+		if !ok {
+			return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+		return strconv.FormatBool(b), nil
+	case ColumnType_STRING, ColumnType_TAG:
+		switch s := v.(type) {
+		case string:
+			return quoteInfluxQLString(s), nil
+		case []byte:
+			return quoteInfluxQLString(string(s)), nil
+		default:
+			return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+	case ColumnType_TIME:
+		switch t := v.(type) {
+		case time.Time:
+			return quoteInfluxQLString(t.UTC().Format(time.RFC3339Nano)), nil
+		case int64:
+			return strconv.FormatInt(t, 10), nil
+		default:
+			return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+		}
+	default:
+		return "", fmt.Errorf("cannot encode %T as %s", v, columnType)
+	}
+}
+
+var influxQLStringReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`)
+
+// quoteInfluxQLString quotes s as an InfluxQL string literal, escaping
+// backslashes, single quotes and newlines.
+func quoteInfluxQLString(s string) string {
+	return "'" + influxQLStringReplacer.Replace(s) + "'"
+}
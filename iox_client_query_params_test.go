@@ -0,0 +1,150 @@
+package influxdbiox
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		args        []interface{}
+		expectQuery string
+		expectError string
+	}{{
+		name:        "no args",
+		query:       "select * from t",
+		expectQuery: "select * from t",
+	}, {
+		name:        "positional",
+		query:       "select * from t where a = $1 and b = $2 and c = $1",
+		args:        []interface{}{int64(1), "x"},
+		expectQuery: "select * from t where a = 1 and b = 'x' and c = 1",
+	}, {
+		name:        "named",
+		query:       "select * from t where host = $host and v > $min_v",
+		args:        []interface{}{Named("min_v", 1.5), Named("host", "server01")},
+		expectQuery: "select * from t where host = 'server01' and v > 1.5",
+	}, {
+		name:        "string escaping",
+		query:       "select * from t where foo = $1",
+		args:        []interface{}{"bar'; drop table t; --"},
+		expectQuery: "select * from t where foo = 'bar''; drop table t; --'",
+	}, {
+		name:        "placeholders in literals, identifiers and comments are ignored",
+		query:       `select '$1', "$1", 'it''s $2' -- $3` + "\n" + `/* $4 */ from t where v = $1`,
+		args:        []interface{}{true},
+		expectQuery: `select '$1', "$1", 'it''s $2' -- $3` + "\n" + `/* $4 */ from t where v = true`,
+	}, {
+		name:        "null",
+		query:       "select $1",
+		args:        []interface{}{nil},
+		expectQuery: "select NULL",
+	}, {
+		name:        "uint64",
+		query:       "select $1",
+		args:        []interface{}{uint64(math.MaxUint64)},
+		expectQuery: "select CAST(18446744073709551615 AS BIGINT UNSIGNED)",
+	}, {
+		name:        "integral float",
+		query:       "select $1, $2",
+		args:        []interface{}{float64(2), math.NaN()},
+		expectQuery: "select 2.0, CAST('NaN' AS DOUBLE)",
+	}, {
+		name:        "timestamp",
+		query:       "select * from t where time > $1",
+		args:        []interface{}{time.Date(2021, time.April, 15, 1, 2, 3, 4, time.FixedZone("x", 3600))},
+		expectQuery: "select * from t where time > CAST('2021-04-15T00:02:03.000000004Z' AS TIMESTAMP)",
+	}, {
+		name:        "typed",
+		query:       "select $1, $2, $3",
+		args:        []interface{}{Typed(ColumnType_U64, 7), Typed(ColumnType_F64, 7), Typed(ColumnType_TAG, []byte("a"))},
+		expectQuery: "select CAST(7 AS BIGINT UNSIGNED), 7.0, 'a'",
+	}, {
+		name:        "bytes",
+		query:       "select $1",
+		args:        []interface{}{[]byte{0xde, 0xad}},
+		expectQuery: "select X'dead'",
+	}, {
+		name:        "missing positional",
+		query:       "select $2",
+		args:        []interface{}{1},
+		expectError: "no argument provided for placeholder $2",
+	}, {
+		name:        "missing named",
+		query:       "select $a",
+		args:        []interface{}{Named("b", 1)},
+		expectError: "no argument provided for placeholder $a",
+	}, {
+		name:        "unreferenced",
+		query:       "select $1",
+		args:        []interface{}{1, 2},
+		expectError: "argument 2 is not referenced by any placeholder",
+	}, {
+		name:        "no placeholders",
+		query:       "select 1",
+		args:        []interface{}{1},
+		expectError: "query has no placeholders but 1 arguments were provided",
+	}, {
+		name:        "unsupported type",
+		query:       "select $1",
+		args:        []interface{}{struct{}{}},
+		expectError: "unsupported argument type struct {}",
+	}, {
+		name:        "type mismatch",
+		query:       "select $1",
+		args:        []interface{}{Typed(ColumnType_BOOL, "true")},
+		expectError: "cannot encode string as bool",
+	}, {
+		name:        "negative uint64",
+		query:       "select $1",
+		args:        []interface{}{Typed(ColumnType_U64, -1)},
+		expectError: "value -1 overflows uint64",
+	}, {
+		name:        "unterminated string",
+		query:       "select '$1",
+		args:        []interface{}{1},
+		expectError: "unterminated quoted string",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotQuery, gotErr := bindQuery(QueryTypeSQL, test.query, test.args)
+			if test.expectError != "" {
+				assert.ErrorContains(t, gotErr, test.expectError)
+			} else {
+				require.NoError(t, gotErr)
+				assert.Equal(t, test.expectQuery, gotQuery)
+			}
+		})
+	}
+}
+
+func TestNumPlaceholders(t *testing.T) {
+	assert.Equal(t, 0, numPlaceholders(QueryTypeSQL, "select 1"))
+	assert.Equal(t, 3, numPlaceholders(QueryTypeSQL, "select $3, $1, $1"))
+	assert.Equal(t, 2, numPlaceholders(QueryTypeSQL, "select $a, $b, $a"))
+	assert.Equal(t, 0, numPlaceholders(QueryTypeSQL, "select '$1'"))
+	assert.Equal(t, -1, numPlaceholders(QueryTypeSQL, "select $1, $a"))
+	assert.Equal(t, -1, numPlaceholders(QueryTypeSQL, "select '$1"))
+}
+
+func TestBindQuery_InfluxQL(t *testing.T) {
+	got, err := bindQuery(QueryTypeInfluxQL,
+		`SELECT * FROM "cpu" WHERE "host" = $host AND usage > $usage AND time > $since AND note = 'it\'s $3'`,
+		[]interface{}{Named("host", `a'b\c`), Named("usage", 1.0), Named("since", time.Date(2021, time.April, 15, 0, 0, 0, 0, time.UTC))})
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "cpu" WHERE "host" = 'a\'b\\c' AND usage > 1.0 AND time > '2021-04-15T00:00:00Z' AND note = 'it\'s $3'`, got)
+
+	got, err = bindQuery(QueryTypeInfluxQL, "SELECT $1", []interface{}{uint64(math.MaxUint64)})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 18446744073709551615", got)
+
+	_, err = bindQuery(QueryTypeInfluxQL, "SELECT $1", []interface{}{nil})
+	assert.ErrorContains(t, err, "cannot encode NULL in InfluxQL")
+}
@@ -35,3 +35,15 @@ func ExampleClient_PrepareQuery() {
 		}
 	}
 }
+
+func ExampleClient_PrepareInfluxQLQuery() {
+	config, _ := influxdbiox.ClientConfigFromAddressString("localhost:8082")
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+
+	req, _ := client.PrepareInfluxQLQuery(context.Background(), "mydb", `SELECT count("v") FROM "t" WHERE "foo" = $foo`)
+	reader, _ := req.Query(context.Background(), influxdbiox.Named("foo", "bar"))
+	defer reader.Release()
+	for reader.Next() {
+		println(reader.Record().NumRows())
+	}
+}
@@ -0,0 +1,315 @@
+package influxdbiox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy defines how idempotent RPCs are retried after transient
+// failures. Zero values are replaced by defaults.
+//
+// The retried RPCs are GetSchema, UpdateNamespaceRetention, GetWriteInfo
+// (polled by WaitForReadable and friends), the Flight SQL GetFlightInfo, and
+// the Flight DoGet stream of a query. A DoGet stream is restarted only if it
+// has not yet delivered a record batch to the caller; once it has, errors are
+// returned by the *QueryReader.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first; default 4.
+	// A value of 1 disables retries.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Delay before the first retry, doubled for each subsequent retry;
+	// default 100ms
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty"`
+	// Maximum delay between retries; default 5s
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"`
+	// Fraction of each delay that is random: with jitter 0.2, a delay d is
+	// chosen from [0.8d, 1.2d]; default 0.2, at most 1.
+	// A negative value disables jitter.
+	Jitter float64 `json:"jitter,omitempty"`
+	// gRPC status codes that are retried; default Unavailable.
+	// Serialized to JSON as names, such as "UNAVAILABLE".
+	RetryableCodes []codes.Code `json:"retryable_codes,omitempty"`
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 4
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 5 * time.Second
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	} else if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if len(p.RetryableCodes) == 0 {
+		p.RetryableCodes = []codes.Code{codes.Unavailable}
+	}
+	return p
+}
+
+// validate returns a description of each invalid field.
+func (p *RetryPolicy) validate() []string {
+	var problems []string
+	if p.Jitter > 1 {
+		problems = append(problems, fmt.Sprintf("retry policy jitter %g must be at most 1", p.Jitter))
+	}
+	for _, code := range p.RetryableCodes {
+		if code == codes.OK {
+			problems = append(problems, "retry policy retryable codes must not include OK")
+		}
+	}
+	return problems
+}
+
+// MarshalJSON encodes durations as strings, and codes as names.
+func (p RetryPolicy) MarshalJSON() ([]byte, error) {
+	type plainRetryPolicy RetryPolicy
+	aux := struct {
+		plainRetryPolicy
+		InitialBackoff string   `json:"initial_backoff,omitempty"`
+		MaxBackoff     string   `json:"max_backoff,omitempty"`
+		RetryableCodes []string `json:"retryable_codes,omitempty"`
+	}{plainRetryPolicy: plainRetryPolicy(p)}
+	if p.InitialBackoff != 0 {
+		aux.InitialBackoff = p.InitialBackoff.String()
+	}
+	if p.MaxBackoff != 0 {
+		aux.MaxBackoff = p.MaxBackoff.String()
+	}
+	for _, code := range p.RetryableCodes {
+		aux.RetryableCodes = append(aux.RetryableCodes, codeName(code))
+	}
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON decodes durations from strings, such as "100ms", or from
+// numbers of nanoseconds, and codes from names or numbers.
+func (p *RetryPolicy) UnmarshalJSON(b []byte) error {
+	type plainRetryPolicy RetryPolicy
+	aux := struct {
+		*plainRetryPolicy
+		InitialBackoff json.RawMessage `json:"initial_backoff,omitempty"`
+		MaxBackoff     json.RawMessage `json:"max_backoff,omitempty"`
+	}{plainRetryPolicy: (*plainRetryPolicy)(p)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	var err error
+	if p.InitialBackoff, err = unmarshalDuration(aux.InitialBackoff); err != nil {
+		return fmt.Errorf("invalid initial_backoff: %w", err)
+	}
+	if p.MaxBackoff, err = unmarshalDuration(aux.MaxBackoff); err != nil {
+		return fmt.Errorf("invalid max_backoff: %w", err)
+	}
+	return nil
+}
+
+// codeName returns the name of code that codes.Code.UnmarshalJSON accepts,
+// such as "DEADLINE_EXCEEDED", or the number of an unknown code.
+func codeName(code codes.Code) string {
+	if code >= codes.Code(17) {
+		return strconv.FormatUint(uint64(code), 10)
+	}
+	var name strings.Builder
+	s := code.String()
+	for i, r := range s {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(rune(s[i-1])) {
+			name.WriteByte('_')
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+// retryer retries one operation, the gRPC method named by method, according
+// to a RetryPolicy.
+type retryer struct {
+	policy  RetryPolicy
+	logger  Logger
+	method  string
+	attempt int
+	backoff time.Duration
+}
+
+func newRetryer(policy *RetryPolicy, logger Logger, method string) *retryer {
+	r := &retryer{logger: logger, method: method}
+	if policy == nil {
+		r.policy = RetryPolicy{MaxAttempts: 1}
+	} else {
+		r.policy = policy.withDefaults()
+	}
+	return r
+}
+
+// retry reports whether the operation should be attempted again after err,
+// after sleeping for the backoff. It returns false if err is not retryable,
+// the attempts are exhausted, or ctx is done while sleeping.
+func (r *retryer) retry(ctx context.Context, err error) bool {
+	r.attempt++
+	if r.attempt >= r.policy.MaxAttempts || !r.retryable(err) {
+		return false
+	}
+
+	if r.backoff == 0 {
+		r.backoff = r.policy.InitialBackoff
+	} else if r.backoff *= 2; r.backoff > r.policy.MaxBackoff {
+		r.backoff = r.policy.MaxBackoff
+	}
+	delay := r.backoff
+	if r.policy.Jitter > 0 {
+		delay += time.Duration(r.policy.Jitter * float64(delay) * (2*rand.Float64() - 1))
+	}
+	r.logger.DebugContext(ctx, "retrying request",
+		"method", r.method,
+		"attempt", r.attempt+1,
+		"delay", delay,
+		"error", err)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (r *retryer) retryable(err error) bool {
+	code := status.Code(err)
+	for _, retryableCode := range r.policy.RetryableCodes {
+		if code == retryableCode {
+			return true
+		}
+	}
+	return false
+}
+
+// retryUnaryInterceptor retries unary RPCs. Every unary RPC made by Client
+// is idempotent.
+func retryUnaryInterceptor(policy *RetryPolicy, logger Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		r := newRetryer(policy, logger, method)
+		for {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || !r.retry(ctx, err) {
+				return err
+			}
+		}
+	}
+}
+
+// retryDoGetStream is a DoGet stream that is restarted after a retryable
+// error, if no record batch has been received from it.
+//
+// Every DoGet stream begins with a schema message; when a stream is
+// restarted after its schema message was received, the schema message of the
+// new stream is skipped.
+type retryDoGetStream struct {
+	ctx             context.Context
+	flightClient    flight.FlightServiceClient
+	ticket          *flight.Ticket
+	grpcCallOptions []grpc.CallOption
+	retryer         *retryer
+
+	current  flight.FlightService_DoGetClient
+	cancel   context.CancelFunc
+	received int   // number of messages returned by Recv
+	skip     int   // number of messages of current to skip
+	err      error // terminal error, including io.EOF
+}
+
+func newRetryDoGetStream(ctx context.Context, flightClient flight.FlightServiceClient, ticket *flight.Ticket, policy *RetryPolicy, logger Logger, grpcCallOptions []grpc.CallOption) (*retryDoGetStream, error) {
+	s := &retryDoGetStream{
+		ctx:             ctx,
+		flightClient:    flightClient,
+		ticket:          ticket,
+		grpcCallOptions: grpcCallOptions,
+		retryer:         newRetryer(policy, logger, "DoGet"),
+	}
+	for {
+		err := s.open()
+		if err == nil {
+			return s, nil
+		}
+		if !s.retryer.retry(ctx, err) {
+			return nil, err
+		}
+	}
+}
+
+func (s *retryDoGetStream) open() error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	current, err := s.flightClient.DoGet(ctx, s.ticket, s.grpcCallOptions...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.current = current
+	s.cancel = cancel
+	s.skip = s.received
+	return nil
+}
+
+func (s *retryDoGetStream) Recv() (*flight.FlightData, error) {
+	for s.err == nil {
+		if s.current == nil {
+			if err := s.open(); err != nil {
+				if !s.retryer.retry(s.ctx, err) {
+					s.err = err
+				}
+				continue
+			}
+		}
+
+		data, err := s.current.Recv()
+		if err == nil {
+			if s.skip > 0 {
+				s.skip--
+				continue
+			}
+			s.received++
+			return data, nil
+		}
+
+		s.cancel()
+		s.current = nil
+		if err == io.EOF || s.received > 1 || !s.retryer.retry(s.ctx, err) {
+			s.err = err
+		}
+	}
+	return nil, s.err
+}
+
+// Close cancels the current DoGet stream, for a reader that is released
+// before the end of the stream.
+func (s *retryDoGetStream) Close() error {
+	if s.current != nil {
+		s.cancel()
+		s.current = nil
+	}
+	if s.err == nil {
+		s.err = errDoGetStreamClosed
+	}
+	return nil
+}
+
+var errDoGetStreamClosed = errors.New("DoGet stream closed")
@@ -0,0 +1,214 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func ExampleRetryPolicy() {
+	config := &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "mydb",
+		// Defaults: 4 attempts, 100ms initial backoff, retry Unavailable.
+		RetryPolicy: &influxdbiox.RetryPolicy{},
+	}
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+	_, _ = client.ListTables(context.Background(), "mydb")
+}
+
+func newRetryTestRecord(values ...int64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	b := array.NewInt64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.AppendValues(values, nil)
+	column := b.NewArray()
+	defer column.Release()
+	return array.NewRecord(schema, []arrow.Array{column}, int64(len(values)))
+}
+
+func newRetryTestClient(ctx context.Context, t *testing.T, server *ioxtest.Server, policy *influxdbiox.RetryPolicy) *influxdbiox.Client {
+	config := server.ClientConfig("mydb")
+	config.RetryPolicy = policy
+	return newTestClient(ctx, t, config)
+}
+
+// readRetryTestQuery returns the values read, and the error that ended the
+// query, if any.
+func readRetryTestQuery(ctx context.Context, t *testing.T, client *influxdbiox.Client) ([]int64, error) {
+	request, err := client.PrepareQuery(ctx, "", "select v from t")
+	require.NoError(t, err)
+	reader, err := request.Query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+	var values []int64
+	for reader.Next() {
+		values = append(values, reader.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	return values, reader.Err()
+}
+
+func TestQueryRequest_Query_retry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	record1, record2 := newRetryTestRecord(1, 2), newRetryTestRecord(3)
+	defer record1.Release()
+	defer record2.Release()
+	server.HandleQuery("select v from t", record1, record2)
+	unavailable := status.Error(codes.Unavailable, "querier restarting")
+
+	client := newRetryTestClient(ctx, t, server, &influxdbiox.RetryPolicy{InitialBackoff: time.Millisecond})
+
+	// Failures before the first message are retried.
+	server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: unavailable, Count: 2})
+	values, err := readRetryTestQuery(ctx, t, client)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, values)
+	assert.Len(t, server.Queries(), 1)
+
+	// A failure after the schema, but before the first record batch, is
+	// retried, and the schema of the new stream is skipped.
+	server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: unavailable, Count: 1, AfterMessages: 1})
+	values, err = readRetryTestQuery(ctx, t, client)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, values)
+	assert.Len(t, server.Queries(), 3)
+
+	// A failure after the first record batch is not retried.
+	server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: unavailable, Count: 1, AfterMessages: 2})
+	values, err = readRetryTestQuery(ctx, t, client)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, []int64{1, 2}, values)
+	assert.Len(t, server.Queries(), 4)
+
+	// Attempts are limited.
+	server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: unavailable, Count: 4})
+	_, err = readRetryTestQuery(ctx, t, client)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Len(t, server.Queries(), 4)
+
+	// Other codes are not retried.
+	server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: status.Error(codes.Internal, "boom"), Count: 1})
+	_, err = readRetryTestQuery(ctx, t, client)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// Without a retry policy, nothing is retried.
+	client = newRetryTestClient(ctx, t, server, nil)
+	server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: unavailable, Count: 1})
+	_, err = readRetryTestQuery(ctx, t, client)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	values, err = readRetryTestQuery(ctx, t, client)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, values)
+}
+
+func TestQueryRequest_Query_release(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	record1, record2 := newRetryTestRecord(1, 2), newRetryTestRecord(3)
+	defer record1.Release()
+	defer record2.Release()
+	server.HandleQuery("select v from t", record1, record2)
+
+	var doGetCtx context.Context
+	config := server.ClientConfig("mydb")
+	config.RetryPolicy = &influxdbiox.RetryPolicy{}
+	config.DialOptions = append(config.DialOptions, grpc.WithStreamInterceptor(
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			doGetCtx = ctx
+			return streamer(ctx, desc, cc, method, opts...)
+		}))
+	client := newTestClient(ctx, t, config)
+
+	// Releasing the reader before the end of the stream cancels the DoGet
+	// request.
+	request, err := client.PrepareQuery(ctx, "", "select v from t")
+	require.NoError(t, err)
+	reader, err := request.Query(ctx)
+	require.NoError(t, err)
+	require.True(t, reader.Next())
+	require.NotNil(t, doGetCtx)
+	assert.NoError(t, doGetCtx.Err())
+	reader.Release()
+	assert.Equal(t, context.Canceled, doGetCtx.Err())
+}
+
+func TestClient_GetSchema_retry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddTable("mydb", "t", map[string]influxdbiox.ColumnType{"v": influxdbiox.ColumnType_I64})
+
+	client := newRetryTestClient(ctx, t, server, &influxdbiox.RetryPolicy{
+		InitialBackoff: time.Millisecond,
+		RetryableCodes: []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+	})
+	server.InjectFailure(ioxtest.Failure{Method: "GetSchema", Err: status.Error(codes.Unavailable, "restarting"), Count: 1})
+	server.InjectFailure(ioxtest.Failure{Method: "GetSchema", Err: status.Error(codes.ResourceExhausted, "busy"), Count: 1})
+	tables, err := client.ListTables(ctx, "mydb")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"t"}, tables)
+
+	_, err = client.ListTables(ctx, "platanos")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClient_WaitForReadable_retry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetWriteInfo("token", []ioxtest.ShardStatus{ioxtest.ShardStatusReadable})
+	server.InjectFailure(ioxtest.Failure{Method: "GetWriteInfo", Err: status.Error(codes.Unavailable, "restarting"), Count: 2})
+
+	client := newRetryTestClient(ctx, t, server, &influxdbiox.RetryPolicy{InitialBackoff: time.Millisecond})
+	require.NoError(t, client.WaitForReadable(ctx, "token"))
+}
+
+func TestRetryPolicy_JSON(t *testing.T) {
+	config, err := influxdbiox.ClientConfigFromJSONString(`{
+  "address": "localhost:8082",
+  "retry_policy": {"max_attempts": 3, "initial_backoff": "10ms", "max_backoff": 1000000000, "retryable_codes": ["UNAVAILABLE", 8]}
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, &influxdbiox.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		RetryableCodes: []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+	}, config.RetryPolicy)
+
+	b, err := json.Marshal(config.RetryPolicy)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"max_attempts":3,"initial_backoff":"10ms","max_backoff":"1s","retryable_codes":["UNAVAILABLE","RESOURCE_EXHAUSTED"]}`, string(b))
+
+	_, err = influxdbiox.ClientConfigFromJSONString(`{"address": "localhost:8082", "retry_policy": {"retryable_codes": ["SOMETIMES"]}}`)
+	assert.ErrorContains(t, err, `invalid code: "\"SOMETIMES\""`)
+
+	config.RetryPolicy.Jitter = 2
+	assert.EqualError(t, config.Validate(), "invalid client config: retry policy jitter 2 must be at most 1")
+}
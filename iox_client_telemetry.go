@@ -0,0 +1,398 @@
+package influxdbiox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	flatbuffers "github.com/google/flatbuffers/go"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// instrumentationName names the tracer and meter of every Client.
+const instrumentationName = "github.com/influxdata/influxdb-iox-client-go/v2"
+
+// Attributes of the spans and metrics recorded with ClientConfig.TracerProvider
+// and ClientConfig.MeterProvider.
+const (
+	attributeNamespace     = attribute.Key("iox.namespace")
+	attributeQueryType     = attribute.Key("iox.query.type")
+	attributeQueryHash     = attribute.Key("iox.query.hash")
+	attributeQueryRows     = attribute.Key("iox.query.rows")
+	attributeQueryBatches  = attribute.Key("iox.query.batches")
+	attributeQueryBytes    = attribute.Key("iox.query.bytes")
+	attributeWaitCondition = attribute.Key("iox.write_token.condition")
+	attributeStatusCode    = attribute.Key("rpc.grpc.status_code")
+	attributeRPCSystem     = attribute.Key("rpc.system")
+	attributeRPCService    = attribute.Key("rpc.service")
+	attributeRPCMethod     = attribute.Key("rpc.method")
+)
+
+// telemetry creates the spans, records the metrics, and logs the events of
+// a Client. Without ClientConfig.TracerProvider, ClientConfig.MeterProvider
+// and ClientConfig.Logger, it does nothing.
+type telemetry struct {
+	config *ClientConfig
+	logger Logger
+	tracer trace.Tracer
+	// Injects trace context into gRPC metadata; nil without a TracerProvider.
+	propagator propagation.TextMapPropagator
+
+	queryDuration instrument.Float64Histogram
+	queryRows     instrument.Int64Counter
+	queryBatches  instrument.Int64Counter
+	queryBytes    instrument.Int64Counter
+	waitDuration  instrument.Float64Histogram
+}
+
+func newTelemetry(dc *ClientConfig) (*telemetry, error) {
+	t := &telemetry{config: dc, logger: dc.logger()}
+	tracerProvider := dc.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = trace.NewNoopTracerProvider()
+	} else {
+		t.propagator = propagation.TraceContext{}
+	}
+	t.tracer = tracerProvider.Tracer(instrumentationName)
+
+	meterProvider := dc.MeterProvider
+	if meterProvider == nil {
+		meterProvider = metric.NewNoopMeterProvider()
+	}
+	meter := meterProvider.Meter(instrumentationName)
+	var err error
+	if t.queryDuration, err = meter.Float64Histogram("iox.client.query.duration",
+		instrument.WithUnit("s"),
+		instrument.WithDescription("Duration of queries, from the request to the end of the result")); err != nil {
+		return nil, err
+	}
+	if t.queryRows, err = meter.Int64Counter("iox.client.query.rows",
+		instrument.WithUnit("{row}"),
+		instrument.WithDescription("Rows received in query results")); err != nil {
+		return nil, err
+	}
+	if t.queryBatches, err = meter.Int64Counter("iox.client.query.batches",
+		instrument.WithUnit("{batch}"),
+		instrument.WithDescription("Record batches received in query results")); err != nil {
+		return nil, err
+	}
+	if t.queryBytes, err = meter.Int64Counter("iox.client.query.bytes",
+		instrument.WithUnit("By"),
+		instrument.WithDescription("Arrow IPC bytes received in query results")); err != nil {
+		return nil, err
+	}
+	if t.waitDuration, err = meter.Float64Histogram("iox.client.write_token.wait.duration",
+		instrument.WithUnit("s"),
+		instrument.WithDescription("Duration of waits for write tokens to reach a condition")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// endSpan records err, if any, in span, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryHash identifies the text of a query in spans, without recording
+// the text itself, which may contain sensitive literals.
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:8])
+}
+
+// queryTelemetry measures one query, from the request until its stream
+// ends.
+type queryTelemetry struct {
+	t          *telemetry
+	ctx        context.Context
+	span       trace.Span
+	start      time.Time
+	attributes []attribute.KeyValue
+
+	once    sync.Once
+	rows    int64
+	batches int64
+	bytes   int64
+}
+
+// startQuery starts the span of a query. The query text, before arguments
+// are bound, is recorded as a hash.
+func (t *telemetry) startQuery(ctx context.Context, namespace, query string, queryType QueryType) (context.Context, *queryTelemetry) {
+	q := &queryTelemetry{
+		t:     t,
+		start: time.Now(),
+		attributes: []attribute.KeyValue{
+			attributeNamespace.String(namespace),
+			attributeQueryType.String(queryType.String()),
+		},
+	}
+	ctx, q.span = t.tracer.Start(ctx, "influxdbiox.Query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(q.attributes...),
+		trace.WithAttributes(attributeQueryHash.String(queryHash(query))))
+	q.ctx = ctx
+	t.logger.DebugContext(ctx, "query started", append([]interface{}{
+		"namespace", namespace,
+		"query_type", queryType.String(),
+	}, t.config.queryLogArgs(query)...)...)
+	return ctx, q
+}
+
+// end ends the span and records the metrics of the query, once.
+func (q *queryTelemetry) end(err error) {
+	q.once.Do(func() {
+		q.span.SetAttributes(
+			attributeQueryRows.Int64(q.rows),
+			attributeQueryBatches.Int64(q.batches),
+			attributeQueryBytes.Int64(q.bytes))
+		endSpan(q.span, err)
+
+		attributes := append(q.attributes, attributeStatusCode.Int(int(status.Code(err))))
+		q.t.queryDuration.Record(q.ctx, time.Since(q.start).Seconds(), attributes...)
+		q.t.queryRows.Add(q.ctx, q.rows, attributes...)
+		q.t.queryBatches.Add(q.ctx, q.batches, attributes...)
+		q.t.queryBytes.Add(q.ctx, q.bytes, attributes...)
+
+		logAttrs := []interface{}{
+			"rows", q.rows,
+			"batches", q.batches,
+			"bytes", q.bytes,
+			"duration", time.Since(q.start),
+		}
+		if err != nil {
+			logAttrs = append(logAttrs, "error", err)
+		}
+		q.t.logger.DebugContext(q.ctx, "query finished", logAttrs...)
+	})
+}
+
+// wrap returns a stream that counts the data received from stream, and ends
+// the query when stream ends or is closed.
+func (q *queryTelemetry) wrap(stream flight.DataStreamReader) flight.DataStreamReader {
+	return &queryTelemetryStream{DataStreamReader: stream, q: q}
+}
+
+type queryTelemetryStream struct {
+	flight.DataStreamReader
+	q *queryTelemetry
+}
+
+func (s *queryTelemetryStream) Recv() (*flight.FlightData, error) {
+	data, err := s.DataStreamReader.Recv()
+	if err == io.EOF {
+		s.q.end(nil)
+	} else if err != nil {
+		s.q.end(err)
+	} else {
+		s.q.bytes += int64(len(data.DataHeader) + len(data.DataBody))
+		if rows, ok := recordBatchLength(data.DataHeader); ok {
+			s.q.batches++
+			s.q.rows += rows
+		}
+	}
+	return data, err
+}
+
+// Close ends the query, if the stream has not ended, and closes the wrapped
+// stream.
+func (s *queryTelemetryStream) Close() error {
+	s.q.end(nil)
+	if closer, ok := s.DataStreamReader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Arrow IPC Message and RecordBatch flatbuffer fields, from Message.fbs.
+const (
+	messageHeaderRecordBatch = 3
+	messageFieldHeaderType   = 6
+	messageFieldHeader       = 8
+	recordBatchFieldLength   = 4
+)
+
+// recordBatchLength returns the number of rows of an Arrow IPC record batch
+// message, from its flatbuffer header. It returns false for other messages,
+// and for malformed headers, which are left for the IPC reader to report.
+func recordBatchLength(header []byte) (length int64, ok bool) {
+	defer func() {
+		if recover() != nil {
+			length, ok = 0, false
+		}
+	}()
+	if len(header) < flatbuffers.SizeUOffsetT {
+		return 0, false
+	}
+	message := &flatbuffers.Table{Bytes: header, Pos: flatbuffers.GetUOffsetT(header)}
+	if message.GetByteSlot(messageFieldHeaderType, 0) != messageHeaderRecordBatch {
+		return 0, false
+	}
+	o := flatbuffers.UOffsetT(message.Offset(messageFieldHeader))
+	if o == 0 {
+		return 0, false
+	}
+	batch := &flatbuffers.Table{Bytes: header, Pos: message.Indirect(message.Pos + o)}
+	return batch.GetInt64Slot(recordBatchFieldLength, 0), true
+}
+
+// waitForToken measures a wait for a write token to reach condition, such
+// as "readable".
+func (t *telemetry) waitForToken(ctx context.Context, condition string, wait func(ctx context.Context) error) error {
+	start := time.Now()
+	ctx, span := t.tracer.Start(ctx, "influxdbiox.WaitForToken",
+		trace.WithAttributes(attributeWaitCondition.String(condition)))
+	err := wait(ctx)
+	endSpan(span, err)
+	t.waitDuration.Record(ctx, time.Since(start).Seconds(),
+		attributeWaitCondition.String(condition),
+		attributeStatusCode.Int(int(status.Code(err))))
+	return err
+}
+
+// untracedKey marks contexts of requests, such as health checks, that are
+// not traced.
+type untracedKey struct{}
+
+func withoutTracing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untracedKey{}, true)
+}
+
+// dialOptions returns interceptors that trace each gRPC request, and send
+// trace context with it, or nil without ClientConfig.TracerProvider.
+func (t *telemetry) dialOptions() []grpc.DialOption {
+	if t.propagator == nil {
+		return nil
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(t.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(t.streamInterceptor),
+	}
+}
+
+// startRPC starts the span of a gRPC request, and injects its context into
+// the outgoing metadata.
+func (t *telemetry) startRPC(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	service, method := name, ""
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		service, method = name[:i], name[i+1:]
+	}
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attributeRPCSystem.String("grpc"),
+			attributeRPCService.String(service),
+			attributeRPCMethod.String(method)))
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	t.propagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+func endRPCSpan(span trace.Span, err error) {
+	span.SetAttributes(attributeStatusCode.Int(int(status.Code(err))))
+	endSpan(span, err)
+}
+
+func (t *telemetry) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if ctx.Value(untracedKey{}) != nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	ctx, span := t.startRPC(ctx, method)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	endRPCSpan(span, err)
+	return err
+}
+
+func (t *telemetry) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if ctx.Value(untracedKey{}) != nil {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	ctx, span := t.startRPC(ctx, method)
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		endRPCSpan(span, err)
+		return nil, err
+	}
+	s := &tracedClientStream{ClientStream: stream, span: span, ended: make(chan struct{})}
+	// RecvMsg ends the span with the status of the stream, unless the caller
+	// cancels the stream before reading it to the end. The stream context is
+	// not watched instead, as gRPC cancels it before RecvMsg returns the
+	// status.
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.end(status.FromContextError(ctx.Err()).Err())
+		case <-s.ended:
+		}
+	}()
+	return s, nil
+}
+
+// tracedClientStream ends its span when the stream ends.
+type tracedClientStream struct {
+	grpc.ClientStream
+	span  trace.Span
+	once  sync.Once
+	ended chan struct{} // closed when the span ends
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.end(nil)
+	} else if err != nil {
+		s.end(err)
+	}
+	return err
+}
+
+func (s *tracedClientStream) end(err error) {
+	s.once.Do(func() {
+		endRPCSpan(s.span, err)
+		close(s.ended)
+	})
+}
+
+// metadataCarrier adapts gRPC metadata to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier(nil)
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,282 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func ExampleClient_telemetry() {
+	tracerProvider := sdktrace.NewTracerProvider()
+	defer func() { _ = tracerProvider.Shutdown(context.Background()) }()
+	meterProvider := sdkmetric.NewMeterProvider()
+	defer func() { _ = meterProvider.Shutdown(context.Background()) }()
+
+	config := &influxdbiox.ClientConfig{
+		Address:        "localhost:8082",
+		Namespace:      "mydb",
+		TracerProvider: tracerProvider,
+		MeterProvider:  meterProvider,
+	}
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+	request, _ := client.PrepareQuery(context.Background(), "", "select * from cpu")
+	reader, _ := request.Query(context.Background())
+	defer reader.Release()
+}
+
+// endedSpan returns the one span named name, failing the test otherwise.
+func endedSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	var found []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			found = append(found, span)
+		}
+	}
+	require.Len(t, found, 1, name)
+	return found[0]
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+// collectMetrics returns each data point by metric name and attributes.
+func collectMetrics(ctx context.Context, t *testing.T, reader sdkmetric.Reader) map[string]map[string]interface{} {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	metrics := make(map[string]map[string]interface{})
+	for _, sm := range rm.ScopeMetrics {
+		assert.Equal(t, "github.com/influxdata/influxdb-iox-client-go/v2", sm.Scope.Name)
+		for _, m := range sm.Metrics {
+			points := make(map[string]interface{})
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range data.DataPoints {
+					points[point.Attributes.Encoded(attribute.DefaultEncoder())] = point.Value
+				}
+			case metricdata.Histogram:
+				for _, point := range data.DataPoints {
+					points[point.Attributes.Encoded(attribute.DefaultEncoder())] = point.Count
+				}
+			}
+			metrics[m.Name] = points
+		}
+	}
+	return metrics
+}
+
+func TestClient_telemetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	record1, record2 := newRetryTestRecord(1, 2), newRetryTestRecord(3)
+	defer record1.Release()
+	defer record2.Release()
+	server.HandleQuery("select v from t", record1, record2)
+	server.SetWriteInfo("token", []ioxtest.ShardStatus{ioxtest.ShardStatusDurable}, []ioxtest.ShardStatus{ioxtest.ShardStatusReadable})
+
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	metricReader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(metricReader))
+
+	config := server.ClientConfig("mydb")
+	config.TracerProvider = tracerProvider
+	config.MeterProvider = meterProvider
+	client := newTestClient(ctx, t, config)
+
+	parentCtx, parent := tracerProvider.Tracer("test").Start(ctx, "parent")
+	values, err := readRetryTestQuery(parentCtx, t, client)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, values)
+	parent.End()
+
+	querySpan := endedSpan(t, recorder, "influxdbiox.Query")
+	assert.Equal(t, parent.SpanContext().SpanID(), querySpan.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindClient, querySpan.SpanKind())
+	assert.Equal(t, otelcodes.Unset, querySpan.Status().Code)
+	hash := sha256.Sum256([]byte("select v from t"))
+	attributes := spanAttributes(querySpan)
+	assert.Equal(t, "mydb", attributes["iox.namespace"].AsString())
+	assert.Equal(t, "sql", attributes["iox.query.type"].AsString())
+	assert.Equal(t, hex.EncodeToString(hash[:8]), attributes["iox.query.hash"].AsString())
+	assert.Equal(t, int64(3), attributes["iox.query.rows"].AsInt64())
+	assert.Equal(t, int64(2), attributes["iox.query.batches"].AsInt64())
+	assert.Greater(t, attributes["iox.query.bytes"].AsInt64(), int64(0))
+
+	// The DoGet span is a child of the query span, and its context is sent
+	// to the server.
+	doGetSpan := endedSpan(t, recorder, "arrow.flight.protocol.FlightService/DoGet")
+	assert.Equal(t, querySpan.SpanContext().SpanID(), doGetSpan.Parent().SpanID())
+	assert.Equal(t, "grpc", spanAttributes(doGetSpan)["rpc.system"].AsString())
+	assert.Equal(t, "DoGet", spanAttributes(doGetSpan)["rpc.method"].AsString())
+	queries := server.Queries()
+	require.Len(t, queries, 1)
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", doGetSpan.SpanContext().TraceID(), doGetSpan.SpanContext().SpanID()), queries[0].TraceParent)
+
+	require.NoError(t, client.WaitForReadable(ctx, "token"))
+	waitSpan := endedSpan(t, recorder, "influxdbiox.WaitForToken")
+	assert.Equal(t, "readable", spanAttributes(waitSpan)["iox.write_token.condition"].AsString())
+	var writeInfoSpans int
+	for _, span := range recorder.Ended() {
+		if span.Name() == "influxdata.iox.ingester.v1.WriteInfoService/GetWriteInfo" {
+			assert.Equal(t, waitSpan.SpanContext().SpanID(), span.Parent().SpanID())
+			writeInfoSpans++
+		}
+	}
+	assert.Equal(t, 2, writeInfoSpans)
+
+	// A failed query ends its span with the error.
+	request, err := client.PrepareQuery(ctx, "", "select nothing")
+	require.NoError(t, err)
+	_, err = request.Query(ctx)
+	require.Error(t, err)
+	var failedQuerySpan, failedDoGetSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Status().Code != otelcodes.Error {
+			continue
+		}
+		switch span.Name() {
+		case "influxdbiox.Query":
+			failedQuerySpan = span
+		case "arrow.flight.protocol.FlightService/DoGet":
+			failedDoGetSpan = span
+		}
+	}
+	require.NotNil(t, failedQuerySpan)
+	assert.NotEmpty(t, failedQuerySpan.Status().Description)
+	require.NotNil(t, failedDoGetSpan)
+	assert.Equal(t, int64(codes.InvalidArgument), spanAttributes(failedDoGetSpan)["rpc.grpc.status_code"].AsInt64())
+
+	metrics := collectMetrics(ctx, t, metricReader)
+	okAttributes := "iox.namespace=mydb,iox.query.type=sql,rpc.grpc.status_code=0"
+	failedAttributes := "iox.namespace=mydb,iox.query.type=sql,rpc.grpc.status_code=3"
+	assert.Equal(t, map[string]interface{}{okAttributes: uint64(1), failedAttributes: uint64(1)}, metrics["iox.client.query.duration"])
+	assert.Equal(t, map[string]interface{}{okAttributes: int64(3), failedAttributes: int64(0)}, metrics["iox.client.query.rows"])
+	assert.Equal(t, map[string]interface{}{okAttributes: int64(2), failedAttributes: int64(0)}, metrics["iox.client.query.batches"])
+	assert.Equal(t, map[string]interface{}{"iox.write_token.condition=readable,rpc.grpc.status_code=0": uint64(1)}, metrics["iox.client.write_token.wait.duration"])
+}
+
+func TestClient_telemetry_release(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	record1, record2 := newRetryTestRecord(1, 2), newRetryTestRecord(3)
+	defer record1.Release()
+	defer record2.Release()
+	server.HandleQuery("select v from t", record1, record2)
+
+	recorder := tracetest.NewSpanRecorder()
+	config := server.ClientConfig("mydb")
+	config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := newTestClient(ctx, t, config)
+
+	// A reader released before the end of the stream ends the query.
+	request, err := client.PrepareQuery(ctx, "", "select v from t")
+	require.NoError(t, err)
+	reader, err := request.Query(ctx)
+	require.NoError(t, err)
+	require.True(t, reader.Next())
+	assert.EqualValues(t, 2, reader.Record().NumRows())
+	for _, span := range recorder.Ended() {
+		assert.NotEqual(t, "influxdbiox.Query", span.Name())
+	}
+	reader.Release()
+
+	querySpan := endedSpan(t, recorder, "influxdbiox.Query")
+	assert.Equal(t, otelcodes.Unset, querySpan.Status().Code)
+	attributes := spanAttributes(querySpan)
+	assert.Equal(t, int64(2), attributes["iox.query.rows"].AsInt64())
+	assert.Equal(t, int64(1), attributes["iox.query.batches"].AsInt64())
+}
+
+func TestClient_telemetry_streamFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	record1, record2 := newRetryTestRecord(1, 2), newRetryTestRecord(3)
+	defer record1.Release()
+	defer record2.Release()
+	server.HandleQuery("select v from t", record1, record2)
+
+	recorder := tracetest.NewSpanRecorder()
+	config := server.ClientConfig("mydb")
+	config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := newTestClient(ctx, t, config)
+	request, err := client.PrepareQuery(ctx, "", "select v from t")
+	require.NoError(t, err)
+
+	// The stream fails after the schema and the first record batch.
+	const queries = 20
+	for i := 0; i < queries; i++ {
+		server.InjectFailure(ioxtest.Failure{Method: "DoGet", Err: status.Error(codes.Internal, "boom"), Count: 1, AfterMessages: 2})
+		reader, err := request.Query(ctx)
+		require.NoError(t, err)
+		require.True(t, reader.Next())
+		require.False(t, reader.Next())
+		require.Error(t, reader.Err())
+		reader.Release()
+	}
+
+	var doGetSpans int
+	for _, span := range recorder.Ended() {
+		if span.Name() == "arrow.flight.protocol.FlightService/DoGet" {
+			doGetSpans++
+			assert.Equal(t, otelcodes.Error, span.Status().Code)
+			assert.Equal(t, int64(codes.Internal), spanAttributes(span)["rpc.grpc.status_code"].AsInt64())
+		}
+	}
+	assert.Equal(t, queries, doGetSpans)
+}
+
+func TestClient_telemetry_disabled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	record := newRetryTestRecord(1)
+	defer record.Release()
+	server.HandleQuery("select v from t", record)
+
+	// Without a TracerProvider, no trace context is sent, even if the
+	// caller's context has a span.
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, parent := tracerProvider.Tracer("test").Start(ctx, "parent")
+	defer parent.End()
+	client := newRetryTestClient(ctx, t, server, nil)
+	_, err := readRetryTestQuery(ctx, t, client)
+	require.NoError(t, err)
+	require.Len(t, server.Queries(), 1)
+	assert.Empty(t, server.Queries()[0].TraceParent)
+	assert.Empty(t, recorder.Ended())
+}
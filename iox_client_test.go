@@ -45,6 +45,15 @@ func getTestGRPCPort() string {
 	return envOrDefault("INFLUXDB_IOX_GRPC_PORT", "8082")
 }
 
+// newTestClient constructs a client with config, which is closed when the
+// test ends.
+func newTestClient(ctx context.Context, t *testing.T, config *influxdbiox.ClientConfig) *influxdbiox.Client {
+	client, err := influxdbiox.NewClient(ctx, config)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
 // Initialises the IOx client with a randomly generated database name.
 //
 // Returns the client & per-client database name.
@@ -63,9 +72,7 @@ func openNewDatabase(ctx context.Context, t *testing.T) (*influxdbiox.Client, st
 		DialOptions: []grpc.DialOption{grpc.WithBlock()},
 	}
 
-	client, err := influxdbiox.NewClient(ctx, &config)
-	require.NoError(t, err)
-	t.Cleanup(func() { _ = client.Close() })
+	client := newTestClient(ctx, t, &config)
 	require.NoError(t, client.Handshake(ctx))
 
 	return client, databaseName
@@ -0,0 +1,269 @@
+package influxdbiox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// Point is a single line protocol point.
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	// Field values may be any integer type, float32, float64, bool, string
+	// or []byte.
+	Fields map[string]interface{}
+	Time   time.Time
+}
+
+// NewPoint constructs a *Point.
+func NewPoint(measurement string, tags map[string]string, fields map[string]interface{}, t time.Time) *Point {
+	return &Point{
+		Measurement: measurement,
+		Tags:        tags,
+		Fields:      fields,
+		Time:        t,
+	}
+}
+
+// WriteError is returned when the IOx HTTP write API responds with an
+// unsuccessful status code.
+type WriteError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("write failed with HTTP status %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the write may succeed if retried.
+func (e *WriteError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode/100 == 5
+}
+
+// Write writes line protocol to namespace via the IOx HTTP write API at
+// ClientConfig.WriteURL, with nanosecond precision and without compression.
+//
+// If namespace is "" then the configured default is used.
+// The returned write token can be passed to WaitForDurable, WaitForReadable
+// and WaitForPersisted. It is "" if the server did not return one.
+func (c *Client) Write(ctx context.Context, namespace string, lines []byte) (string, error) {
+	return c.PrepareWrite(namespace).Write(ctx, lines)
+}
+
+// WritePoints encodes points as line protocol and writes them like Write.
+func (c *Client) WritePoints(ctx context.Context, namespace string, points ...*Point) (string, error) {
+	return c.PrepareWrite(namespace).WritePoints(ctx, points...)
+}
+
+// PrepareWrite prepares a write request, which can be configured before
+// writing.
+//
+// If namespace is "" then the configured default is used.
+func (c *Client) PrepareWrite(namespace string) *WriteRequest {
+	if namespace == "" {
+		namespace = c.config.Namespace
+	}
+	return &WriteRequest{
+		client:    c,
+		namespace: namespace,
+		precision: lineprotocol.Nanosecond,
+	}
+}
+
+// WriteRequest represents a prepared write.
+type WriteRequest struct {
+	client    *Client
+	namespace string
+	precision lineprotocol.Precision
+	gzip      bool
+}
+
+// WithPrecision sets the precision of line protocol timestamps.
+func (r *WriteRequest) WithPrecision(precision lineprotocol.Precision) *WriteRequest {
+	return &WriteRequest{
+		client:    r.client,
+		namespace: r.namespace,
+		precision: precision,
+		gzip:      r.gzip,
+	}
+}
+
+// WithGzip enables gzip compression of the request body.
+func (r *WriteRequest) WithGzip(enabled bool) *WriteRequest {
+	return &WriteRequest{
+		client:    r.client,
+		namespace: r.namespace,
+		precision: r.precision,
+		gzip:      enabled,
+	}
+}
+
+// WritePoints encodes points as line protocol and writes them.
+func (r *WriteRequest) WritePoints(ctx context.Context, points ...*Point) (string, error) {
+	lines, err := EncodePoints(r.precision, points...)
+	if err != nil {
+		return "", err
+	}
+	return r.Write(ctx, lines)
+}
+
+// Write writes line protocol, returning the write token.
+func (r *WriteRequest) Write(ctx context.Context, lines []byte) (string, error) {
+	writeURL, err := r.url()
+	if err != nil {
+		return "", err
+	}
+
+	body := lines
+	if r.gzip {
+		b := bytes.NewBuffer(nil)
+		gz := gzip.NewWriter(b)
+		if _, err = gz.Write(lines); err != nil {
+			return "", err
+		}
+		if err = gz.Close(); err != nil {
+			return "", err
+		}
+		body = b.Bytes()
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx, r.client.config.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, writeURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if r.gzip {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	if tokenSource := r.client.config.tokenSource(); tokenSource != nil {
+		if request.URL.Scheme != "https" && !r.client.config.AllowInsecureToken {
+			return "", errInsecureToken
+		}
+		token, err := tokenSource.Token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get authorization token: %w", err)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := r.client.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 64*1024))
+		return "", &WriteError{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	writeToken, _ := WriteTokenFromHTTPResponse(response)
+	return writeToken, nil
+}
+
+// url builds the write API URL, splitting the namespace into the org and
+// bucket parameters that IOx joins with an underscore.
+func (r *WriteRequest) url() (string, error) {
+	if r.client.config.WriteURL == "" {
+		return "", errors.New("ClientConfig.WriteURL is required to write")
+	}
+	writeURL, err := url.Parse(r.client.config.WriteURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse write URL: %w", err)
+	}
+	orgBucket := strings.SplitN(r.namespace, "_", 2)
+	if len(orgBucket) != 2 || orgBucket[0] == "" || orgBucket[1] == "" {
+		return "", fmt.Errorf("namespace %q must have the form org_bucket", r.namespace)
+	}
+
+	writeURL.Path = strings.TrimSuffix(writeURL.Path, "/") + "/api/v2/write"
+	queryValues := writeURL.Query()
+	queryValues.Set("org", orgBucket[0])
+	queryValues.Set("bucket", orgBucket[1])
+	queryValues.Set("precision", precisionParameter(r.precision))
+	writeURL.RawQuery = queryValues.Encode()
+	return writeURL.String(), nil
+}
+
+func precisionParameter(precision lineprotocol.Precision) string {
+	switch precision {
+	case lineprotocol.Microsecond:
+		return "us"
+	case lineprotocol.Millisecond:
+		return "ms"
+	case lineprotocol.Second:
+		return "s"
+	default:
+		return "ns"
+	}
+}
+
+// EncodePoints encodes points as line protocol, with timestamps at the given
+// precision. Tags and fields are written in lexical key order.
+func EncodePoints(precision lineprotocol.Precision, points ...*Point) ([]byte, error) {
+	e := new(lineprotocol.Encoder)
+	e.SetPrecision(precision)
+
+	for _, point := range points {
+		e.StartLine(point.Measurement)
+		for _, key := range sortedKeys(point.Tags) {
+			e.AddTag(key, point.Tags[key])
+		}
+		for _, key := range sortedKeys(point.Fields) {
+			value, err := newFieldValue(point.Fields[key])
+			if err != nil {
+				return nil, fmt.Errorf("field %q of measurement %q: %w", key, point.Measurement, err)
+			}
+			e.AddField(key, value)
+		}
+		e.EndLine(point.Time)
+		if err := e.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return e.Bytes(), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newFieldValue converts a Go value to a line protocol field value.
+func newFieldValue(v interface{}) (lineprotocol.Value, error) {
+	switch x := v.(type) {
+	case int, int8, int16, int32:
+		i, _ := toInt64(x)
+		return lineprotocol.IntValue(i), nil
+	case uint, uint8, uint16, uint32:
+		u, _ := toUint64(x)
+		return lineprotocol.UintValue(u), nil
+	case float32:
+		v = float64(x)
+	}
+	value, ok := lineprotocol.NewValue(v)
+	if !ok {
+		return lineprotocol.Value{}, fmt.Errorf("invalid field value %v of type %T", v, v)
+	}
+	return value, nil
+}
@@ -0,0 +1,134 @@
+package influxdbiox_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+func ExampleClient_WritePoints() {
+	config := &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		WriteURL:  "http://localhost:8080",
+		Namespace: "myorg_mybucket",
+	}
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+
+	point := influxdbiox.NewPoint("cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"usage_user": 0.5},
+		time.Now())
+	writeToken, _ := client.WritePoints(context.Background(), "", point)
+	_ = client.WaitForReadable(context.Background(), writeToken)
+}
+
+func TestEncodePoints(t *testing.T) {
+	baseTime := time.Date(2021, time.April, 15, 0, 0, 0, 0, time.UTC)
+	lines, err := influxdbiox.EncodePoints(lineprotocol.Second,
+		influxdbiox.NewPoint("t", map[string]string{"b": "2", "a": "1"}, map[string]interface{}{"v": 1, "u": uint32(2), "f": float32(0.5), "s": "x"}, baseTime),
+		influxdbiox.NewPoint("t", nil, map[string]interface{}{"ok": true}, baseTime.Add(time.Second)),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "t,a=1,b=2 f=0.5,s=\"x\",u=2u,v=1i 1618444800\nt ok=true 1618444801\n", string(lines))
+
+	_, err = influxdbiox.EncodePoints(lineprotocol.Second,
+		influxdbiox.NewPoint("t", nil, map[string]interface{}{"v": struct{}{}}, baseTime))
+	assert.EqualError(t, err, `field "v" of measurement "t": invalid field value {} of type struct {}`)
+}
+
+func TestClient_Write(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	var gotRequest *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequest = r
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+		gotBody, _ = ioutil.ReadAll(body)
+		if r.URL.Query().Get("bucket") == "missing" {
+			http.Error(w, `{"code":"not found","message":"bucket not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-IOx-Write-Token", "token")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := influxdbiox.NewClient(ctx, &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "myorg_my_bucket",
+		WriteURL:  server.URL + "/",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	writeToken, err := client.Write(ctx, "", []byte("t v=1i 1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "token", writeToken)
+	assert.Equal(t, "/api/v2/write", gotRequest.URL.Path)
+	assert.Equal(t, "myorg", gotRequest.URL.Query().Get("org"))
+	assert.Equal(t, "my_bucket", gotRequest.URL.Query().Get("bucket"))
+	assert.Equal(t, "ns", gotRequest.URL.Query().Get("precision"))
+	assert.Equal(t, "t v=1i 1\n", string(gotBody))
+
+	writeToken, err = client.PrepareWrite("a_b").WithPrecision(lineprotocol.Millisecond).WithGzip(true).
+		WritePoints(ctx, influxdbiox.NewPoint("t", nil, map[string]interface{}{"v": 1}, time.UnixMilli(2)))
+	require.NoError(t, err)
+	assert.Equal(t, "token", writeToken)
+	assert.Equal(t, "ms", gotRequest.URL.Query().Get("precision"))
+	assert.Equal(t, "gzip", gotRequest.Header.Get("Content-Encoding"))
+	assert.Equal(t, "t v=1i 2\n", string(gotBody))
+
+	_, err = client.Write(ctx, "myorg_missing", []byte("t v=1i 1\n"))
+	var writeError *influxdbiox.WriteError
+	require.ErrorAs(t, err, &writeError)
+	assert.Equal(t, http.StatusNotFound, writeError.StatusCode)
+	assert.False(t, writeError.Temporary())
+
+	_, err = client.Write(ctx, "nounderscore", []byte("t v=1i 1\n"))
+	assert.EqualError(t, err, `namespace "nounderscore" must have the form org_bucket`)
+}
+
+func TestClient_Write_timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(unblock) })
+
+	client, err := influxdbiox.NewClient(ctx, &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "myorg_mybucket",
+		WriteURL:  server.URL,
+		Timeout:   50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	// The configured timeout applies to a context without a deadline.
+	_, err = client.Write(context.Background(), "", []byte("t v=1i 1\n"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
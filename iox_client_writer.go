@@ -0,0 +1,387 @@
+package influxdbiox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// ErrWriterClosed is returned by Writer methods called after Writer.Close.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// ErrWriterBufferFull is returned by Writer.WritePoint when the buffer is full
+// and WriterConfig.Backpressure is BackpressureDrop.
+var ErrWriterBufferFull = errors.New("writer buffer is full; point dropped")
+
+// BackpressurePolicy defines what a Writer does when its buffer is full.
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock blocks Writer.WritePoint until there is space in the
+	// buffer, or the context is done. This is the default.
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDrop drops the point, and Writer.WritePoint returns
+	// ErrWriterBufferFull.
+	BackpressureDrop
+)
+
+// WriterConfig contains the options used by a Writer.
+// Zero values are replaced by defaults.
+type WriterConfig struct {
+	// Maximum number of points per write request; default 5000
+	BatchSize int
+	// Maximum size in bytes of line protocol per write request; default 1 MiB
+	BatchBytes int
+	// Maximum time a point is buffered before it is written; default 1s
+	FlushInterval time.Duration
+
+	// Maximum number of points buffered before they are batched;
+	// default 100000
+	BufferSize int
+	// What to do when the buffer is full
+	Backpressure BackpressurePolicy
+
+	// Maximum number of times a failed write request is retried; default 3.
+	// Only temporary failures are retried: timeouts, refused or reset
+	// connections, HTTP 429 and 5xx.
+	// A negative value disables retries.
+	MaxRetries int
+	// Delay before the first retry, doubled for each subsequent retry, with
+	// random jitter; default 100ms
+	RetryInterval time.Duration
+	// Maximum delay between retries; default 10s
+	MaxRetryInterval time.Duration
+
+	// Precision of line protocol timestamps; default nanosecond
+	Precision lineprotocol.Precision
+	// Compress write requests with gzip
+	Gzip bool
+
+	// Capacity of the channel returned by Writer.Errors; default 100.
+	// Errors are dropped if the channel is full.
+	ErrorBufferSize int
+}
+
+func (wc WriterConfig) withDefaults() WriterConfig {
+	if wc.BatchSize <= 0 {
+		wc.BatchSize = 5000
+	}
+	if wc.BatchBytes <= 0 {
+		wc.BatchBytes = 1 << 20
+	}
+	if wc.FlushInterval <= 0 {
+		wc.FlushInterval = time.Second
+	}
+	if wc.BufferSize <= 0 {
+		wc.BufferSize = 100000
+	}
+	if wc.MaxRetries == 0 {
+		wc.MaxRetries = 3
+	}
+	if wc.RetryInterval <= 0 {
+		wc.RetryInterval = 100 * time.Millisecond
+	}
+	if wc.MaxRetryInterval <= 0 {
+		wc.MaxRetryInterval = 10 * time.Second
+	}
+	if wc.ErrorBufferSize <= 0 {
+		wc.ErrorBufferSize = 100
+	}
+	return wc
+}
+
+// WriteBatchError is sent to Writer.Errors when a batch of points could not
+// be written, after any retries.
+type WriteBatchError struct {
+	Namespace string
+	Points    int
+	Err       error
+}
+
+func (e *WriteBatchError) Error() string {
+	return fmt.Sprintf("failed to write %d points to namespace %q: %s", e.Points, e.Namespace, e.Err)
+}
+
+func (e *WriteBatchError) Unwrap() error {
+	return e.Err
+}
+
+// Writer writes points asynchronously, in batches per namespace.
+//
+// Points are batched until a batch reaches WriterConfig.BatchSize or
+// WriterConfig.BatchBytes, or until WriterConfig.FlushInterval elapses.
+// Batches are written one at a time, so a slow or failing IOx router fills
+// the buffer, and then WriterConfig.Backpressure applies.
+//
+// Construct a Writer with Client.NewWriter. Writer is safe for concurrent use.
+type Writer struct {
+	client *Client
+	config WriterConfig
+
+	points  chan writerPoint
+	flushes chan chan writerFlushResult
+	errors  chan error
+	done    chan struct{}
+
+	mu      sync.RWMutex // guards closed, and adding to senders
+	closed  bool
+	closing chan struct{}
+	senders sync.WaitGroup // WritePoint calls sending to points
+
+	ctx    context.Context // canceled to abort writes when Close times out
+	cancel context.CancelFunc
+}
+
+type writerPoint struct {
+	namespace string
+	line      []byte
+}
+
+type writerBatch struct {
+	namespace string
+	lines     []byte
+	points    int
+}
+
+type writerFlushResult struct {
+	writeTokens []string
+}
+
+// NewWriter constructs a *Writer and starts its background goroutine.
+// The Writer must be closed with Writer.Close.
+func (c *Client) NewWriter(config WriterConfig) *Writer {
+	config = config.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer{
+		client:  c,
+		config:  config,
+		points:  make(chan writerPoint, config.BufferSize),
+		flushes: make(chan chan writerFlushResult),
+		errors:  make(chan error, config.ErrorBufferSize),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go w.run()
+	return w
+}
+
+// WritePoint adds a point to the batch for namespace.
+//
+// If namespace is "" then the configured default is used.
+// The point is encoded immediately, so it may be reused after WritePoint
+// returns. If the Writer is closed while WritePoint is blocked on a full
+// buffer, the point is not written and ErrWriterClosed is returned.
+func (w *Writer) WritePoint(ctx context.Context, namespace string, point *Point) error {
+	if namespace == "" {
+		namespace = w.client.config.Namespace
+	}
+	line, err := EncodePoints(w.config.Precision, point)
+	if err != nil {
+		return err
+	}
+	p := writerPoint{namespace: namespace, line: line}
+
+	// The lock is not held while blocked on a full buffer, so that Close
+	// does not wait for the buffer to drain before it can abort writes.
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return ErrWriterClosed
+	}
+	w.senders.Add(1)
+	w.mu.RUnlock()
+	defer w.senders.Done()
+
+	if w.config.Backpressure == BackpressureDrop {
+		select {
+		case w.points <- p:
+			return nil
+		default:
+			return ErrWriterBufferFull
+		}
+	}
+	select {
+	case w.points <- p:
+		return nil
+	case <-w.closing:
+		return ErrWriterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Errors returns a channel that receives a *WriteBatchError for each batch
+// that could not be written. The channel is closed by Writer.Close.
+func (w *Writer) Errors() <-chan error {
+	return w.errors
+}
+
+// Flush writes all buffered points, and returns the write tokens of every
+// batch written since the previous Flush, including those written in the
+// background when a batch is full or WriterConfig.FlushInterval elapses.
+// The tokens can be passed to Client.WaitForDurable and friends.
+//
+// Batches that fail are reported to Writer.Errors, not by Flush.
+func (w *Writer) Flush(ctx context.Context) ([]string, error) {
+	reply := make(chan writerFlushResult, 1)
+	select {
+	case w.flushes <- reply:
+	case <-w.done:
+		return nil, ErrWriterClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case result := <-reply:
+		return result.writeTokens, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close writes all buffered points and stops the Writer.
+// If ctx is done before the points are written, in-progress writes are
+// aborted and ctx.Err() is returned.
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	w.closed = true
+	close(w.closing)
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	defer close(w.errors)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batches := make(map[string]*writerBatch)
+	var writeTokens []string // of the batches written since the last Flush
+
+	writeBatch := func(batch *writerBatch) {
+		delete(batches, batch.namespace)
+		if writeToken, err := w.writeWithRetry(batch); err != nil {
+			select {
+			case w.errors <- &WriteBatchError{Namespace: batch.namespace, Points: batch.points, Err: err}:
+			default:
+			}
+		} else if writeToken != "" {
+			writeTokens = append(writeTokens, writeToken)
+		}
+	}
+	add := func(p writerPoint) {
+		batch := batches[p.namespace]
+		if batch != nil && len(batch.lines)+len(p.line) > w.config.BatchBytes {
+			writeBatch(batch)
+			batch = nil
+		}
+		if batch == nil {
+			batch = &writerBatch{namespace: p.namespace}
+			batches[p.namespace] = batch
+		}
+		batch.lines = append(batch.lines, p.line...)
+		batch.points++
+		if batch.points >= w.config.BatchSize || len(batch.lines) >= w.config.BatchBytes {
+			writeBatch(batch)
+		}
+	}
+	flushAll := func() {
+		// Include every point buffered before the flush was requested.
+		for n := len(w.points); n > 0; n-- {
+			add(<-w.points)
+		}
+		for _, namespace := range sortedKeys(batches) {
+			writeBatch(batches[namespace])
+		}
+	}
+
+	for {
+		select {
+		case p := <-w.points:
+			add(p)
+		case <-ticker.C:
+			flushAll()
+		case reply := <-w.flushes:
+			flushAll()
+			reply <- writerFlushResult{writeTokens: writeTokens}
+			writeTokens = nil
+		case <-w.closing:
+			// No more points can be sent once the senders are done.
+			w.senders.Wait()
+			flushAll()
+			return
+		}
+	}
+}
+
+// writeWithRetry writes a batch, retrying temporary failures with jittered
+// exponential backoff.
+func (w *Writer) writeWithRetry(batch *writerBatch) (string, error) {
+	request := w.client.PrepareWrite(batch.namespace).WithPrecision(w.config.Precision).WithGzip(w.config.Gzip)
+	interval := w.config.RetryInterval
+	for attempt := 0; ; attempt++ {
+		writeToken, err := request.Write(w.ctx, batch.lines)
+		if err == nil {
+			return writeToken, nil
+		}
+		if attempt >= w.config.MaxRetries || !isTemporaryWriteError(err) {
+			return "", err
+		}
+
+		// Sleep for a random duration in [interval/2, interval).
+		jittered := interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+		select {
+		case <-time.After(jittered):
+		case <-w.ctx.Done():
+			return "", err
+		}
+		if interval *= 2; interval > w.config.MaxRetryInterval {
+			interval = w.config.MaxRetryInterval
+		}
+	}
+}
+
+// isTemporaryWriteError reports whether a write request that failed with err
+// may succeed if retried: HTTP 429 and 5xx responses, timeouts, and refused
+// or reset connections. Other errors of the HTTP client, such as TLS
+// failures and malformed URLs, are not temporary.
+func isTemporaryWriteError(err error) bool {
+	var writeError *WriteError
+	if errors.As(err, &writeError) {
+		return writeError.Temporary()
+	}
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		err = urlError.Err
+	}
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,36 @@
+package influxdbiox
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTemporaryWriteError(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		expect bool
+	}{
+		{&WriteError{StatusCode: 429}, true},
+		{&WriteError{StatusCode: 503}, true},
+		{&WriteError{StatusCode: 400}, false},
+		{&url.Error{Op: "Post", URL: "http://iox:8080", Err: context.DeadlineExceeded}, true},
+		{&url.Error{Op: "Post", URL: "http://iox:8080", Err: os.ErrDeadlineExceeded}, true},
+		{&url.Error{Op: "Post", URL: "http://iox:8080", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, true},
+		{&url.Error{Op: "Post", URL: "http://iox:8080", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, true},
+		{&url.Error{Op: "Post", URL: "https://iox:8080", Err: x509.UnknownAuthorityError{}}, false},
+		{&url.Error{Op: "parse", URL: "http://iox:port", Err: errors.New("invalid port")}, false},
+		{&net.DNSError{Err: "no such host", Name: "iox"}, false},
+		{fmt.Errorf("write: %w", &net.DNSError{Err: "timeout", Name: "iox", IsTimeout: true}), true},
+	} {
+		assert.Equal(t, tc.expect, isTemporaryWriteError(tc.err), "%v", tc.err)
+	}
+}
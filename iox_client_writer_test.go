@@ -0,0 +1,231 @@
+package influxdbiox_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+func ExampleClient_NewWriter() {
+	config := &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		WriteURL:  "http://localhost:8080",
+		Namespace: "myorg_mybucket",
+	}
+	client, _ := influxdbiox.NewClient(context.Background(), config)
+
+	writer := client.NewWriter(influxdbiox.WriterConfig{BatchSize: 1000})
+	go func() {
+		for err := range writer.Errors() {
+			fmt.Println(err)
+		}
+	}()
+
+	for i := 0; i < 10000; i++ {
+		point := influxdbiox.NewPoint("cpu", nil, map[string]interface{}{"i": i}, time.Now())
+		_ = writer.WritePoint(context.Background(), "", point)
+	}
+	writeTokens, _ := writer.Flush(context.Background())
+	for _, writeToken := range writeTokens {
+		_ = client.WaitForReadable(context.Background(), writeToken)
+	}
+	_ = writer.Close(context.Background())
+}
+
+type writerTestServer struct {
+	mu       sync.Mutex
+	bodies   map[string][]string // bucket -> request bodies
+	failures int                 // number of requests to fail with 503
+	requests int
+}
+
+func (s *writerTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+		return
+	}
+	if r.URL.Query().Get("bucket") == "missing" {
+		http.Error(w, "bucket not found", http.StatusNotFound)
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	s.bodies[bucket] = append(s.bodies[bucket], string(body))
+	w.Header().Set("X-IOx-Write-Token", fmt.Sprintf("token%d", s.requests))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newWriterTestClient(t *testing.T, handler http.Handler) *influxdbiox.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := influxdbiox.NewClient(context.Background(), &influxdbiox.ClientConfig{
+		Address:   "localhost:8082",
+		Namespace: "myorg_a",
+		WriteURL:  server.URL,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestWriter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := &writerTestServer{bodies: make(map[string][]string), failures: 1}
+	client := newWriterTestClient(t, server)
+
+	writer := client.NewWriter(influxdbiox.WriterConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		RetryInterval: time.Millisecond,
+	})
+	for i := 0; i < 3; i++ {
+		point := influxdbiox.NewPoint("t", nil, map[string]interface{}{"v": i}, time.Unix(0, int64(i)))
+		require.NoError(t, writer.WritePoint(ctx, "", point))
+	}
+	// The first batch is full, so it is written in the background; its
+	// first request fails with 503 and is retried.
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.requests == 2
+	}, 5*time.Second, time.Millisecond)
+	point := influxdbiox.NewPoint("t", nil, map[string]interface{}{"v": 9}, time.Unix(0, 9))
+	require.NoError(t, writer.WritePoint(ctx, "myorg_b", point))
+	require.NoError(t, writer.WritePoint(ctx, "myorg_missing", point))
+
+	// Flush returns the tokens of the batches it wrote, and of those written
+	// in the background since the previous Flush.
+	writeTokens, err := writer.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token2", "token3", "token4"}, writeTokens)
+	assert.Equal(t, map[string][]string{
+		"a": {"t v=0i 0\nt v=1i 1\n", "t v=2i 2\n"},
+		"b": {"t v=9i 9\n"},
+	}, server.bodies)
+
+	var batchError *influxdbiox.WriteBatchError
+	require.ErrorAs(t, <-writer.Errors(), &batchError)
+	assert.Equal(t, "myorg_missing", batchError.Namespace)
+	assert.Equal(t, 1, batchError.Points)
+	var writeError *influxdbiox.WriteError
+	require.ErrorAs(t, batchError, &writeError)
+	assert.Equal(t, http.StatusNotFound, writeError.StatusCode)
+
+	writeTokens, err = writer.Flush(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, writeTokens)
+
+	require.NoError(t, writer.WritePoint(ctx, "", point))
+	require.NoError(t, writer.Close(ctx))
+	assert.Equal(t, "t v=9i 9\n", server.bodies["a"][2])
+	_, open := <-writer.Errors()
+	assert.False(t, open)
+
+	assert.ErrorIs(t, writer.WritePoint(ctx, "", point), influxdbiox.ErrWriterClosed)
+	_, err = writer.Flush(ctx)
+	assert.ErrorIs(t, err, influxdbiox.ErrWriterClosed)
+}
+
+func TestWriter_backpressure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	received := make(chan struct{}, 10)
+	unblock := make(chan struct{})
+	client := newWriterTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-unblock
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, policy := range []influxdbiox.BackpressurePolicy{influxdbiox.BackpressureDrop, influxdbiox.BackpressureBlock} {
+		writer := client.NewWriter(influxdbiox.WriterConfig{
+			BatchSize:    1,
+			BufferSize:   1,
+			Backpressure: policy,
+		})
+		point := influxdbiox.NewPoint("t", nil, map[string]interface{}{"v": 1}, time.Unix(0, 1))
+
+		// The first point is being written, the second fills the buffer.
+		require.NoError(t, writer.WritePoint(ctx, "", point))
+		<-received
+		require.NoError(t, writer.WritePoint(ctx, "", point))
+
+		if policy == influxdbiox.BackpressureDrop {
+			assert.ErrorIs(t, writer.WritePoint(ctx, "", point), influxdbiox.ErrWriterBufferFull)
+		} else {
+			shortCtx, shortCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			assert.ErrorIs(t, writer.WritePoint(shortCtx, "", point), context.DeadlineExceeded)
+			shortCancel()
+		}
+
+		unblock <- struct{}{}
+		<-received
+		unblock <- struct{}{}
+		require.NoError(t, writer.Close(ctx))
+	}
+}
+
+func TestWriter_closeStalled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	received := make(chan struct{}, 10)
+	client := newWriterTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The request context is only canceled once the body has been read.
+		_, _ = ioutil.ReadAll(r.Body)
+		received <- struct{}{}
+		<-r.Context().Done()
+	}))
+
+	writer := client.NewWriter(influxdbiox.WriterConfig{
+		BatchSize:  1,
+		BufferSize: 1,
+		MaxRetries: -1,
+	})
+	point := influxdbiox.NewPoint("t", nil, map[string]interface{}{"v": 1}, time.Unix(0, 1))
+
+	// The first point is being written, the second fills the buffer, and
+	// the third blocks WritePoint.
+	require.NoError(t, writer.WritePoint(ctx, "", point))
+	<-received
+	require.NoError(t, writer.WritePoint(ctx, "", point))
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- writer.WritePoint(ctx, "", point)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Close aborts the stalled write when its context is done, and the
+	// blocked WritePoint returns.
+	closeCtx, closeCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer closeCancel()
+	closed := make(chan error, 1)
+	go func() {
+		closed <- writer.Close(closeCtx)
+	}()
+	select {
+	case err := <-closed:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-ctx.Done():
+		t.Fatal("Close did not return")
+	}
+	assert.ErrorIs(t, <-blocked, influxdbiox.ErrWriterClosed)
+}
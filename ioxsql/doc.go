@@ -1,7 +1,7 @@
 // Package ioxsql is the compatibility layer from influxdbiox to database/sql.
 //
 // A database/sql connection can be established through sql.Open.
-// Two data source name formats are supported.
+// Three data source name formats are supported.
 //
 // The complete influxdbiox.ClientConfig struct is supported as JSON:
 //
@@ -21,10 +21,28 @@
 //	dsn, err = config.ToJSONString()
 //	db, err := sql.Open("influxdb-iox", dsn)
 //
+// ToJSONString redacts the "token" field, which sets a bearer token for every
+// request. To open a connection with a token, use json.Marshal or NewConnector.
+//
+// The URL format supports the common fields, including a bearer token as the
+// user name, and the namespace as the path; see
+// influxdbiox.ClientConfigFromURLString. Use scheme "iox+tls" for TLS:
+//
+//	db, err := sql.Open("influxdb-iox", "iox+tls://mytoken@iox.example.com:8082/mydb?timeout=30s")
+//
+// The influxdbiox.ClientConfig struct serializes to a URL with method ToURL.
+//
 // The host:port address format is simpler to type, but only sets the address field:
 //
 //	db, err := sql.Open("influxdb-iox", "localhost:8082")
 //
+// To balance queries across several IOx servers, list their addresses, in
+// the URL or address format, or in the "addresses" JSON field; see
+// influxdbiox.LoadBalancingPolicy:
+//
+//	db, err := sql.Open("influxdb-iox", "iox://iox-1:8082,iox-2:8082/mydb?load_balancing=least_outstanding")
+//	db, err = sql.Open("influxdb-iox", "iox-1:8082,iox-2:8082/mydb")
+//
 // Or a influxdbiox.ClientConfig can be used directly.
 //
 //	config := &influxdbiox.ClientConfig{
@@ -32,4 +50,66 @@
 //	  TLSInsecureSkipVerify: true,
 //	}
 //	db := sql.OpenDB(ioxsql.NewConnector(config))
+//
+// InfluxQL queries are enabled with the "query_type" field of the JSON data
+// source name:
+//
+//	db, err := sql.Open("influxdb-iox", `{"address":"localhost:8082","namespace":"mydb","query_type":"influxql"}`)
+//	rows, err := db.Query(`SELECT mean("usage") FROM "cpu" WHERE time > now() - 1h`)
+//
+// To query with the Arrow Flight SQL protocol, for example against a Flight SQL
+// server other than IOx, set the "flight_sql" field of the JSON data source name.
+// Query arguments are then bound by the server to a prepared statement:
+//
+//	db, err := sql.Open("influxdb-iox", `{"address":"localhost:8082","namespace":"mydb","flight_sql":true}`)
+//
+// Query arguments are bound to positional ($1) or named ($name) placeholders,
+// and are encoded as SQL literals before the query is sent:
+//
+//	rows, err := db.Query("select * from cpu where host = $1", "server01")
+//	rows, err = db.Query("select * from cpu where host = $host", sql.Named("host", "server01"))
+//
+// Exec writes to the default namespace via the IOx HTTP write API, which
+// requires the "write_url" field of the data source name. The statement is
+// either line protocol, with nanosecond timestamps:
+//
+//	result, err := db.Exec("cpu,host=server01 usage=0.5 1618444800000000000")
+//
+// or an INSERT statement with a column list, which is translated to line
+// protocol according to the schema of the table, so the table must exist.
+// Values are string, number, TRUE, FALSE and NULL literals, or placeholders;
+// timestamps are RFC 3339 strings, nanoseconds since the epoch, or
+// time.Time arguments. Unquoted identifiers are folded to lower case, as in
+// queries:
+//
+//	result, err = db.Exec(`INSERT INTO cpu (host, usage, time) VALUES ('server01', $1, $2)`, 0.5, time.Now())
+//
+// RowsAffected is the number of points written. The write token of the
+// last write on a connection is returned by Connection.WriteToken.
+//
+// Result columns are scanned from these Go types, which are also reported by
+// sql.ColumnType.ScanType, according to their Arrow type:
+//
+//	int64      signed integers, and durations and times of day since
+//	           midnight in nanoseconds, which also scan into time.Duration
+//	uint64     unsigned integers, which are passed to database/sql as int64,
+//	           or as decimal strings above math.MaxInt64
+//	float64    floating point numbers
+//	string     strings; decimals as exact decimal strings such as "-1.50",
+//	           which also scan into float64; and lists as JSON arrays
+//	[]byte     binary
+//	bool       booleans
+//	time.Time  timestamps and dates
+//
+// Timestamps are converted according to the unit of their Arrow type, and
+// are presented in its time zone, or in UTC if it has none, such as the IOx
+// time column. The "location" field of the data source name presents every
+// timestamp in the named time zone instead:
+//
+//	db, err := sql.Open("influxdb-iox", "iox://localhost:8082/mydb?location=Local")
+//
+// Dictionary-encoded columns, such as IOx tags, have the type of their
+// values. sql.ColumnType.DatabaseTypeName is the Arrow type ID, such as
+// "INT64" or "DECIMAL128", and sql.ColumnType.DecimalSize reports the
+// precision and scale of decimals.
 package ioxsql
@@ -5,7 +5,8 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
-	"strings"
+	"fmt"
+	"time"
 
 	"github.com/influxdata/influxdb-iox-client-go/v2"
 	"google.golang.org/grpc/connectivity"
@@ -40,13 +41,7 @@ func (d *Driver) Open(dataSourceName string) (driver.Conn, error) {
 }
 
 func (*Driver) OpenConnector(dataSourceName string) (driver.Connector, error) {
-	var config *influxdbiox.ClientConfig
-	var err error
-	if s := strings.TrimSpace(dataSourceName); len(s) > 0 && s[0] == '{' {
-		config, err = influxdbiox.ClientConfigFromJSONString(dataSourceName)
-	} else {
-		config, err = influxdbiox.ClientConfigFromAddressString(dataSourceName)
-	}
+	config, err := influxdbiox.ClientConfigFromString(dataSourceName)
 	if err != nil {
 		return nil, err
 	}
@@ -66,12 +61,19 @@ func NewConnector(config *influxdbiox.ClientConfig) *Connector {
 }
 
 func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var location *time.Location
+	if c.config.Location != "" {
+		var err error
+		if location, err = time.LoadLocation(c.config.Location); err != nil {
+			return nil, fmt.Errorf("invalid location: %w", err)
+		}
+	}
 	client, err := influxdbiox.NewClient(ctx, c.config)
 	if err != nil {
 		return nil, err
 	}
 
-	return newConnection(client), nil
+	return newConnection(client, location), nil
 }
 
 func (c *Connector) Driver() driver.Driver {
@@ -87,12 +89,15 @@ var (
 )
 
 type Connection struct {
-	client *influxdbiox.Client
+	client     *influxdbiox.Client
+	location   *time.Location // of timestamps; nil for the default
+	writeToken string         // of the last write by Exec
 }
 
-func newConnection(client *influxdbiox.Client) *Connection {
+func newConnection(client *influxdbiox.Client, location *time.Location) *Connection {
 	return &Connection{
-		client: client,
+		client:   client,
+		location: location,
 	}
 }
 
@@ -110,12 +115,27 @@ func (c *Connection) Client() *influxdbiox.Client {
 	return c.client
 }
 
+// WriteToken returns the write token of the last write by Exec on this
+// Connection, or "" if there has been none, or IOx did not return one. Pass
+// it to influxdbiox.Client.WaitForReadable to wait until the write can be
+// queried:
+//
+//	conn, err := db.Conn(context.Background())
+//	_, err = conn.ExecContext(ctx, "cpu,host=a usage=0.5")
+//	err = conn.Raw(func(driverConn interface{}) error {
+//	  connection := driverConn.(*ioxsql.Connection)
+//	  return connection.Client().WaitForReadable(ctx, connection.WriteToken())
+//	})
+func (c *Connection) WriteToken() string {
+	return c.writeToken
+}
+
 func (c *Connection) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	request, err := c.client.PrepareQuery(ctx, "", query)
 	if err != nil {
 		return nil, err
 	}
-	return newStatement(request), nil
+	return newStatement(c, query, request), nil
 }
 
 func (c *Connection) Prepare(query string) (driver.Stmt, error) {
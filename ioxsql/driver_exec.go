@@ -0,0 +1,577 @@
+package ioxsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+)
+
+var _ driver.Result = writeResult(0)
+
+// writeResult is the result of a write by Exec; the number of points written.
+type writeResult int64
+
+func (r writeResult) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported")
+}
+
+func (r writeResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+// execWrite writes query, which is either line protocol or an INSERT
+// statement, to the default namespace of the connection.
+func execWrite(ctx context.Context, conn *Connection, query string, args []driver.NamedValue) (driver.Result, error) {
+	var lines []byte
+	var n int64
+	if isInsert(query) {
+		insert, err := parseInsert(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INSERT statement: %w", err)
+		}
+		namespace := conn.client.Config().Namespace
+		columnTypes, err := conn.client.GetSchema(ctx, namespace, insert.table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get schema of table %q: %w", insert.table, err)
+		}
+		points, err := insert.points(columnTypes, args)
+		if err != nil {
+			return nil, err
+		}
+		if lines, err = influxdbiox.EncodePoints(lineprotocol.Nanosecond, points...); err != nil {
+			return nil, err
+		}
+		n = int64(len(points))
+	} else {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("line protocol does not accept arguments, but %d were provided", len(args))
+		}
+		lines = []byte(query)
+		var err error
+		if n, err = countLines(lines); err != nil {
+			return nil, fmt.Errorf("invalid line protocol: %w", err)
+		}
+	}
+	if n == 0 {
+		return writeResult(0), nil
+	}
+
+	writeToken, err := conn.client.Write(ctx, "", lines)
+	if err != nil {
+		return nil, err
+	}
+	conn.writeToken = writeToken
+	return writeResult(n), nil
+}
+
+// countLines validates line protocol, returning the number of points.
+func countLines(lines []byte) (int64, error) {
+	var n int64
+	decoder := lineprotocol.NewDecoderWithBytes(lines)
+	for decoder.Next() {
+		if _, err := decoder.Measurement(); err != nil {
+			return 0, err
+		}
+		for {
+			key, _, err := decoder.NextTag()
+			if err != nil {
+				return 0, err
+			} else if key == nil {
+				break
+			}
+		}
+		for {
+			key, _, err := decoder.NextField()
+			if err != nil {
+				return 0, err
+			} else if key == nil {
+				break
+			}
+		}
+		if _, err := decoder.Time(lineprotocol.Nanosecond, time.Time{}); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, decoder.Err()
+}
+
+// isInsert reports whether query is an INSERT statement, rather than line
+// protocol: it starts with INSERT, whitespace and INTO. INSERT alone may be
+// a measurement, and "INSERT into=1" is a point with an into field.
+func isInsert(query string) bool {
+	tokens := newInsertTokenizer(query)
+	token, err := tokens.next()
+	if err != nil || token.kind != tokenIdentifier || token.quoted || token.text != "insert" {
+		return false
+	}
+	if tokens.i >= len(tokens.s) || strings.IndexByte(" \t\r\n", tokens.s[tokens.i]) < 0 {
+		return false
+	}
+	token, err = tokens.next()
+	if err != nil || token.kind != tokenIdentifier || token.quoted || token.text != "into" {
+		return false
+	}
+	return tokens.i >= len(tokens.s) || tokens.s[tokens.i] != '='
+}
+
+// insertStatement is a parsed INSERT INTO table (columns) VALUES (values)
+// statement.
+type insertStatement struct {
+	table   string
+	columns []string
+	rows    [][]interface{} // literal values, nil for NULL, or placeholders
+}
+
+// insertPlaceholder is a $1 or $name placeholder in the values of an INSERT
+// statement.
+type insertPlaceholder struct {
+	ordinal int    // 1-based position, or 0 if named
+	name    string // set if the placeholder is named
+}
+
+// parseInsert parses an INSERT statement. Literal values are parsed as
+// string, int64, uint64 (integers that overflow int64), float64, bool or
+// nil.
+func parseInsert(query string) (*insertStatement, error) {
+	tokens := newInsertTokenizer(query)
+	for _, keyword := range []string{"insert", "into"} {
+		if err := tokens.expectKeyword(keyword); err != nil {
+			return nil, err
+		}
+	}
+	table, err := tokens.expect(tokenIdentifier, "table name")
+	if err != nil {
+		return nil, err
+	}
+	insert := &insertStatement{table: table.text}
+
+	if _, err = tokens.expect(tokenPunctuation, "("); err != nil {
+		return nil, fmt.Errorf("%w; a column list is required", err)
+	}
+	seen := make(map[string]bool)
+	for {
+		column, err := tokens.expect(tokenIdentifier, "column name")
+		if err != nil {
+			return nil, err
+		}
+		if seen[column.text] {
+			return nil, fmt.Errorf("column %q is listed more than once", column.text)
+		}
+		seen[column.text] = true
+		insert.columns = append(insert.columns, column.text)
+		if more, err := tokens.listSeparator(); err != nil {
+			return nil, err
+		} else if !more {
+			break
+		}
+	}
+
+	if err = tokens.expectKeyword("values"); err != nil {
+		return nil, err
+	}
+	for {
+		if _, err = tokens.expect(tokenPunctuation, "("); err != nil {
+			return nil, err
+		}
+		var row []interface{}
+		for {
+			value, err := tokens.value()
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, value)
+			if more, err := tokens.listSeparator(); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+		if len(row) != len(insert.columns) {
+			return nil, fmt.Errorf("row %d has %d values, but %d columns are listed", len(insert.rows)+1, len(row), len(insert.columns))
+		}
+		insert.rows = append(insert.rows, row)
+
+		token, err := tokens.next()
+		if err != nil {
+			return nil, err
+		}
+		if token.kind == tokenPunctuation && token.text == "," {
+			continue
+		}
+		if token.kind == tokenPunctuation && token.text == ";" {
+			token, err = tokens.next()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if token.kind != tokenEOF {
+			return nil, fmt.Errorf("unexpected %s after VALUES", token)
+		}
+		return insert, nil
+	}
+}
+
+// points converts the rows of the statement to points, with the tags, fields
+// and time given by columnTypes, the schema of the table. Placeholders are
+// replaced by args, as in a query.
+func (s *insertStatement) points(columnTypes map[string]influxdbiox.ColumnType, args []driver.NamedValue) ([]*influxdbiox.Point, error) {
+	for _, column := range s.columns {
+		if _, found := columnTypes[column]; !found {
+			return nil, fmt.Errorf("column %q not found in table %q", column, s.table)
+		}
+	}
+
+	named := make(map[string]int)
+	for i, arg := range args {
+		if arg.Name != "" {
+			named[arg.Name] = i
+		}
+	}
+	used := make([]bool, len(args))
+
+	points := make([]*influxdbiox.Point, len(s.rows))
+	for i, row := range s.rows {
+		point := &influxdbiox.Point{
+			Measurement: s.table,
+			Tags:        make(map[string]string),
+			Fields:      make(map[string]interface{}),
+		}
+		for j, value := range row {
+			column := s.columns[j]
+			if p, ok := value.(insertPlaceholder); ok {
+				index, found := named[p.name]
+				if p.name == "" {
+					index, found = p.ordinal-1, p.ordinal <= len(args)
+				}
+				if !found {
+					return nil, fmt.Errorf("no argument provided for placeholder %s", p)
+				}
+				used[index] = true
+				value = args[index].Value
+				if typedArg, ok := value.(influxdbiox.TypedArg); ok {
+					var err error
+					if value, err = typedArgValue(typedArg); err != nil {
+						return nil, fmt.Errorf("row %d, column %q: %w", i+1, column, err)
+					}
+				}
+			}
+			if value == nil {
+				continue
+			}
+			columnType := columnTypes[column]
+			converted, err := insertValue(columnType, value)
+			if err != nil {
+				return nil, fmt.Errorf("row %d, column %q: %w", i+1, column, err)
+			}
+			switch columnType {
+			case influxdbiox.ColumnType_TAG:
+				point.Tags[column] = converted.(string)
+			case influxdbiox.ColumnType_TIME:
+				point.Time = converted.(time.Time)
+			default:
+				point.Fields[column] = converted
+			}
+		}
+		if len(point.Fields) == 0 {
+			return nil, fmt.Errorf("row %d has no non-NULL field values", i+1)
+		}
+		points[i] = point
+	}
+
+	for i, u := range used {
+		if !u {
+			return nil, fmt.Errorf("argument %d is not referenced by any placeholder", i+1)
+		}
+	}
+	return points, nil
+}
+
+func (p insertPlaceholder) String() string {
+	if p.name != "" {
+		return "$" + p.name
+	}
+	return "$" + strconv.Itoa(p.ordinal)
+}
+
+// typedArgValue returns the value of a TypedArg, which database/sql passes
+// to the driver unconverted, converted as database/sql converts other
+// arguments; uint64 values are kept, as for other arguments.
+func typedArgValue(arg influxdbiox.TypedArg) (interface{}, error) {
+	if u, ok := arg.Value.(uint64); ok {
+		return u, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(arg.Value)
+}
+
+// insertValue converts a literal or argument value to the Go type of a
+// point tag, field or time for a column of columnType.
+func insertValue(columnType influxdbiox.ColumnType, v interface{}) (interface{}, error) {
+	switch columnType {
+	case influxdbiox.ColumnType_TAG, influxdbiox.ColumnType_STRING:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case []byte:
+			return string(s), nil
+		}
+	case influxdbiox.ColumnType_I64:
+		switch i := v.(type) {
+		case int64:
+			return i, nil
+		case uint64:
+			if i <= math.MaxInt64 {
+				return int64(i), nil
+			}
+			return nil, fmt.Errorf("value %d overflows %s", i, columnType)
+		}
+	case influxdbiox.ColumnType_U64:
+		switch u := v.(type) {
+		case uint64:
+			return u, nil
+		case int64:
+			if u >= 0 {
+				return uint64(u), nil
+			}
+			return nil, fmt.Errorf("value %d overflows %s", u, columnType)
+		}
+	case influxdbiox.ColumnType_F64:
+		switch f := v.(type) {
+		case float64:
+			return f, nil
+		case int64:
+			return float64(f), nil
+		case uint64:
+			return float64(f), nil
+		}
+	case influxdbiox.ColumnType_BOOL:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case influxdbiox.ColumnType_TIME:
+		switch t := v.(type) {
+		case time.Time:
+			return t, nil
+		case int64:
+			return time.Unix(0, t), nil
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, t)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp: %w", err)
+			}
+			return parsed, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot insert %T into a %s column", v, columnType)
+}
+
+type insertTokenKind int
+
+const (
+	tokenEOF insertTokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenPlaceholder
+	tokenPunctuation
+)
+
+type insertToken struct {
+	kind insertTokenKind
+	// text is the identifier, folded to lower case unless quoted; the
+	// unquoted string; the number or placeholder as written; or the
+	// punctuation character.
+	text   string
+	quoted bool
+}
+
+func (t insertToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of statement"
+	case tokenString:
+		return fmt.Sprintf("string '%s'", t.text)
+	case tokenIdentifier:
+		if t.quoted {
+			return fmt.Sprintf("identifier %q", t.text)
+		}
+		return fmt.Sprintf("%q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// insertTokenizer splits an INSERT statement into tokens, skipping
+// whitespace and comments.
+type insertTokenizer struct {
+	s string
+	i int
+}
+
+func newInsertTokenizer(s string) *insertTokenizer {
+	return &insertTokenizer{s: s}
+}
+
+func (t *insertTokenizer) next() (insertToken, error) {
+	for t.i < len(t.s) {
+		switch {
+		case strings.HasPrefix(t.s[t.i:], "--"):
+			if end := strings.IndexByte(t.s[t.i:], '\n'); end >= 0 {
+				t.i += end
+			} else {
+				t.i = len(t.s)
+			}
+		case strings.HasPrefix(t.s[t.i:], "/*"):
+			end := strings.Index(t.s[t.i+2:], "*/")
+			if end < 0 {
+				return insertToken{}, fmt.Errorf("unterminated comment starting at offset %d", t.i)
+			}
+			t.i += end + 4
+		case strings.IndexByte(" \t\r\n", t.s[t.i]) >= 0:
+			t.i++
+		default:
+			return t.token()
+		}
+	}
+	return insertToken{kind: tokenEOF}, nil
+}
+
+func (t *insertTokenizer) token() (insertToken, error) {
+	start := t.i
+	switch c := t.s[t.i]; {
+	case c == '\'' || c == '"':
+		// A doubled quote is an escaped quote.
+		var b strings.Builder
+		for t.i++; t.i < len(t.s); t.i++ {
+			if t.s[t.i] == c {
+				if t.i+1 < len(t.s) && t.s[t.i+1] == c {
+					t.i++
+				} else {
+					t.i++
+					if c == '"' {
+						return insertToken{kind: tokenIdentifier, text: b.String(), quoted: true}, nil
+					}
+					return insertToken{kind: tokenString, text: b.String()}, nil
+				}
+			}
+			b.WriteByte(t.s[t.i])
+		}
+		return insertToken{}, fmt.Errorf("unterminated quoted string starting at offset %d", start)
+	case c == '$':
+		for t.i++; t.i < len(t.s) && isIdentifierByte(t.s[t.i]); t.i++ {
+		}
+		if t.i == start+1 {
+			return insertToken{}, fmt.Errorf("invalid placeholder at offset %d", start)
+		}
+		return insertToken{kind: tokenPlaceholder, text: t.s[start:t.i]}, nil
+	case c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9':
+		for t.i++; t.i < len(t.s) && (isIdentifierByte(t.s[t.i]) || t.s[t.i] == '.' ||
+			(t.s[t.i] == '-' || t.s[t.i] == '+') && (t.s[t.i-1] == 'e' || t.s[t.i-1] == 'E')); t.i++ {
+		}
+		return insertToken{kind: tokenNumber, text: t.s[start:t.i]}, nil
+	case isIdentifierByte(c):
+		for t.i++; t.i < len(t.s) && isIdentifierByte(t.s[t.i]); t.i++ {
+		}
+		return insertToken{kind: tokenIdentifier, text: strings.ToLower(t.s[start:t.i])}, nil
+	case strings.IndexByte("(),;", c) >= 0:
+		t.i++
+		return insertToken{kind: tokenPunctuation, text: string(c)}, nil
+	default:
+		return insertToken{}, fmt.Errorf("unexpected character %q at offset %d", c, start)
+	}
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// expect returns the next token, which must be of kind; for punctuation,
+// what is the expected character.
+func (t *insertTokenizer) expect(kind insertTokenKind, what string) (insertToken, error) {
+	token, err := t.next()
+	if err != nil {
+		return insertToken{}, err
+	}
+	if token.kind != kind || kind == tokenPunctuation && token.text != what {
+		return insertToken{}, fmt.Errorf("expected %s, got %s", what, token)
+	}
+	return token, nil
+}
+
+func (t *insertTokenizer) expectKeyword(keyword string) error {
+	token, err := t.next()
+	if err != nil {
+		return err
+	}
+	if token.kind != tokenIdentifier || token.quoted || token.text != keyword {
+		return fmt.Errorf("expected %s, got %s", strings.ToUpper(keyword), token)
+	}
+	return nil
+}
+
+// listSeparator consumes a comma, reporting that the list continues, or a
+// closing parenthesis, reporting that it ends.
+func (t *insertTokenizer) listSeparator() (bool, error) {
+	token, err := t.next()
+	if err != nil {
+		return false, err
+	}
+	if token.kind == tokenPunctuation && (token.text == "," || token.text == ")") {
+		return token.text == ",", nil
+	}
+	return false, fmt.Errorf("expected \",\" or \")\", got %s", token)
+}
+
+// value parses a literal or placeholder.
+func (t *insertTokenizer) value() (interface{}, error) {
+	token, err := t.next()
+	if err != nil {
+		return nil, err
+	}
+	switch token.kind {
+	case tokenString:
+		return token.text, nil
+	case tokenNumber:
+		if i, err := strconv.ParseInt(token.text, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(token.text, 10, 64); err == nil {
+			return u, nil
+		}
+		f, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token.text)
+		}
+		return f, nil
+	case tokenPlaceholder:
+		if s := token.text[1:]; s[0] >= '0' && s[0] <= '9' {
+			ordinal, err := strconv.Atoi(s)
+			if err != nil || ordinal < 1 {
+				return nil, fmt.Errorf("invalid positional placeholder %q", token.text)
+			}
+			return insertPlaceholder{ordinal: ordinal}, nil
+		}
+		return insertPlaceholder{name: token.text[1:]}, nil
+	case tokenIdentifier:
+		if !token.quoted {
+			switch token.text {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			case "null":
+				return nil, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("expected a literal value or placeholder, got %s", token)
+}
@@ -3,15 +3,14 @@ package ioxsql
 import (
 	"context"
 	"database/sql/driver"
-	"fmt"
+	"encoding/json"
 	"io"
 	"math"
 	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/apache/arrow/go/v10/arrow"
-	"github.com/apache/arrow/go/v10/arrow/array"
-	"github.com/apache/arrow/go/v10/arrow/flight"
 	"github.com/influxdata/influxdb-iox-client-go/v2"
 )
 
@@ -25,15 +24,16 @@ var (
 )
 
 type rows struct {
-	flightReader *flight.Reader // stream of result sets
+	flightReader *influxdbiox.QueryReader // stream of result sets
 	fields       []arrow.Field
-	record       arrow.Record // current result set
-	rowI         int          // next row index for current result set
+	record       arrow.Record   // current result set
+	rowI         int            // next row index for current result set
+	location     *time.Location // of timestamps; nil for the default
 }
 
 // queryRows constructs a new rows object by executing a query request
-func queryRows(ctx context.Context, request *influxdbiox.QueryRequest, _argsReserved []interface{}) (*rows, error) {
-	flightReader, err := request.Query(ctx) // n.b. this must be released
+func queryRows(ctx context.Context, request *influxdbiox.QueryRequest, location *time.Location, args []interface{}) (*rows, error) {
+	flightReader, err := request.Query(ctx, args...) // n.b. this must be released
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +41,7 @@ func queryRows(ctx context.Context, request *influxdbiox.QueryRequest, _argsRese
 	return &rows{
 		flightReader: flightReader,
 		fields:       flightReader.Schema().Fields(),
+		location:     location,
 	}, nil
 }
 
@@ -85,7 +86,7 @@ func (r *rows) Next(dest []driver.Value) error {
 
 	for i := 0; i < int(r.record.NumCols()); i++ {
 		col := r.record.Column(i)
-		value, err := driverValueFromArrowColumn(col, r.rowI)
+		value, err := driverValueFromArrowColumn(col, r.rowI, r.location)
 		if err != nil {
 			_ = r.Close()
 			return err
@@ -97,72 +98,109 @@ func (r *rows) Next(dest []driver.Value) error {
 	return nil
 }
 
-func driverValueFromArrowColumn(column arrow.Array, row int) (driver.Value, error) {
-	if column.IsNull(row) {
-		return nil, nil
-	}
-	switch typedColumn := column.(type) {
-	case *array.Timestamp:
-		return time.Unix(0, int64(typedColumn.Value(row))), nil
-	case *array.Float64:
-		return typedColumn.Value(row), nil
-	case *array.Uint64:
-		return typedColumn.Value(row), nil
-	case *array.Int64:
-		return typedColumn.Value(row), nil
-	case *array.String:
-		return typedColumn.Value(row), nil
-	case *array.Binary:
-		return typedColumn.Value(row), nil
-	case *array.Boolean:
-		return typedColumn.Value(row), nil
+// driverValueFromArrowColumn returns the value at row of column, as the Go
+// type given for the column's data type by scanType. Timestamps are
+// presented in location, unless it is nil; see
+// influxdbiox.ValueFromArrowColumnInLocation.
+//
+// Values that are not valid driver values are converted: unsigned integers
+// to int64, or to decimal strings if they overflow it, durations to int64
+// nanoseconds, and lists to JSON arrays.
+func driverValueFromArrowColumn(column arrow.Array, row int, location *time.Location) (driver.Value, error) {
+	value, err := influxdbiox.ValueFromArrowColumnInLocation(column, row, location)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10), nil
+		}
+		return int64(v), nil
+	case time.Duration:
+		return int64(v), nil
+	case []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
 	default:
-		return nil, fmt.Errorf("unsupported arrow type %q", column.DataType().Name())
+		return value, nil
 	}
 }
 
-func (r *rows) ColumnTypeScanType(index int) reflect.Type {
-	if index >= len(r.fields) {
-		return nil
-	}
-	switch r.fields[index].Type.ID() {
-	case arrow.TIMESTAMP:
-		return reflect.TypeOf(time.Time{})
-	case arrow.FLOAT32:
-		return reflect.TypeOf(float32(0))
-	case arrow.DECIMAL, arrow.FLOAT64:
-		return reflect.TypeOf(float64(0))
-	case arrow.UINT64:
-		return reflect.TypeOf(uint64(0))
-	case arrow.INT64:
-		return reflect.TypeOf(int64(0))
-	case arrow.STRING:
-		return reflect.TypeOf("")
-	case arrow.BINARY:
-		return reflect.TypeOf([]byte(nil))
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	float64Type = reflect.TypeOf(float64(0))
+	uint64Type  = reflect.TypeOf(uint64(0))
+	int64Type   = reflect.TypeOf(int64(0))
+	stringType  = reflect.TypeOf("")
+	bytesType   = reflect.TypeOf([]byte(nil))
+	boolType    = reflect.TypeOf(true)
+)
+
+// scanType returns the Go type of the values of dataType returned by
+// driverValueFromArrowColumn, or nil if the data type is not supported.
+func scanType(dataType arrow.DataType) reflect.Type {
+	switch dataType.ID() {
+	case arrow.TIMESTAMP, arrow.DATE32, arrow.DATE64:
+		return timeType
+	case arrow.FLOAT16, arrow.FLOAT32, arrow.FLOAT64:
+		return float64Type
+	case arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64:
+		return uint64Type
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64, arrow.TIME32, arrow.TIME64, arrow.DURATION:
+		return int64Type
+	case arrow.STRING, arrow.LARGE_STRING, arrow.DECIMAL128, arrow.DECIMAL256, arrow.LIST, arrow.LARGE_LIST, arrow.FIXED_SIZE_LIST:
+		return stringType
+	case arrow.BINARY, arrow.LARGE_BINARY, arrow.FIXED_SIZE_BINARY:
+		return bytesType
 	case arrow.BOOL:
-		return reflect.TypeOf(true)
+		return boolType
+	case arrow.DICTIONARY:
+		return scanType(dataType.(*arrow.DictionaryType).ValueType)
 	default:
 		return nil
 	}
 }
 
+// valueType returns dataType, or the value type of a dictionary, which
+// describes the values returned for a column.
+func valueType(dataType arrow.DataType) arrow.DataType {
+	if dictionaryType, ok := dataType.(*arrow.DictionaryType); ok {
+		return dictionaryType.ValueType
+	}
+	return dataType
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if index >= len(r.fields) {
+		return nil
+	}
+	return scanType(r.fields[index].Type)
+}
+
+// ColumnTypeDatabaseTypeName returns the name of the Arrow type ID of the
+// column, such as "INT64", or of its value type if it is dictionary-encoded.
 func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
 	if index >= len(r.fields) {
 		return ""
 	}
-	return r.fields[index].Type.ID().String()
+	return valueType(r.fields[index].Type).ID().String()
 }
 
+// ColumnTypeLength returns the byte width of fixed size binary columns, and
+// math.MaxInt64 for other string and binary columns.
 func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
 	if index >= len(r.fields) {
 		return 0, false
 	}
-	switch r.fields[index].Type.ID() {
-	case arrow.TIMESTAMP, arrow.FLOAT64, arrow.UINT64, arrow.INT64, arrow.BOOL:
-		return 0, false
-	case arrow.STRING, arrow.BINARY:
+	switch dataType := valueType(r.fields[index].Type); dataType.ID() {
+	case arrow.STRING, arrow.LARGE_STRING, arrow.BINARY, arrow.LARGE_BINARY:
 		return math.MaxInt64, true
+	case arrow.FIXED_SIZE_BINARY:
+		return int64(dataType.(*arrow.FixedSizeBinaryType).ByteWidth), true
 	default:
 		return 0, false
 	}
@@ -175,6 +213,18 @@ func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
 	return r.fields[index].Nullable, true
 }
 
+// ColumnTypePrecisionScale returns the precision and scale of decimal
+// columns.
 func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
-	return 0, 0, false
+	if index >= len(r.fields) {
+		return 0, 0, false
+	}
+	switch dataType := valueType(r.fields[index].Type).(type) {
+	case *arrow.Decimal128Type:
+		return int64(dataType.Precision), int64(dataType.Scale), true
+	case *arrow.Decimal256Type:
+		return int64(dataType.Precision), int64(dataType.Scale), true
+	default:
+		return 0, 0, false
+	}
 }
@@ -3,23 +3,27 @@ package ioxsql
 import (
 	"context"
 	"database/sql/driver"
-	"errors"
 
 	"github.com/influxdata/influxdb-iox-client-go/v2"
 )
 
 var (
-	_ driver.Stmt             = (*statement)(nil)
-	_ driver.StmtExecContext  = (*statement)(nil)
-	_ driver.StmtQueryContext = (*statement)(nil)
+	_ driver.Stmt              = (*statement)(nil)
+	_ driver.StmtExecContext   = (*statement)(nil)
+	_ driver.StmtQueryContext  = (*statement)(nil)
+	_ driver.NamedValueChecker = (*statement)(nil)
 )
 
 type statement struct {
+	conn    *Connection
+	query   string
 	request *influxdbiox.QueryRequest
 }
 
-func newStatement(request *influxdbiox.QueryRequest) *statement {
+func newStatement(conn *Connection, query string, request *influxdbiox.QueryRequest) *statement {
 	return &statement{
+		conn:    conn,
+		query:   query,
 		request: request,
 	}
 }
@@ -28,28 +32,64 @@ func (s *statement) Close() error {
 	return nil
 }
 
+// NumInput returns the number of placeholders in an INSERT statement.
+// Otherwise the statement may be line protocol, in which $ is not a
+// placeholder, so -1 is returned and the arguments of a query are checked
+// when they are bound instead.
 func (s *statement) NumInput() int {
-	return -1
+	if !isInsert(s.query) {
+		return -1
+	}
+	return s.request.NumInput()
+}
+
+// CheckNamedValue accepts the argument types that influxdbiox can bind, but
+// that database/sql would otherwise convert or reject.
+func (s *statement) CheckNamedValue(namedValue *driver.NamedValue) error {
+	switch namedValue.Value.(type) {
+	case uint64, influxdbiox.TypedArg:
+		return nil
+	default:
+		return driver.ErrSkip
+	}
 }
 
 func (s *statement) Exec(args []driver.Value) (driver.Result, error) {
-	return nil, errors.New("exec not implemented")
+	namedArgs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		namedArgs[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return s.ExecContext(context.Background(), namedArgs)
 }
 
+// ExecContext writes line protocol, or the rows of an INSERT statement, to
+// the default namespace; see the package documentation.
 func (s *statement) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	return nil, errors.New("exec not implemented")
+	return execWrite(ctx, s.conn, s.query, args)
 }
 
 func (s *statement) Query(args []driver.Value) (driver.Rows, error) {
-	if len(args) > 0 {
-		return nil, errors.New("query args not supported")
+	queryArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		queryArgs[i] = arg
 	}
-	return queryRows(context.Background(), s.request, nil)
+	return queryRows(context.Background(), s.request, s.conn.location, queryArgs)
 }
 
 func (s *statement) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	if len(args) > 0 {
-		return nil, errors.New("query args not supported")
+	return queryRows(ctx, s.request, s.conn.location, queryArgsFromNamedValues(args))
+}
+
+// queryArgsFromNamedValues converts database/sql arguments to arguments
+// accepted by influxdbiox.QueryRequest.Query.
+func queryArgsFromNamedValues(args []driver.NamedValue) []interface{} {
+	queryArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			queryArgs[i] = influxdbiox.Named(arg.Name, arg.Value)
+		} else {
+			queryArgs[i] = arg.Value
+		}
 	}
-	return queryRows(ctx, s.request, nil)
+	return queryArgs
 }
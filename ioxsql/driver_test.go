@@ -4,25 +4,34 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"io"
 	"math"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/influxdata/line-protocol/v2/lineprotocol"
 
 	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/decimal128"
+	"github.com/apache/arrow/go/v10/arrow/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 
 	"github.com/influxdata/influxdb-iox-client-go/v2"
 	"github.com/influxdata/influxdb-iox-client-go/v2/ioxsql"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
 )
 
 func openNewDatabase(ctx context.Context, t *testing.T) (*sql.DB, *influxdbiox.Client, string) {
@@ -189,24 +198,40 @@ func TestQueryCloseRowsEarly(t *testing.T) {
 	require.NoError(t, stmt.Close())
 }
 
-func TestExecNotSupported(t *testing.T) {
+func TestExecNotLineProtocol(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	db, _, _ := openNewDatabase(ctx, t)
 	_, err := db.Exec("create table t(a varchar not null)")
-	require.EqualError(t, err, "exec not implemented")
+	require.ErrorContains(t, err, "invalid line protocol")
 }
 
-func TestArgsNotSupported(t *testing.T) {
+func TestQueryArgs(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	db, _, _ := openNewDatabase(ctx, t)
+	db, client, writeURL := openNewDatabase(ctx, t)
+	writeDataset(ctx, t, client, writeURL)
 
-	_, err := db.Query("select v from t where k = $1", "arg")
-	assert.EqualError(t, err, "query args not supported")
+	var n int64
+	err := db.QueryRowContext(ctx, "select count(*) from t where foo = $1 and v >= $2", "bar", 5).Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, n)
+
+	err = db.QueryRowContext(ctx, "select count(*) from t where foo = $foo", sql.Named("foo", "bar' or 'a' = 'a")).Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	stmt := prepareStmt(t, db, "select v from t where v = $1")
+	rows := queryStmt(t, stmt, 3)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&n))
+	assert.EqualValues(t, 3, n)
 
 	_, err = db.Query("select v from t where k = ?", "arg")
-	assert.EqualError(t, err, "query args not supported")
+	assert.EqualError(t, err, "failed to bind query arguments: query has no placeholders but 1 arguments were provided")
+
+	_, err = db.Query("select v from t where k = $1 and j = $2", "arg")
+	assert.EqualError(t, err, "failed to bind query arguments: no argument provided for placeholder $2")
 }
 
 func TestConnQueryNull(t *testing.T) {
@@ -453,7 +478,7 @@ func TestRowsColumnTypes(t *testing.T) {
 				Len: 0,
 				OK:  false,
 			},
-			ScanType: reflect.TypeOf(float64(0)),
+			ScanType: reflect.TypeOf(""),
 		}, {
 			Name:     "d",
 			TypeName: arrow.TIMESTAMP.String(),
@@ -532,3 +557,494 @@ func TestQueryLifeCycle(t *testing.T) {
 	err = rows.Close()
 	require.NoError(t, err)
 }
+
+func TestInfluxQLQuery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, client, writeURL := openNewDatabase(ctx, t)
+	writeDataset(ctx, t, client, writeURL)
+
+	config := *client.Config()
+	config.QueryType = influxdbiox.QueryTypeInfluxQL
+	dsn, err := config.ToJSONString()
+	require.NoError(t, err)
+	db, err := sql.Open(ioxsql.DriverName, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	rows, err := db.QueryContext(ctx, `SELECT "v" FROM "t" WHERE "foo" = $foo`, sql.Named("foo", "bar"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = rows.Close() })
+
+	rowCount := 0
+	for rows.Next() {
+		rowCount++
+	}
+	require.NoError(t, rows.Err())
+	assert.EqualValues(t, 10, rowCount)
+}
+
+func TestOpenURL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	db, err := sql.Open(ioxsql.DriverName, "iox://mytoken@localhost:8082/mydb?query_type=influxql&timeout=10s&allow_insecure_token=true")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	err = conn.Raw(func(driverConn interface{}) error {
+		config := driverConn.(*ioxsql.Connection).Client().Config()
+		assert.Equal(t, "localhost:8082", config.Address)
+		assert.Equal(t, "mydb", config.Namespace)
+		assert.Equal(t, "mytoken", config.Token)
+		assert.True(t, config.AllowInsecureToken)
+		assert.Equal(t, influxdbiox.QueryTypeInfluxQL, config.QueryType)
+		assert.Equal(t, 10*time.Second, config.Timeout)
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = (&ioxsql.Driver{}).OpenConnector("iox://localhost:8082?bogus=1")
+	assert.ErrorContains(t, err, `parameter "bogus": unknown parameter`)
+}
+
+func TestOpenAddress(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for dsn, expect := range map[string]*influxdbiox.ClientConfig{
+		"dns:///localhost:8082/mydb": {
+			Address:   "dns:///localhost:8082",
+			Namespace: "mydb",
+		},
+		"localhost:8082,dns:///localhost:8083/mydb": {
+			Address:   "localhost:8082",
+			Addresses: []string{"dns:///localhost:8083"},
+			Namespace: "mydb",
+		},
+	} {
+		db, err := sql.Open(ioxsql.DriverName, dsn)
+		require.NoError(t, err, dsn)
+		t.Cleanup(func() { _ = db.Close() })
+
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err, dsn)
+		t.Cleanup(func() { _ = conn.Close() })
+		err = conn.Raw(func(driverConn interface{}) error {
+			config := driverConn.(*ioxsql.Connection).Client().Config()
+			assert.Equal(t, expect.Address, config.Address, dsn)
+			assert.Equal(t, expect.Addresses, config.Addresses, dsn)
+			assert.Equal(t, expect.Namespace, config.Namespace, dsn)
+			return nil
+		})
+		require.NoError(t, err, dsn)
+	}
+}
+
+func TestConnector_loadBalancing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	b := array.NewInt64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.Append(1)
+	column := b.NewArray()
+	defer column.Release()
+	record := array.NewRecord(schema, []arrow.Array{column}, 1)
+	defer record.Release()
+
+	servers := map[string]*ioxtest.Server{}
+	for _, address := range []string{"iox-1:8082", "iox-2:8082"} {
+		server := ioxtest.NewServer()
+		t.Cleanup(server.Close)
+		record.Retain()
+		server.HandleDefault(record)
+		servers[address] = server
+	}
+	dialer := func(ctx context.Context, address string) (net.Conn, error) {
+		return servers[address].DialContext(ctx, address)
+	}
+
+	config, err := influxdbiox.ClientConfigFromString("iox://iox-1,iox-2/mydb")
+	require.NoError(t, err)
+	config.DialOptions = []grpc.DialOption{grpc.WithContextDialer(dialer)}
+	db := sql.OpenDB(ioxsql.NewConnector(config))
+	t.Cleanup(func() { _ = db.Close() })
+
+	for i := 0; i < 4; i++ {
+		var v int64
+		require.NoError(t, db.QueryRowContext(ctx, "select v from t").Scan(&v))
+		assert.EqualValues(t, 1, v)
+	}
+	for address, server := range servers {
+		assert.Len(t, server.Queries(), 2, address)
+	}
+}
+
+func TestRowsArrowTypes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "tag", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}, Nullable: true},
+		{Name: "i32", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "u32", Type: arrow.PrimitiveTypes.Uint32},
+		{Name: "f32", Type: arrow.PrimitiveTypes.Float32},
+		{Name: "date", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+		{Name: "dur", Type: &arrow.DurationType{Unit: arrow.Microsecond}},
+		{Name: "tod", Type: arrow.FixedWidthTypes.Time64ns},
+		{Name: "large", Type: arrow.BinaryTypes.LargeString},
+		{Name: "fixed", Type: &arrow.FixedSizeBinaryType{ByteWidth: 2}},
+		{Name: "dec", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}},
+		{Name: "list", Type: arrow.ListOf(arrow.PrimitiveTypes.Int64), Nullable: true},
+		{Name: "pair", Type: arrow.FixedSizeListOf(2, arrow.BinaryTypes.String)},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	tag := b.Field(0).(*array.BinaryDictionaryBuilder)
+	require.NoError(t, tag.AppendString("server-a"))
+	tag.AppendNull()
+	b.Field(1).(*array.Int32Builder).AppendValues([]int32{-1, 2}, nil)
+	b.Field(2).(*array.Uint32Builder).AppendValues([]uint32{3, 4}, nil)
+	b.Field(3).(*array.Float32Builder).AppendValues([]float32{0.5, 1.5}, nil)
+	b.Field(4).(*array.Date32Builder).AppendValues([]arrow.Date32{18732, 0}, nil)
+	b.Field(5).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1618444800123, 0}, nil)
+	b.Field(6).(*array.DurationBuilder).AppendValues([]arrow.Duration{1500000, 0}, nil)
+	b.Field(7).(*array.Time64Builder).AppendValues([]arrow.Time64{arrow.Time64(time.Hour + time.Second), 0}, nil)
+	b.Field(8).(*array.LargeStringBuilder).AppendValues([]string{"large", ""}, nil)
+	b.Field(9).(*array.FixedSizeBinaryBuilder).AppendValues([][]byte{{0xbe, 0xef}, {0, 1}}, nil)
+	b.Field(10).(*array.Decimal128Builder).AppendValues([]decimal128.Num{decimal128.FromI64(12345), decimal128.FromI64(-1)}, nil)
+	list := b.Field(11).(*array.ListBuilder)
+	list.Append(true)
+	list.ValueBuilder().(*array.Int64Builder).AppendValues([]int64{1, 2}, []bool{true, false})
+	list.AppendNull()
+	pair := b.Field(12).(*array.FixedSizeListBuilder)
+	pair.AppendValues([]bool{true, true})
+	pair.ValueBuilder().(*array.StringBuilder).AppendValues([]string{"a", "b", "c", "d"}, nil)
+	record := b.NewRecord()
+	defer record.Release()
+
+	server := ioxtest.NewServer()
+	defer server.Close()
+	server.HandleQuery("select * from t", record)
+	db := sql.OpenDB(ioxsql.NewConnector(server.ClientConfig("mydb")))
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "select * from t")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	for i, tt := range []struct {
+		typeName string
+		scanType reflect.Type
+	}{
+		{"STRING", reflect.TypeOf("")},
+		{"INT32", reflect.TypeOf(int64(0))},
+		{"UINT32", reflect.TypeOf(uint64(0))},
+		{"FLOAT32", reflect.TypeOf(float64(0))},
+		{"DATE32", reflect.TypeOf(time.Time{})},
+		{"TIMESTAMP", reflect.TypeOf(time.Time{})},
+		{"DURATION", reflect.TypeOf(int64(0))},
+		{"TIME64", reflect.TypeOf(int64(0))},
+		{"LARGE_STRING", reflect.TypeOf("")},
+		{"FIXED_SIZE_BINARY", reflect.TypeOf([]byte(nil))},
+		{"DECIMAL128", reflect.TypeOf("")},
+		{"LIST", reflect.TypeOf("")},
+		{"FIXED_SIZE_LIST", reflect.TypeOf("")},
+	} {
+		assert.Equal(t, tt.typeName, columnTypes[i].DatabaseTypeName(), columnTypes[i].Name())
+		assert.Equal(t, tt.scanType, columnTypes[i].ScanType(), columnTypes[i].Name())
+	}
+	length, ok := columnTypes[0].Length()
+	assert.True(t, ok)
+	assert.EqualValues(t, math.MaxInt64, length)
+	length, ok = columnTypes[9].Length()
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, length)
+	_, ok = columnTypes[1].Length()
+	assert.False(t, ok)
+	precision, scale, ok := columnTypes[10].DecimalSize()
+	assert.True(t, ok)
+	assert.EqualValues(t, 10, precision)
+	assert.EqualValues(t, 2, scale)
+	_, _, ok = columnTypes[1].DecimalSize()
+	assert.False(t, ok)
+
+	var (
+		tagValue sql.NullString
+		i32      int32
+		u32      uint32
+		f32      float32
+		date     time.Time
+		ts       time.Time
+		dur      time.Duration
+		tod      time.Duration
+		large    string
+		fixed    []byte
+		dec      string
+		listV    sql.NullString
+		pairV    string
+	)
+	dest := []interface{}{&tagValue, &i32, &u32, &f32, &date, &ts, &dur, &tod, &large, &fixed, &dec, &listV, &pairV}
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(dest...))
+	assert.Equal(t, sql.NullString{String: "server-a", Valid: true}, tagValue)
+	assert.EqualValues(t, -1, i32)
+	assert.EqualValues(t, 3, u32)
+	assert.EqualValues(t, 0.5, f32)
+	assert.True(t, date.Equal(time.Date(2021, 4, 15, 0, 0, 0, 0, time.UTC)), date)
+	assert.True(t, ts.Equal(time.Date(2021, 4, 15, 0, 0, 0, 123000000, time.UTC)), ts)
+	assert.Equal(t, 1500*time.Millisecond, dur)
+	assert.Equal(t, time.Hour+time.Second, tod)
+	assert.Equal(t, "large", large)
+	assert.Equal(t, []byte{0xbe, 0xef}, fixed)
+	assert.Equal(t, "123.45", dec)
+	assert.Equal(t, sql.NullString{String: "[1,null]", Valid: true}, listV)
+	assert.Equal(t, `["a","b"]`, pairV)
+
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(dest...))
+	assert.False(t, tagValue.Valid)
+	assert.Equal(t, "-0.01", dec)
+	assert.False(t, listV.Valid)
+	assert.Equal(t, `["c","d"]`, pairV)
+	assert.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+
+	// Scanners receive the values returned by the driver unconverted.
+	rows, err = db.QueryContext(ctx, "select * from t")
+	require.NoError(t, err)
+	defer rows.Close()
+	values := make([]driverValue, len(columnTypes))
+	dest = make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		require.NoError(t, rows.Scan(dest...))
+		for i, value := range values {
+			assert.True(t, driver.IsValue(value.value), "%s: %T", columnTypes[i].Name(), value.value)
+		}
+	}
+	require.NoError(t, rows.Err())
+
+	// Decimals also scan into float64.
+	var decFloat float64
+	dest[10] = &decFloat
+	require.NoError(t, db.QueryRowContext(ctx, "select * from t").Scan(dest...))
+	assert.Equal(t, 123.45, decFloat)
+}
+
+// driverValue is a sql.Scanner that records the value it is given.
+type driverValue struct {
+	value interface{}
+}
+
+func (v *driverValue) Scan(value interface{}) error {
+	v.value = value
+	return nil
+}
+
+func TestRowsTimestampLocation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "s", Type: &arrow.TimestampType{Unit: arrow.Second}},
+		{Name: "ms", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "America/New_York"}},
+		{Name: "us", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "+05:30"}},
+		{Name: "ns", Type: &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	// Seconds beyond the range of int64 nanoseconds, in the year 3000.
+	b.Field(0).(*array.TimestampBuilder).Append(32503680000)
+	b.Field(1).(*array.TimestampBuilder).Append(1618444800123)
+	b.Field(2).(*array.TimestampBuilder).Append(1618444800000456)
+	b.Field(3).(*array.TimestampBuilder).Append(1618444800000000789)
+	record := b.NewRecord()
+	defer record.Release()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	expect := []time.Time{
+		time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 4, 15, 0, 0, 0, 123000000, time.UTC),
+		time.Date(2021, 4, 15, 0, 0, 0, 456000, time.UTC),
+		time.Date(2021, 4, 15, 0, 0, 0, 789, time.UTC),
+	}
+
+	for _, tt := range []struct {
+		location  string
+		locations []*time.Location
+	}{
+		{"", []*time.Location{time.UTC, newYork, time.FixedZone("+05:30", 5*60*60+30*60), time.UTC}},
+		{"Asia/Tokyo", []*time.Location{tokyo, tokyo, tokyo, tokyo}},
+		{"Local", []*time.Location{time.Local, time.Local, time.Local, time.Local}},
+	} {
+		server := ioxtest.NewServer()
+		t.Cleanup(server.Close)
+		record.Retain()
+		server.HandleDefault(record)
+		config := server.ClientConfig("mydb")
+		config.Location = tt.location
+		db := sql.OpenDB(ioxsql.NewConnector(config))
+		t.Cleanup(func() { _ = db.Close() })
+
+		values := make([]time.Time, 4)
+		require.NoError(t, db.QueryRowContext(ctx, "select * from t").Scan(&values[0], &values[1], &values[2], &values[3]))
+		for i, value := range values {
+			assert.True(t, value.Equal(expect[i]), "%s: %s", tt.location, value)
+			assert.Equal(t, tt.locations[i].String(), value.Location().String(), "%s: %s", tt.location, value)
+			_, offset := value.Zone()
+			_, expectOffset := value.In(tt.locations[i]).Zone()
+			assert.Equal(t, expectOffset, offset, "%s: %s", tt.location, value)
+		}
+	}
+
+	db := sql.OpenDB(ioxsql.NewConnector(&influxdbiox.ClientConfig{Address: "localhost:8082", Location: "Mars/Olympus"}))
+	defer db.Close()
+	assert.ErrorContains(t, db.PingContext(ctx), "invalid location: unknown time zone Mars/Olympus")
+}
+
+// newExecTestDB opens a database whose writes are appended to the returned
+// slice, which must not be read until the writes return.
+func newExecTestDB(t *testing.T) (*sql.DB, *[]string) {
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddTable("myorg_mybucket", "cpu", map[string]influxdbiox.ColumnType{
+		"host":  influxdbiox.ColumnType_TAG,
+		"usage": influxdbiox.ColumnType_F64,
+		"count": influxdbiox.ColumnType_I64,
+		"total": influxdbiox.ColumnType_U64,
+		"ok":    influxdbiox.ColumnType_BOOL,
+		"note":  influxdbiox.ColumnType_STRING,
+		"time":  influxdbiox.ColumnType_TIME,
+	})
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	writeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		w.Header().Set("X-IOx-Write-Token", fmt.Sprintf("token-%d", len(bodies)))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(writeServer.Close)
+
+	config := server.ClientConfig("myorg_mybucket")
+	config.WriteURL = writeServer.URL
+	db := sql.OpenDB(ioxsql.NewConnector(config))
+	t.Cleanup(func() { _ = db.Close() })
+	return db, &bodies
+}
+
+func TestExec(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db, bodies := newExecTestDB(t)
+
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	writeToken := func() string {
+		var token string
+		require.NoError(t, conn.Raw(func(driverConn interface{}) error {
+			token = driverConn.(*ioxsql.Connection).WriteToken()
+			return nil
+		}))
+		return token
+	}
+	assert.Empty(t, writeToken())
+
+	result, err := conn.ExecContext(ctx, "# comment\ncpu,host=a usage=0.5 1\n\ncpu,host=b usage=1.5 2\n")
+	require.NoError(t, err)
+	n, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	_, err = result.LastInsertId()
+	assert.Error(t, err)
+	assert.Equal(t, "token-1", writeToken())
+
+	result, err = conn.ExecContext(ctx, `INSERT INTO cpu (host, "usage", count, total, ok, note, time) VALUES
+		('a', 0.5, -1, 18446744073709551615, true, 'it''s', '2021-04-15T00:00:00Z'),
+		($1, $2, NULL, 2, FALSE, NULL, $3); -- comment`, "b", 1, time.Unix(0, 1618444800000000001))
+	require.NoError(t, err)
+	n, err = result.RowsAffected()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	assert.Equal(t, "token-2", writeToken())
+
+	_, err = db.ExecContext(ctx, "insert into CPU (Host, Usage) values ($host, $usage)",
+		sql.Named("host", "c"), sql.Named("usage", influxdbiox.Typed(influxdbiox.ColumnType_F64, 2.5)))
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, "insert into cpu (host, total, time) values ($1, $2, $3)",
+		"d", influxdbiox.Typed(influxdbiox.ColumnType_U64, 3), influxdbiox.Typed(influxdbiox.ColumnType_TIME, int32(4)))
+	require.NoError(t, err)
+
+	// Line protocol whose measurement is insert.
+	_, err = db.ExecContext(ctx, "insert,host=a v=1 5\nINSERT v=1 6\ninsert into=1 7")
+	require.NoError(t, err)
+
+	// Line protocol in which $ is not a placeholder.
+	_, err = db.ExecContext(ctx, "prices,sym=$USD v=1 8")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"# comment\ncpu,host=a usage=0.5 1\n\ncpu,host=b usage=1.5 2\n",
+		"cpu,host=a count=-1i,note=\"it's\",ok=true,total=18446744073709551615u,usage=0.5 1618444800000000000\n" +
+			"cpu,host=b ok=false,total=2u,usage=1 1618444800000000001\n",
+		"cpu,host=c usage=2.5\n",
+		"cpu,host=d total=3u 4\n",
+		"insert,host=a v=1 5\nINSERT v=1 6\ninsert into=1 7",
+		"prices,sym=$USD v=1 8",
+	}, *bodies)
+}
+
+func TestExec_errors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db, bodies := newExecTestDB(t)
+
+	for query, expectErr := range map[string]string{
+		"cpu usage":                                            "invalid line protocol",
+		"insert cpu (usage) values (1)":                        "invalid line protocol",
+		"insert into cpu values (1)":                           "invalid INSERT statement: expected (, got \"values\"; a column list is required",
+		"insert into cpu (usage, usage) values (1, 2)":         `invalid INSERT statement: column "usage" is listed more than once`,
+		"insert into cpu (usage) values (1, 2)":                "invalid INSERT statement: row 1 has 2 values, but 1 columns are listed",
+		"insert into cpu (usage) values (1) (2)":               `invalid INSERT statement: unexpected "(" after VALUES`,
+		"insert into cpu (usage) values ('1)":                  "invalid INSERT statement: unterminated quoted string",
+		"insert into cpu (usage) values (now())":               `invalid INSERT statement: expected a literal value or placeholder, got "now"`,
+		"insert into mem (usage) values (1)":                   `failed to get schema of table "mem": table not found`,
+		"insert into cpu (usage, idle) values (1, 2)":          `column "idle" not found in table "cpu"`,
+		"insert into cpu (host) values ('a')":                  "row 1 has no non-NULL field values",
+		"insert into cpu (usage) values ('high')":              `row 1, column "usage": cannot insert string into a float64 column`,
+		"insert into cpu (total) values (-1)":                  `row 1, column "total": value -1 overflows uint64`,
+		"insert into cpu (usage, time) values (1, 'today')":    `row 1, column "time": invalid timestamp`,
+		"insert into cpu (count) values (9223372036854775808)": `row 1, column "count": value 9223372036854775808 overflows int64`,
+	} {
+		_, err := db.ExecContext(ctx, query)
+		assert.ErrorContains(t, err, expectErr, query)
+	}
+
+	_, err := db.ExecContext(ctx, "insert into cpu (usage) values ($2)", 1, 2)
+	assert.ErrorContains(t, err, "argument 1 is not referenced by any placeholder")
+	_, err = db.ExecContext(ctx, "insert into cpu (usage) values ($x)", sql.Named("y", 1))
+	assert.ErrorContains(t, err, "no argument provided for placeholder $x")
+	assert.Empty(t, *bodies)
+}
@@ -0,0 +1,678 @@
+// Package ioxtest provides an in-process fake InfluxDB/IOx server, for
+// testing code that uses influxdbiox.Client without a running IOx.
+//
+// The fake serves the gRPC APIs used by influxdbiox.Client over an in-memory
+// listener:
+//
+//   - Arrow Flight Handshake, and DoGet with the IOx JSON ticket, answering
+//     each query with Arrow records registered for the exact query string
+//   - Arrow Flight DoGet with an ingester query ticket, answering with the
+//     partitions registered for the table with Server.HandleIngesterQuery
+//   - the IOx SchemaService, backed by tables added with Server.AddTable
+//   - the IOx WriteInfoService, replaying shard status progressions set with
+//     Server.SetWriteInfo
+//
+// Every API can be made to require a bearer token with Server.RequireToken,
+// and to fail with Server.InjectFailure.
+//
+// Example:
+//
+//	server := ioxtest.NewServer()
+//	defer server.Close()
+//	server.HandleQuery("select * from t", record)
+//	client, err := influxdbiox.NewClient(ctx, server.ClientConfig("mydb"))
+package ioxtest
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	ingester "github.com/influxdata/influxdb-iox-client-go/v2/internal/ingester"
+	schema "github.com/influxdata/influxdb-iox-client-go/v2/internal/schema"
+)
+
+// ShardStatus is the status of a write on one shard, as reported by the
+// WriteInfoService.
+type ShardStatus int32
+
+const (
+	// ShardStatusUnspecified is an invalid status.
+	ShardStatusUnspecified ShardStatus = ShardStatus(ingester.ShardStatus_SHARD_STATUS_UNSPECIFIED)
+	// ShardStatusDurable means the write is in the write-ahead log.
+	ShardStatusDurable ShardStatus = ShardStatus(ingester.ShardStatus_SHARD_STATUS_DURABLE)
+	// ShardStatusReadable means the write is durable and can be queried.
+	ShardStatusReadable ShardStatus = ShardStatus(ingester.ShardStatus_SHARD_STATUS_READABLE)
+	// ShardStatusPersisted means the write is readable and persisted.
+	ShardStatusPersisted ShardStatus = ShardStatus(ingester.ShardStatus_SHARD_STATUS_PERSISTED)
+	// ShardStatusUnknown means the ingester has no information about the write.
+	ShardStatusUnknown ShardStatus = ShardStatus(ingester.ShardStatus_SHARD_STATUS_UNKNOWN)
+)
+
+// Query is a query received by the Server.
+type Query struct {
+	Namespace string
+	Query     string
+	QueryType influxdbiox.QueryType
+	// W3C trace context header of the request, if any, such as
+	// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	TraceParent string
+}
+
+// IngesterPartition is the unpersisted data of a partition, returned by the
+// Server in response to influxdbiox.Client.QueryIngester.
+type IngesterPartition struct {
+	// ID is the partition ID.
+	ID int64
+	// ParquetMaxSequenceNumber is reported in the partition status.
+	ParquetMaxSequenceNumber *int64
+	// Records are the buffered rows of the partition. They must have the
+	// same schema as the records of every other partition of the table.
+	Records []arrow.Record
+}
+
+// Server is a fake IOx gRPC server. Construct one with NewServer.
+// Server is safe for concurrent use.
+type Server struct {
+	listener     *bufconn.Listener
+	flightServer flight.Server
+
+	mu              sync.Mutex
+	queryRecords    map[string][]arrow.Record
+	defaultRecords  []arrow.Record
+	queries         []Query
+	ingesterTables  map[int64][]IngesterPartition
+	ingesterQueries []influxdbiox.IngesterQuery
+	namespaces      map[string]*namespace
+	nextID          int64
+	writeInfos      map[string]*writeInfo
+	token           string
+	failures        []*Failure
+}
+
+type namespace struct {
+	id          int64
+	retention   time.Duration
+	tables      map[string]*table
+	queryPoolID int64
+	topicID     int64
+}
+
+type table struct {
+	id      int64
+	columns map[string]*column
+}
+
+type column struct {
+	id         int64
+	columnType influxdbiox.ColumnType
+}
+
+type writeInfo struct {
+	steps [][]ShardStatus
+	next  int
+}
+
+// NewServer starts a Server. The caller must call Server.Close when done.
+func NewServer() *Server {
+	s := &Server{
+		listener:       bufconn.Listen(1 << 20),
+		queryRecords:   make(map[string][]arrow.Record),
+		ingesterTables: make(map[int64][]IngesterPartition),
+		namespaces:     make(map[string]*namespace),
+		writeInfos:     make(map[string]*writeInfo),
+	}
+	s.flightServer = flight.NewServerWithMiddleware([]flight.ServerMiddleware{{
+		Unary: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+			if failure := s.takeFailure(info.FullMethod); failure != nil {
+				return nil, failure.Err
+			}
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authorize(stream.Context()); err != nil {
+				return err
+			}
+			if failure := s.takeFailure(info.FullMethod); failure != nil {
+				if failure.AfterMessages <= 0 {
+					return failure.Err
+				}
+				_ = handler(srv, &failingServerStream{ServerStream: stream, remaining: failure.AfterMessages, err: failure.Err})
+				return failure.Err
+			}
+			return handler(srv, stream)
+		},
+	}})
+	s.flightServer.RegisterFlightService(&flightService{server: s})
+	schema.RegisterSchemaServiceServer(s.flightServer, &schemaService{server: s})
+	ingester.RegisterWriteInfoServiceServer(s.flightServer, &writeInfoService{server: s})
+	s.flightServer.InitListener(s.listener)
+	go func() { _ = s.flightServer.Serve() }()
+	return s
+}
+
+// Close stops the Server and releases all registered records.
+func (s *Server) Close() {
+	s.flightServer.Shutdown()
+	_ = s.listener.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for query, records := range s.queryRecords {
+		releaseRecords(records)
+		delete(s.queryRecords, query)
+	}
+	releaseRecords(s.defaultRecords)
+	s.defaultRecords = nil
+	for tableID, partitions := range s.ingesterTables {
+		for _, partition := range partitions {
+			releaseRecords(partition.Records)
+		}
+		delete(s.ingesterTables, tableID)
+	}
+}
+
+// DialContext connects to the Server. It can be used with
+// grpc.WithContextDialer.
+func (s *Server) DialContext(ctx context.Context, _ string) (net.Conn, error) {
+	return s.listener.DialContext(ctx)
+}
+
+// ClientConfig returns a new *influxdbiox.ClientConfig that connects to the
+// Server, with the given default namespace. The connection is in memory, so
+// tokens are allowed without TLS.
+func (s *Server) ClientConfig(namespace string) *influxdbiox.ClientConfig {
+	return &influxdbiox.ClientConfig{
+		Address:            "bufnet",
+		Namespace:          namespace,
+		DialOptions:        []grpc.DialOption{grpc.WithContextDialer(s.DialContext)},
+		AllowInsecureToken: true,
+	}
+}
+
+// RequireToken makes every request fail with codes.Unauthenticated unless it
+// has the header "authorization: Bearer <token>". An empty token disables
+// authorization.
+func (s *Server) RequireToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+func (s *Server) authorize(ctx context.Context) error {
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+	if token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if authorization == "Bearer "+token {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+}
+
+// Failure describes calls that fail, for Server.InjectFailure.
+type Failure struct {
+	// Method is the gRPC method name, without the service name, such as
+	// "DoGet", "GetSchema" or "GetWriteInfo".
+	Method string
+	// Err is returned by each failing call, such as
+	// status.Error(codes.Unavailable, "querier restarting").
+	Err error
+	// Count is the number of calls that fail; later calls succeed.
+	Count int
+	// AfterMessages is the number of messages a streaming call sends before
+	// it fails. The first message of a DoGet stream is the schema, so
+	// AfterMessages 1 fails a query before any record batch is sent.
+	AfterMessages int
+}
+
+// InjectFailure makes the next calls to failure.Method fail with
+// failure.Err, after those of failures injected previously for the same
+// method. Queries that fail before sending a message are not recorded by
+// Server.Queries.
+func (s *Server) InjectFailure(failure Failure) {
+	if failure.Count <= 0 {
+		panic("ioxtest: InjectFailure requires a positive Count")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure)
+}
+
+// takeFailure returns the next failure injected for fullMethod, if any.
+func (s *Server) takeFailure(fullMethod string) *Failure {
+	method := path.Base(fullMethod)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, failure := range s.failures {
+		if failure.Method != method {
+			continue
+		}
+		if failure.Count--; failure.Count == 0 {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+		}
+		return failure
+	}
+	return nil
+}
+
+// failingServerStream fails after sending remaining messages.
+type failingServerStream struct {
+	grpc.ServerStream
+	remaining int
+	err       error
+}
+
+func (s *failingServerStream) SendMsg(m interface{}) error {
+	if s.remaining == 0 {
+		return s.err
+	}
+	s.remaining--
+	return s.ServerStream.SendMsg(m)
+}
+
+// HandleQuery registers records as the result of query, which is matched
+// exactly, in any namespace. All records must have the same schema, and at
+// least one record is required. The records are retained until Close.
+func (s *Server) HandleQuery(query string, records ...arrow.Record) {
+	if len(records) == 0 {
+		panic("ioxtest: HandleQuery requires at least one record")
+	}
+	retainRecords(records)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	releaseRecords(s.queryRecords[query])
+	s.queryRecords[query] = records
+}
+
+// HandleDefault registers records as the result of every query without
+// records registered by HandleQuery. Without default records, such queries
+// fail with codes.InvalidArgument.
+func (s *Server) HandleDefault(records ...arrow.Record) {
+	if len(records) == 0 {
+		panic("ioxtest: HandleDefault requires at least one record")
+	}
+	retainRecords(records)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	releaseRecords(s.defaultRecords)
+	s.defaultRecords = records
+}
+
+// HandleIngesterQuery registers the partitions returned by ingester queries
+// of the table with ID tableID, as given by Server.AddTable. The response
+// has the records of each partition followed by its status; the projection
+// and predicate of the query are ignored. Queries of other tables fail with
+// codes.NotFound. The records are retained until Close.
+func (s *Server) HandleIngesterQuery(tableID int64, partitions ...IngesterPartition) {
+	partitions = append([]IngesterPartition(nil), partitions...)
+	for _, partition := range partitions {
+		retainRecords(partition.Records)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, partition := range s.ingesterTables[tableID] {
+		releaseRecords(partition.Records)
+	}
+	s.ingesterTables[tableID] = partitions
+}
+
+// IngesterQueries returns every ingester query received by the Server, in
+// order.
+func (s *Server) IngesterQueries() []influxdbiox.IngesterQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]influxdbiox.IngesterQuery(nil), s.ingesterQueries...)
+}
+
+// Queries returns every query received by the Server, in order.
+func (s *Server) Queries() []Query {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Query(nil), s.queries...)
+}
+
+// AddNamespace creates a namespace, if it does not exist.
+func (s *Server) AddNamespace(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namespace(name)
+}
+
+// AddTable creates a table in namespace, creating the namespace if it does
+// not exist. Columns are added to the table if it already exists.
+func (s *Server) AddTable(namespace, name string, columns map[string]influxdbiox.ColumnType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns := s.namespace(namespace)
+	t, ok := ns.tables[name]
+	if !ok {
+		s.nextID++
+		t = &table{id: s.nextID, columns: make(map[string]*column)}
+		ns.tables[name] = t
+	}
+	for columnName, columnType := range columns {
+		if c, ok := t.columns[columnName]; ok {
+			c.columnType = columnType
+			continue
+		}
+		s.nextID++
+		t.columns[columnName] = &column{id: s.nextID, columnType: columnType}
+	}
+}
+
+// NamespaceRetention returns the retention period of a namespace, or
+// influxdbiox.InfiniteRetention, and whether the namespace exists.
+func (s *Server) NamespaceRetention(name string) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns, ok := s.namespaces[name]
+	if !ok {
+		return 0, false
+	}
+	if ns.retention == 0 {
+		return influxdbiox.InfiniteRetention, true
+	}
+	return ns.retention, true
+}
+
+// SetWriteInfo scripts the responses to GetWriteInfo for writeToken.
+// Each step lists the status of every shard; the first request receives the
+// first step, the next request the next step, and so on. The last step is
+// repeated once reached.
+func (s *Server) SetWriteInfo(writeToken string, steps ...[]ShardStatus) {
+	if len(steps) == 0 {
+		panic("ioxtest: SetWriteInfo requires at least one step")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeInfos[writeToken] = &writeInfo{steps: steps}
+}
+
+// namespace returns the named namespace, creating it if needed.
+// s.mu must be held.
+func (s *Server) namespace(name string) *namespace {
+	ns, ok := s.namespaces[name]
+	if !ok {
+		s.nextID++
+		ns = &namespace{
+			id:          s.nextID,
+			tables:      make(map[string]*table),
+			queryPoolID: 1,
+			topicID:     1,
+		}
+		s.namespaces[name] = ns
+	}
+	return ns
+}
+
+func retainRecords(records []arrow.Record) {
+	for _, record := range records {
+		record.Retain()
+	}
+}
+
+func releaseRecords(records []arrow.Record) {
+	for _, record := range records {
+		record.Release()
+	}
+}
+
+type flightService struct {
+	flight.BaseFlightServer
+	server *Server
+}
+
+// Handshake echoes each request payload, like IOx.
+func (f *flightService) Handshake(stream flight.FlightService_HandshakeServer) error {
+	for {
+		request, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if err = stream.Send(&flight.HandshakeResponse{Payload: request.Payload}); err != nil {
+			return err
+		}
+	}
+}
+
+// ticketReadInfo mirrors the JSON ticket sent by influxdbiox.QueryRequest.
+type ticketReadInfo struct {
+	NamespaceName string `json:"namespace_name"`
+	SQLQuery      string `json:"sql_query"`
+	QueryType     string `json:"query_type"`
+}
+
+func (f *flightService) DoGet(ticket *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	var traceParent string
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if values := md.Get("traceparent"); len(values) > 0 {
+			traceParent = values[0]
+		}
+	}
+	var readInfo ticketReadInfo
+	if err := json.Unmarshal(ticket.Ticket, &readInfo); err != nil {
+		request := &ingester.IngesterQueryRequest{}
+		if proto.Unmarshal(ticket.Ticket, request) == nil {
+			return f.doGetIngester(request, stream)
+		}
+		return status.Errorf(codes.InvalidArgument, "invalid ticket: %s", err)
+	}
+	queryType, err := influxdbiox.ParseQueryType(readInfo.QueryType)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s := f.server
+	s.mu.Lock()
+	s.queries = append(s.queries, Query{Namespace: readInfo.NamespaceName, Query: readInfo.SQLQuery, QueryType: queryType, TraceParent: traceParent})
+	records, ok := s.queryRecords[readInfo.SQLQuery]
+	if !ok {
+		records = s.defaultRecords
+	}
+	retainRecords(records)
+	s.mu.Unlock()
+	defer releaseRecords(records)
+
+	if len(records) == 0 {
+		return status.Errorf(codes.InvalidArgument, "no records registered for query %q", readInfo.SQLQuery)
+	}
+
+	writer := flight.NewRecordWriter(stream, ipc.WithSchema(records[0].Schema()))
+	for _, record := range records {
+		if err = writer.Write(record); err != nil {
+			_ = writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// doGetIngester answers an ingester query like an IOx ingester.
+func (f *flightService) doGetIngester(request *ingester.IngesterQueryRequest, stream flight.FlightService_DoGetServer) error {
+	query := influxdbiox.IngesterQuery{
+		NamespaceID: request.GetNamespaceId(),
+		TableID:     request.GetTableId(),
+		Columns:     request.GetColumns(),
+	}
+	if p := request.GetPredicate(); p != nil {
+		query.Predicate = &influxdbiox.IngesterPredicate{
+			FieldColumns: p.GetFieldColumns(),
+			Exprs:        p.GetExprs(),
+		}
+		if r := p.GetRange(); r != nil {
+			query.Predicate.TimeRange = &influxdbiox.TimeRange{
+				Start: time.Unix(0, r.GetStart()).UTC(),
+				End:   time.Unix(0, r.GetEnd()).UTC(),
+			}
+		}
+		for _, valueExpr := range p.GetValueExpr() {
+			query.Predicate.ValueExprs = append(query.Predicate.ValueExprs, valueExpr.GetExpr())
+		}
+	}
+
+	s := f.server
+	s.mu.Lock()
+	s.ingesterQueries = append(s.ingesterQueries, query)
+	partitions, ok := s.ingesterTables[query.TableID]
+	for _, partition := range partitions {
+		retainRecords(partition.Records)
+	}
+	s.mu.Unlock()
+	defer func() {
+		for _, partition := range partitions {
+			releaseRecords(partition.Records)
+		}
+	}()
+
+	if !ok {
+		return status.Errorf(codes.NotFound, "table %d not found", query.TableID)
+	}
+
+	var writer *flight.Writer
+	defer func() {
+		if writer != nil {
+			_ = writer.Close()
+		}
+	}()
+	for _, partition := range partitions {
+		metadata, err := proto.Marshal(&ingester.IngesterQueryResponseMetadata{PartitionId: partition.ID})
+		if err != nil {
+			return err
+		}
+		for _, record := range partition.Records {
+			if writer == nil {
+				writer = flight.NewRecordWriter(stream, ipc.WithSchema(record.Schema()))
+			}
+			if err = writer.WriteWithAppMetadata(record, metadata); err != nil {
+				return err
+			}
+		}
+		metadata, err = proto.Marshal(&ingester.IngesterQueryResponseMetadata{
+			PartitionId: partition.ID,
+			Status:      &ingester.PartitionStatus{ParquetMaxSequenceNumber: partition.ParquetMaxSequenceNumber},
+		})
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(&flight.FlightData{AppMetadata: metadata}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type schemaService struct {
+	schema.UnimplementedSchemaServiceServer
+	server *Server
+}
+
+func (ss *schemaService) GetSchema(_ context.Context, request *schema.GetSchemaRequest) (*schema.GetSchemaResponse, error) {
+	s := ss.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns, ok := s.namespaces[request.GetNamespace()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "namespace %s not found", request.GetNamespace())
+	}
+
+	namespaceSchema := &schema.NamespaceSchema{
+		Id:          ns.id,
+		TopicId:     ns.topicID,
+		QueryPoolId: ns.queryPoolID,
+		Tables:      make(map[string]*schema.TableSchema, len(ns.tables)),
+	}
+	for tableName, t := range ns.tables {
+		tableSchema := &schema.TableSchema{
+			Id:      t.id,
+			Columns: make(map[string]*schema.ColumnSchema, len(t.columns)),
+		}
+		for columnName, c := range t.columns {
+			tableSchema.Columns[columnName] = &schema.ColumnSchema{
+				Id:         c.id,
+				ColumnType: schema.ColumnSchema_ColumnType(c.columnType),
+			}
+		}
+		namespaceSchema.Tables[tableName] = tableSchema
+	}
+	return &schema.GetSchemaResponse{Schema: namespaceSchema}, nil
+}
+
+func (ss *schemaService) UpdateNamespaceRetention(_ context.Context, request *schema.UpdateNamespaceRetentionRequest) (*schema.UpdateNamespaceRetentionResponse, error) {
+	if request.GetRetentionHours() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "retention period must not be negative")
+	}
+	s := ss.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns, ok := s.namespaces[request.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "namespace %s not found", request.GetName())
+	}
+	// Zero hours means infinite retention, reported as no retention period.
+	ns.retention = time.Duration(request.GetRetentionHours()) * time.Hour
+	response := &schema.UpdateNamespaceRetentionResponse{
+		Namespace: &schema.Namespace{
+			Id:   ns.id,
+			Name: request.GetName(),
+		},
+	}
+	if ns.retention > 0 {
+		retentionPeriodNs := int64(ns.retention)
+		response.Namespace.RetentionPeriodNs = &retentionPeriodNs
+	}
+	return response, nil
+}
+
+type writeInfoService struct {
+	ingester.UnimplementedWriteInfoServiceServer
+	server *Server
+}
+
+func (ws *writeInfoService) GetWriteInfo(_ context.Context, request *ingester.GetWriteInfoRequest) (*ingester.GetWriteInfoResponse, error) {
+	s := ws.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.writeInfos[request.GetWriteToken()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "write token %q not found", request.GetWriteToken())
+	}
+
+	step := info.steps[info.next]
+	if info.next < len(info.steps)-1 {
+		info.next++
+	}
+	response := &ingester.GetWriteInfoResponse{}
+	for i, shardStatus := range step {
+		response.ShardInfos = append(response.ShardInfos, &ingester.ShardInfo{
+			ShardIndex: int32(i),
+			Status:     ingester.ShardStatus(shardStatus),
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,190 @@
+package ioxtest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2"
+	"github.com/influxdata/influxdb-iox-client-go/v2/ioxtest"
+)
+
+func newInt64Record(mem memory.Allocator, name string, values ...int64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{{Name: name, Type: arrow.PrimitiveTypes.Int64}}, nil)
+	b := array.NewInt64Builder(mem)
+	defer b.Release()
+	b.AppendValues(values, nil)
+	column := b.NewArray()
+	defer column.Release()
+	return array.NewRecord(schema, []arrow.Array{column}, int64(len(values)))
+}
+
+func ExampleServer() {
+	server := ioxtest.NewServer()
+	defer server.Close()
+
+	record := newInt64Record(memory.DefaultAllocator, "v", 1, 2, 3)
+	defer record.Release()
+	server.HandleQuery("select v from t", record)
+
+	client, _ := influxdbiox.NewClient(context.Background(), server.ClientConfig("mydb"))
+	defer client.Close()
+	req, _ := client.PrepareQuery(context.Background(), "", "select v from t")
+	reader, _ := req.Query(context.Background())
+	defer reader.Release()
+	for reader.Next() {
+		fmt.Println(reader.Record().Column(0).(*array.Int64).Int64Values())
+	}
+	// Output: [1 2 3]
+}
+
+func newTestClient(ctx context.Context, t *testing.T, server *ioxtest.Server) *influxdbiox.Client {
+	client, err := influxdbiox.NewClient(ctx, server.ClientConfig("mydb"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestServer_query(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	t.Cleanup(func() { mem.AssertSize(t, 0) })
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	client := newTestClient(ctx, t, server)
+	require.NoError(t, client.Handshake(ctx))
+
+	first, second := newInt64Record(mem, "v", 1, 2), newInt64Record(mem, "v", 3)
+	server.HandleQuery("select v from t", first, second)
+	first.Release()
+	second.Release()
+	fallback := newInt64Record(mem, "n", 0)
+	server.HandleDefault(fallback)
+	fallback.Release()
+
+	req, err := client.PrepareQuery(ctx, "", "select v from t")
+	require.NoError(t, err)
+	reader, err := req.Query(ctx)
+	require.NoError(t, err)
+	var values []int64
+	for reader.Next() {
+		values = append(values, reader.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, reader.Err())
+	reader.Release()
+	assert.Equal(t, []int64{1, 2, 3}, values)
+
+	req, err = client.PrepareInfluxQLQuery(ctx, "other", "select n from t")
+	require.NoError(t, err)
+	reader, err = req.Query(ctx)
+	require.NoError(t, err)
+	require.True(t, reader.Next())
+	assert.Equal(t, "n", reader.Schema().Field(0).Name)
+	reader.Release()
+
+	assert.Equal(t, []ioxtest.Query{
+		{Namespace: "mydb", Query: "select v from t", QueryType: influxdbiox.QueryTypeSQL},
+		{Namespace: "other", Query: "select n from t", QueryType: influxdbiox.QueryTypeInfluxQL},
+	}, server.Queries())
+}
+
+func TestServer_queryNotRegistered(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	client := newTestClient(ctx, t, server)
+
+	req, err := client.PrepareQuery(ctx, "", "select 1")
+	require.NoError(t, err)
+	reader, err := req.Query(ctx)
+	if err == nil {
+		assert.False(t, reader.Next())
+		err = reader.Err()
+		reader.Release()
+	}
+	assert.ErrorContains(t, err, `no records registered for query "select 1"`)
+}
+
+func TestServer_schema(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	client := newTestClient(ctx, t, server)
+
+	server.AddTable("mydb", "cpu", map[string]influxdbiox.ColumnType{
+		"time": influxdbiox.ColumnType_TIME,
+		"host": influxdbiox.ColumnType_TAG,
+		"v":    influxdbiox.ColumnType_F64,
+	})
+	columns, err := client.GetSchema(ctx, "mydb", "cpu")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]influxdbiox.ColumnType{
+		"time": influxdbiox.ColumnType_TIME,
+		"host": influxdbiox.ColumnType_TAG,
+		"v":    influxdbiox.ColumnType_F64,
+	}, columns)
+
+	_, err = client.GetSchema(ctx, "mydb", "mem")
+	assert.ErrorContains(t, err, "table not found")
+	_, err = client.GetSchema(ctx, "missing", "cpu")
+	assert.ErrorContains(t, err, "namespace missing not found")
+
+	retention, found := server.NamespaceRetention("mydb")
+	assert.True(t, found)
+	assert.Equal(t, influxdbiox.InfiniteRetention, retention)
+}
+
+func TestServer_writeInfo(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	client := newTestClient(ctx, t, server)
+
+	server.SetWriteInfo("token",
+		[]ioxtest.ShardStatus{ioxtest.ShardStatusDurable, ioxtest.ShardStatusUnknown},
+		[]ioxtest.ShardStatus{ioxtest.ShardStatusReadable, ioxtest.ShardStatusDurable},
+		[]ioxtest.ShardStatus{ioxtest.ShardStatusPersisted, ioxtest.ShardStatusReadable},
+	)
+	require.NoError(t, client.WaitForReadable(ctx, "token"))
+
+	shortCtx, shortCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer shortCancel()
+	assert.ErrorIs(t, client.WaitForPersisted(shortCtx, "token"), context.DeadlineExceeded)
+
+	assert.ErrorContains(t, client.WaitForDurable(ctx, "missing"), `write token "missing" not found`)
+}
+
+func TestServer_injectFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	server := ioxtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddNamespace("mydb")
+	client := newTestClient(ctx, t, server)
+
+	server.InjectFailure(ioxtest.Failure{Method: "GetSchema", Err: status.Error(codes.Unavailable, "restarting"), Count: 2})
+	for i := 0; i < 2; i++ {
+		_, err := client.GetNamespaceSchema(ctx, "mydb")
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	_, err := client.GetNamespaceSchema(ctx, "mydb")
+	require.NoError(t, err)
+}
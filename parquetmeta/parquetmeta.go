@@ -0,0 +1,226 @@
+// Package parquetmeta decodes the metadata that IOx stores in the Parquet
+// files it persists to object storage.
+//
+// IOx serializes its metadata as a protobuf message, in base64, under the
+// file-level key-value metadata key "IOX:metadata". ReadFile extracts it:
+//
+//	metadata, err := parquetmeta.ReadFile("0a2d5e1c-....parquet")
+//	err = metadata.Print(os.Stdout)
+package parquetmeta
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/file"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ingester "github.com/influxdata/influxdb-iox-client-go/v2/internal/ingester"
+)
+
+// Key is the Parquet key-value metadata key of the IOx metadata.
+const Key = "IOX:metadata"
+
+// ErrNoMetadata is returned for Parquet files without IOx metadata.
+var ErrNoMetadata = errors.New("no IOx metadata in Parquet file")
+
+// Metadata is the IOx metadata of a Parquet file.
+type Metadata struct {
+	// ObjectStoreID is the UUID that names the file in object storage.
+	ObjectStoreID string `json:"object_store_id"`
+	// CreationTime is when the file was created.
+	CreationTime time.Time `json:"creation_time"`
+	NamespaceID  int64     `json:"namespace_id"`
+	// NamespaceName is the name of the namespace, which is unique.
+	NamespaceName string `json:"namespace_name"`
+	ShardID       int64  `json:"shard_id"`
+	TableID       int64  `json:"table_id"`
+	TableName     string `json:"table_name"`
+	PartitionID   int64  `json:"partition_id"`
+	// PartitionKey is the key of the partition that holds the file, such
+	// as "2023-01-31".
+	PartitionKey string `json:"partition_key"`
+	// MaxSequenceNumber is the greatest sequence number of the writes in
+	// the file.
+	MaxSequenceNumber int64 `json:"max_sequence_number"`
+	// SortKey lists the columns by which the rows of the file are sorted.
+	SortKey []SortKeyExpr `json:"sort_key"`
+	// CompactionLevel is 0 for files persisted by an ingester, and higher
+	// for files produced by compaction.
+	CompactionLevel int32 `json:"compaction_level"`
+}
+
+// SortKeyExpr is a column of a sort key.
+type SortKeyExpr struct {
+	Column     string `json:"column"`
+	Descending bool   `json:"descending"`
+	NullsFirst bool   `json:"nulls_first"`
+}
+
+// String formats e like a SQL ORDER BY expression, such as
+// "host DESC NULLS FIRST".
+func (e SortKeyExpr) String() string {
+	var b strings.Builder
+	b.WriteString(e.Column)
+	if e.Descending {
+		b.WriteString(" DESC")
+	}
+	if e.NullsFirst {
+		b.WriteString(" NULLS FIRST")
+	}
+	return b.String()
+}
+
+// ReadFile reads the IOx metadata of the Parquet file named filename.
+func ReadFile(filename string) (*Metadata, error) {
+	reader, err := file.OpenParquetFile(filename, false)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return fromReader(reader)
+}
+
+// Read reads the IOx metadata of a Parquet file.
+func Read(r parquet.ReaderAtSeeker) (*Metadata, error) {
+	reader, err := file.NewParquetReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return fromReader(reader)
+}
+
+func fromReader(reader *file.Reader) (*Metadata, error) {
+	value := reader.MetaData().KeyValueMetadata().FindValue(Key)
+	if value == nil {
+		return nil, ErrNoMetadata
+	}
+	return Decode(*value)
+}
+
+// Decode decodes the value of the IOx key-value metadata of a Parquet file.
+func Decode(value string) (*Metadata, error) {
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IOx metadata: %w", err)
+	}
+	message := &ingester.IoxMetadata{}
+	if err = proto.Unmarshal(b, message); err != nil {
+		return nil, fmt.Errorf("invalid IOx metadata: %w", err)
+	}
+
+	m := &Metadata{
+		ObjectStoreID:     formatUUID(message.GetObjectStoreId()),
+		NamespaceID:       message.GetNamespaceId(),
+		NamespaceName:     message.GetNamespaceName(),
+		ShardID:           message.GetShardId(),
+		TableID:           message.GetTableId(),
+		TableName:         message.GetTableName(),
+		PartitionID:       message.GetPartitionId(),
+		PartitionKey:      message.GetPartitionKey(),
+		MaxSequenceNumber: message.GetMaxSequenceNumber(),
+		CompactionLevel:   message.GetCompactionLevel(),
+	}
+	if message.CreationTimestamp != nil {
+		m.CreationTime = message.GetCreationTimestamp().AsTime()
+	}
+	for _, expr := range message.GetSortKey().GetExpressions() {
+		m.SortKey = append(m.SortKey, SortKeyExpr{
+			Column:     expr.GetColumn(),
+			Descending: expr.GetDescending(),
+			NullsFirst: expr.GetNullsFirst(),
+		})
+	}
+	return m, nil
+}
+
+// Encode encodes m as the value of the IOx key-value metadata of a Parquet
+// file. ObjectStoreID must be a UUID.
+func (m *Metadata) Encode() (string, error) {
+	objectStoreID, err := parseUUID(m.ObjectStoreID)
+	if err != nil {
+		return "", err
+	}
+	message := &ingester.IoxMetadata{
+		ObjectStoreId:     objectStoreID,
+		NamespaceId:       m.NamespaceID,
+		NamespaceName:     m.NamespaceName,
+		ShardId:           m.ShardID,
+		TableId:           m.TableID,
+		TableName:         m.TableName,
+		PartitionId:       m.PartitionID,
+		PartitionKey:      m.PartitionKey,
+		MaxSequenceNumber: m.MaxSequenceNumber,
+		CompactionLevel:   m.CompactionLevel,
+	}
+	if !m.CreationTime.IsZero() {
+		message.CreationTimestamp = timestamppb.New(m.CreationTime)
+	}
+	if len(m.SortKey) > 0 {
+		message.SortKey = &ingester.SortKey{}
+		for _, expr := range m.SortKey {
+			message.SortKey.Expressions = append(message.SortKey.Expressions, &ingester.SortKey_Expr{
+				Column:     expr.Column,
+				Descending: expr.Descending,
+				NullsFirst: expr.NullsFirst,
+			})
+		}
+	}
+	b, err := proto.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Print writes m to w, one field per line.
+func (m *Metadata) Print(w io.Writer) error {
+	sortKey := make([]string, len(m.SortKey))
+	for i, expr := range m.SortKey {
+		sortKey[i] = expr.String()
+	}
+	_, err := fmt.Fprintf(w, `object_store_id:     %s
+creation_time:       %s
+namespace:           %s (id %d)
+shard_id:            %d
+table:               %s (id %d)
+partition:           %s (id %d)
+max_sequence_number: %d
+sort_key:            %s
+compaction_level:    %d
+`,
+		m.ObjectStoreID,
+		m.CreationTime.Format(time.RFC3339Nano),
+		m.NamespaceName, m.NamespaceID,
+		m.ShardID,
+		m.TableName, m.TableID,
+		m.PartitionKey, m.PartitionID,
+		m.MaxSequenceNumber,
+		strings.Join(sortKey, ", "),
+		m.CompactionLevel)
+	return err
+}
+
+// formatUUID formats 16 big-endian bytes as a UUID, or other lengths in
+// hexadecimal.
+func formatUUID(b []byte) string {
+	if len(b) != 16 {
+		return fmt.Sprintf("%x", b)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func parseUUID(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != 16 || len(s) != 36 {
+		return nil, fmt.Errorf("invalid object store ID %q: not a UUID", s)
+	}
+	return b, nil
+}
@@ -0,0 +1,126 @@
+package parquetmeta_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/influxdb-iox-client-go/v2/parquetmeta"
+)
+
+func ExampleReadFile() {
+	metadata, err := parquetmeta.ReadFile("0a2d5e1c-7b3f-4a52-9f1e-6c0d2b8e4f11.parquet")
+	if err != nil {
+		return
+	}
+	_ = metadata.Print(os.Stdout)
+}
+
+var testMetadata = &parquetmeta.Metadata{
+	ObjectStoreID:     "0a2d5e1c-7b3f-4a52-9f1e-6c0d2b8e4f11",
+	CreationTime:      time.Date(2023, 1, 31, 12, 30, 0, 5, time.UTC),
+	NamespaceID:       1,
+	NamespaceName:     "myorg_mybucket",
+	ShardID:           2,
+	TableID:           3,
+	TableName:         "cpu",
+	PartitionID:       4,
+	PartitionKey:      "2023-01-31",
+	MaxSequenceNumber: 99,
+	SortKey: []parquetmeta.SortKeyExpr{
+		{Column: "host"},
+		{Column: "time", Descending: true, NullsFirst: true},
+	},
+	CompactionLevel: 1,
+}
+
+// writeParquet writes a Parquet file with the key-value metadata given as
+// pairs of keys and values.
+func writeParquet(t *testing.T, filename string, keyValues ...string) {
+	var keys, values []string
+	for i := 0; i < len(keyValues); i += 2 {
+		keys = append(keys, keyValues[i])
+		values = append(values, keyValues[i+1])
+	}
+	keyValueMetadata := arrow.NewMetadata(keys, values)
+	schema := arrow.NewSchema([]arrow.Field{{Name: "usage", Type: arrow.PrimitiveTypes.Float64}}, &keyValueMetadata)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Field(0).(*array.Float64Builder).AppendValues([]float64{0.5}, nil)
+	record := b.NewRecord()
+	defer record.Release()
+
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	w, err := pqarrow.NewFileWriter(schema, f, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
+	require.NoError(t, err)
+	require.NoError(t, w.Write(record))
+	require.NoError(t, w.Close())
+}
+
+func TestReadFile(t *testing.T) {
+	value, err := testMetadata.Encode()
+	require.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "file.parquet")
+	writeParquet(t, filename, "other", "x", parquetmeta.Key, value)
+
+	metadata, err := parquetmeta.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, testMetadata, metadata)
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	metadata, err = parquetmeta.Read(f)
+	require.NoError(t, err)
+	assert.Equal(t, testMetadata, metadata)
+
+	var out bytes.Buffer
+	require.NoError(t, metadata.Print(&out))
+	assert.Equal(t, `object_store_id:     0a2d5e1c-7b3f-4a52-9f1e-6c0d2b8e4f11
+creation_time:       2023-01-31T12:30:00.000000005Z
+namespace:           myorg_mybucket (id 1)
+shard_id:            2
+table:               cpu (id 3)
+partition:           2023-01-31 (id 4)
+max_sequence_number: 99
+sort_key:            host, time DESC NULLS FIRST
+compaction_level:    1
+`, out.String())
+}
+
+func TestReadFile_errors(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "none.parquet")
+	writeParquet(t, filename)
+	_, err := parquetmeta.ReadFile(filename)
+	assert.ErrorIs(t, err, parquetmeta.ErrNoMetadata)
+
+	filename = filepath.Join(dir, "invalid.parquet")
+	writeParquet(t, filename, parquetmeta.Key, "not base64!")
+	_, err = parquetmeta.ReadFile(filename)
+	assert.ErrorContains(t, err, "invalid IOx metadata")
+
+	_, err = parquetmeta.ReadFile(filepath.Join(dir, "missing.parquet"))
+	assert.Error(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	// An object store ID of the wrong length is shown in hexadecimal.
+	metadata, err := parquetmeta.Decode("CgOqu8w=")
+	require.NoError(t, err)
+	assert.Equal(t, &parquetmeta.Metadata{ObjectStoreID: "aabbcc"}, metadata)
+
+	_, err = (&parquetmeta.Metadata{ObjectStoreID: "aabbcc"}).Encode()
+	assert.ErrorContains(t, err, "not a UUID")
+}
@@ -3,25 +3,20 @@
 set -e
 
 cd "$(dirname "$0")"
-BASEDIR=$(pwd)
 
-for package in . ./ioxsql; do
-  echo checking ${package}
-  cd ${BASEDIR}/${package}
-  if ! go build; then
-    fail=1
-  fi
-  if [[ -n $(gofmt -s -l . | head -n 1) ]]; then
-    fail=1
-    gofmt -s -d .
-  fi
-  if ! go vet; then
-    fail=1
-  fi
-  if ! staticcheck -f stylish; then
-    fail=1
-  fi
-done
+if ! go build ./...; then
+  fail=1
+fi
+if [[ -n $(gofmt -s -l . | head -n 1) ]]; then
+  fail=1
+  gofmt -s -d .
+fi
+if ! go vet ./...; then
+  fail=1
+fi
+if ! staticcheck -f stylish ./...; then
+  fail=1
+fi
 
 echo
 